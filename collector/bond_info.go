@@ -0,0 +1,207 @@
+package collector
+
+import (
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/newrushbolt/go-ethtool-exporter/metrics"
+	"github.com/newrushbolt/go-ethtool-exporter/registry"
+	"github.com/newrushbolt/go-ethtool-metrics/common"
+)
+
+const bondInfoTag = "bond_info"
+
+type BondInfoConfig struct {
+	Collect bool
+	// Usually /proc/net/bonding
+	ProcNetBondingPath string
+	// Usually /sys/class/net
+	NetClassPath string
+}
+
+// Bond-level info, eg `/proc/net/bonding/bond0` before the first slave
+type BondInfo struct {
+	Mode               string `bond_info:"Bonding Mode"`
+	TransmitHashPolicy string `bond_info:"Transmit Hash Policy"`
+	MiiStatus          string `bond_info:"MII Status"`
+	// Numeric mode from sysfs, eg `4` for `802.3ad 4`
+	ModeId        *float64
+	MiiUp         bool
+	Slaves        *float64
+	LacpRate      string   `bond_info:"LACP rate"`
+	SystemMac     string   `bond_info:"System MAC address"`
+	AggregatorId  *float64 `bond_info:"aggregator Aggregator ID"`
+	NumberOfPorts *float64 `bond_info:"aggregator Number of ports"`
+	ActorKey      *float64 `bond_info:"aggregator Actor Key"`
+	PartnerKey    *float64 `bond_info:"aggregator Partner Key"`
+	PartnerMac    string   `bond_info:"aggregator Partner Mac Address"`
+}
+
+// Per-slave info, LACP fields are only present in 802.3ad mode
+type BondSlaveInfo struct {
+	MiiStatus           string `bond_info:"MII Status"`
+	MiiUp               bool
+	LinkFailureCount    *float64 `bond_info:"Link Failure Count"`
+	PermanentHwAddr     string   `bond_info:"Permanent HW addr"`
+	AggregatorId        *float64 `bond_info:"Aggregator ID"`
+	ActorChurnState     string   `bond_info:"Actor Churn State"`
+	PartnerChurnState   string   `bond_info:"Partner Churn State"`
+	ActorChurned        bool
+	PartnerChurned      bool
+	ActorChurnedCount   *float64 `bond_info:"Actor Churned Count"`
+	PartnerChurnedCount *float64 `bond_info:"Partner Churned Count"`
+	ActorPortState      *float64 `bond_info:"actor port state"`
+	PartnerMac          string   `bond_info:"partner system mac address"`
+	PartnerOperKey      *float64 `bond_info:"partner oper key"`
+	PartnerPortState    *float64 `bond_info:"partner port state"`
+}
+
+// Splits `/proc/net/bonding/<bond>` into bond-level and per-slave key-value maps.
+// Keys of nested blocks (active aggregator, LACP PDU details) are prefixed with block name.
+func splitBondingData(rawInfo string) (map[string]string, []string, []map[string]string) {
+	bondData := map[string]string{}
+	slaveNames := []string{}
+	slavesData := []map[string]string{}
+	currentData := bondData
+	blockPrefix := ""
+
+	for _, line := range strings.Split(rawInfo, "\n") {
+		trimmedLine := strings.TrimSpace(line)
+		switch {
+		case trimmedLine == "":
+			blockPrefix = ""
+			continue
+		case trimmedLine == "Active Aggregator Info:":
+			blockPrefix = "aggregator "
+			continue
+		case trimmedLine == "details actor lacp pdu:":
+			blockPrefix = "actor "
+			continue
+		case trimmedLine == "details partner lacp pdu:":
+			blockPrefix = "partner "
+			continue
+		}
+		// MAC addresses contain colons, keys don't
+		key, value, found := strings.Cut(trimmedLine, ":")
+		if !found {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if key == "Slave Interface" {
+			currentData = map[string]string{}
+			slaveNames = append(slaveNames, value)
+			slavesData = append(slavesData, currentData)
+			blockPrefix = ""
+			continue
+		}
+		// Nested block lines are always indented
+		if line == trimmedLine {
+			blockPrefix = ""
+		}
+		currentData[blockPrefix+key] = value
+	}
+	return bondData, slaveNames, slavesData
+}
+
+func ParseBondInfo(rawInfo string) (*BondInfo, map[string]*BondSlaveInfo) {
+	if rawInfo == "" {
+		return nil, nil
+	}
+	bondData, slaveNames, slavesData := splitBondingData(rawInfo)
+
+	var bondInfo BondInfo
+	common.ParseAbstractDataObject(&bondData, &bondInfo, bondInfoTag)
+	bondInfo.MiiUp = bondInfo.MiiStatus == "up"
+	slavesCount := float64(len(slaveNames))
+	bondInfo.Slaves = &slavesCount
+
+	slaves := map[string]*BondSlaveInfo{}
+	for slaveIndex, slaveName := range slaveNames {
+		var slaveInfo BondSlaveInfo
+		common.ParseAbstractDataObject(&slavesData[slaveIndex], &slaveInfo, bondInfoTag)
+		slaveInfo.MiiUp = slaveInfo.MiiStatus == "up"
+		slaveInfo.ActorChurned = slaveInfo.ActorChurnState == "churned"
+		slaveInfo.PartnerChurned = slaveInfo.PartnerChurnState == "churned"
+		slaves[slaveName] = &slaveInfo
+	}
+	return &bondInfo, slaves
+}
+
+// Reads numeric bonding mode from sysfs, eg `802.3ad 4`
+func readBondModeId(netClassPath string, bondName string) *float64 {
+	modePath := path.Join(netClassPath, bondName, "bonding/mode")
+	modeRaw, err := os.ReadFile(modePath)
+	if err != nil {
+		slog.Debug("Cannot read bonding mode from sysfs", "bondName", bondName, "error", err)
+		return nil
+	}
+	modeFields := strings.Fields(string(modeRaw))
+	if len(modeFields) != 2 {
+		slog.Debug("Cannot parse bonding mode from sysfs", "bondName", bondName, "mode", string(modeRaw))
+		return nil
+	}
+	modeId, err := strconv.ParseFloat(modeFields[1], 64)
+	if err != nil {
+		slog.Debug("Cannot parse bonding mode from sysfs", "bondName", bondName, "mode", string(modeRaw), "error", err)
+		return nil
+	}
+	return &modeId
+}
+
+// Parsed bond info with its slaves, keyed by slave device
+type BondData struct {
+	Bond   *BondInfo
+	Slaves map[string]*BondSlaveInfo
+}
+
+// Returns nil if device is not a bond master
+func readBondData(bondName string, config BondInfoConfig) *BondData {
+	bondingPath := path.Join(config.ProcNetBondingPath, bondName)
+	rawInfo, err := os.ReadFile(bondingPath)
+	if os.IsNotExist(err) {
+		slog.Debug("Device is not a bond master, skipping", "interfaceName", bondName, "collector", bondInfoTag)
+		return nil
+	} else if err != nil {
+		slog.Info("Cannot read bonding info", "bondingPath", bondingPath, "error", err)
+		return nil
+	}
+
+	bondInfo, slaves := ParseBondInfo(string(rawInfo))
+	if bondInfo == nil {
+		return nil
+	}
+	bondInfo.ModeId = readBondModeId(config.NetClassPath, bondName)
+	return &BondData{Bond: bondInfo, Slaves: slaves}
+}
+
+// Bond-level metrics are labeled by bond device, per-slave metrics are labeled by slave device and its master
+func collectBondInfo(bondName string, config BondInfoConfig, absentMetrics metrics.AbsentMetricsConfig, listLabelFormat string) registry.Registry {
+	var metricRegistry registry.Registry
+	bondData := readBondData(bondName, config)
+	if bondData == nil {
+		return metricRegistry
+	}
+	bondInfo, slaves := bondData.Bond, bondData.Slaves
+	collectorLabels := map[string]string{"collector": bondInfoTag}
+
+	bondLabels := map[string]string{"device": bondName}
+	metrics.MetricListFromStructs(bondInfo, &metricRegistry, []string{bondInfoTag}, bondLabels, absentMetrics, listLabelFormat)
+	for _, slaveName := range slices.Sorted(maps.Keys(slaves)) {
+		slaveLabels := map[string]string{
+			"device": slaveName,
+			"master": bondName,
+		}
+		// Separate registry per slave, otherwise info-metric labels of all slaves are merged into the first one
+		var slaveRegistry registry.Registry
+		metrics.MetricListFromStructs(slaves[slaveName], &slaveRegistry, []string{bondInfoTag, "Slave"}, slaveLabels, absentMetrics, listLabelFormat)
+		metricRegistry = append(metricRegistry, slaveRegistry...)
+	}
+	metricRegistry.AddLabelsToSomeMetrics(metrics.AbsentMetricDetailedName, collectorLabels)
+	return metricRegistry
+}
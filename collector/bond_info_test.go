@@ -0,0 +1,66 @@
+package collector
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/newrushbolt/go-ethtool-exporter/metrics"
+)
+
+func TestCollectBondInfo(t *testing.T) {
+	expectedBytes, err := os.ReadFile("../testdata/bond0.bond_info.prom")
+	if err != nil {
+		t.Fatalf("Failed to read expected metrics: %v", err)
+	}
+	expectedMetricResult := string(expectedBytes)
+
+	config := BondInfoConfig{
+		Collect:            true,
+		ProcNetBondingPath: "../testdata/proc/net/bonding",
+		NetClassPath:       "../testdata/interfaces/sys/class/net",
+	}
+	registry := collectBondInfo("bond0", config, metrics.AbsentMetricsConfig{}, "single-label")
+
+	assert.Equal(t, expectedMetricResult, registry.FormatTextfileString())
+}
+
+func TestCollectBondInfoNotBond(t *testing.T) {
+	config := BondInfoConfig{
+		Collect:            true,
+		ProcNetBondingPath: "../testdata/proc/net/bonding",
+		NetClassPath:       "../testdata/interfaces/sys/class/net",
+	}
+	registry := collectBondInfo("eth0", config, metrics.AbsentMetricsConfig{}, "single-label")
+
+	assert.Empty(t, registry)
+}
+
+func TestParseBondInfoActiveBackup(t *testing.T) {
+	rawInfo := `Ethernet Channel Bonding Driver: v5.15.0
+
+Bonding Mode: fault-tolerance (active-backup)
+Primary Slave: None
+Currently Active Slave: eth1
+MII Status: up
+
+Slave Interface: eth1
+MII Status: down
+Link Failure Count: 7
+Permanent HW addr: 52:54:00:00:00:01
+`
+	bondInfo, slaves := ParseBondInfo(rawInfo)
+
+	assert.Equal(t, "fault-tolerance (active-backup)", bondInfo.Mode)
+	assert.True(t, bondInfo.MiiUp)
+	assert.Nil(t, bondInfo.AggregatorId)
+	assert.Len(t, slaves, 1)
+	assert.False(t, slaves["eth1"].MiiUp)
+	assert.Equal(t, float64(7), *slaves["eth1"].LinkFailureCount)
+	assert.Nil(t, slaves["eth1"].AggregatorId)
+
+	bondInfo, slaves = ParseBondInfo("")
+	assert.Nil(t, bondInfo)
+	assert.Nil(t, slaves)
+}
@@ -1,13 +1,12 @@
 package collector
 
 import (
-	"context"
 	"log/slog"
-	"os/exec"
 	"strings"
 	"time"
 
 	"github.com/newrushbolt/go-ethtool-exporter/metrics"
+	"github.com/newrushbolt/go-ethtool-exporter/netns"
 	"github.com/newrushbolt/go-ethtool-exporter/registry"
 
 	"github.com/newrushbolt/go-ethtool-metrics/pkg/metrics/driver_info"
@@ -26,101 +25,205 @@ type CollectorConfig struct {
 	ModuleInfoAbsentMetrics  metrics.AbsentMetricsConfig
 	Statistics               statistics.CollectConfig
 	StatisticsAbsentMetrics  metrics.AbsentMetricsConfig
+	// Not an ethtool collector, reads procfs and sysfs of bond masters
+	BondInfo              BondInfoConfig
+	BondInfoAbsentMetrics metrics.AbsentMetricsConfig
 	// Common configs
-	EthtoolPath     string
-	EthtoolTimeout  time.Duration
-	ListLabelFormat string
+	// Source of raw ethtool output, ethtool binary from EthtoolPath is run if it's nil
+	DataSource     DataSource
+	EthtoolPath    string
+	EthtoolTimeout time.Duration
+	// Network namespace to run ethtool in, eg `/host/proc/1/ns/net`. Empty means exporter's own namespace
+	EthtoolNetnsPath string
+	ListLabelFormat  string
+	// Set by RunInEthtoolNetns if the namespace cannot be entered, ethtool collectors are skipped then
+	ethtoolNetnsErr error
 }
 
-func readEthtoolData(interfaceName string, ethtoolMode string, ethtoolPath string, ethtoolTimeout time.Duration) string {
-	var ethtoolOutputRaw []byte
-	var err error
-	var cancel context.CancelFunc
-	var ctx context.Context
-
-	ctx, cancel = context.WithTimeout(context.Background(), ethtoolTimeout)
-	defer cancel()
+func (config CollectorConfig) getDataSource() DataSource {
+	if config.DataSource != nil {
+		return config.DataSource
+	}
+	return ExecDataSource{Path: config.EthtoolPath, Timeout: config.EthtoolTimeout}
+}
 
-	ethtoolArgs := []string{}
-	if ethtoolMode != "" {
-		ethtoolArgs = append(ethtoolArgs, ethtoolMode)
+func runInEthtoolNetns(config CollectorConfig, function func() error) error {
+	if config.ethtoolNetnsErr != nil {
+		return config.ethtoolNetnsErr
 	}
-	ethtoolArgs = append(ethtoolArgs, interfaceName)
+	if config.EthtoolNetnsPath == "" {
+		return function()
+	}
+	ethtoolNamespace := netns.Namespace{Name: "ethtool", Path: config.EthtoolNetnsPath}
+	return ethtoolNamespace.Enter(function)
+}
 
-	ethtoolOutputRaw, err = exec.CommandContext(ctx, ethtoolPath, ethtoolArgs...).Output()
+// RunInEthtoolNetns calls the function inside ethtool network namespace, with the namespace removed from the config,
+// so it's entered once for all interfaces instead of every one of them.
+// If the namespace cannot be entered, the function is still called, with ethtool collectors failing.
+func RunInEthtoolNetns(config CollectorConfig, function func(config CollectorConfig)) {
+	if config.EthtoolNetnsPath == "" {
+		function(config)
+		return
+	}
+	namespaceConfig := config
+	namespaceConfig.EthtoolNetnsPath = ""
+	err := runInEthtoolNetns(config, func() error {
+		function(namespaceConfig)
+		return nil
+	})
 	if err != nil {
-		slog.Info("Cannot run ethtool command", "ethtoolPath", ethtoolPath, "ethtoolMode", ethtoolMode, "error", err)
-		return ""
+		config.ethtoolNetnsErr = err
+		function(config)
 	}
-	ethtoolOutput := string(ethtoolOutputRaw)
-	return ethtoolOutput
+}
+
+// Ethtool collectors with their ethtool options, in the order of collection
+var EthtoolModes = []struct {
+	Collector   string
+	EthtoolMode string
+}{
+	{Collector: "driver_info", EthtoolMode: "-i"},
+	{Collector: "generic_info", EthtoolMode: ""},
+	{Collector: "module_info", EthtoolMode: "-m"},
+	{Collector: "statistics", EthtoolMode: "-S"},
+}
+
+// ReadRawEthtoolData reads data source in every mode, regardless of enabled collectors, eg for diagnostic reports.
+// Output is keyed by collector name, it is empty if data source failed.
+func ReadRawEthtoolData(interfaceName string, config CollectorConfig) (map[string]string, error) {
+	rawData := map[string]string{}
+	dataSource := config.getDataSource()
+	err := runInEthtoolNetns(config, func() error {
+		for _, mode := range EthtoolModes {
+			dataRaw, err := dataSource.ReadEthtoolData(interfaceName, mode.Collector)
+			if err != nil {
+				slog.Info("Cannot read ethtool data", "interfaceName", interfaceName, "collector", mode.Collector, "error", err)
+			}
+			rawData[mode.Collector] = dataRaw
+		}
+		return nil
+	})
+	return rawData, err
 }
 
 type metricCollector struct {
 	Name          string
 	Enabled       bool
-	EthtoolMode   string
 	ParseFunc     func(string) any
 	AbsentMetrics metrics.AbsentMetricsConfig
 }
 
-func CollectInterfaceMetrics(interfaceName string, config CollectorConfig) registry.Registry {
-	collectors := []metricCollector{
+func newMetricCollectors(config CollectorConfig) []metricCollector {
+	return []metricCollector{
 		{
 			Name:          "driver_info",
-			EthtoolMode:   "-i",
 			Enabled:       config.DriverInfo.CollectCommon || config.DriverInfo.CollectFeatures,
 			ParseFunc:     func(raw string) any { return driver_info.ParseInfo(raw, &config.DriverInfo) },
 			AbsentMetrics: config.DriverInfoAbsentMetrics,
 		},
 		{
 			Name:          "generic_info",
-			EthtoolMode:   "",
 			Enabled:       config.GenericInfo.CollectAdvertisedSettings || config.GenericInfo.CollectSupportedSettings || config.GenericInfo.CollectSettings,
 			ParseFunc:     func(raw string) any { return generic_info.ParseInfo(raw, &config.GenericInfo) },
 			AbsentMetrics: config.GenericInfoAbsentMetrics,
 		},
 		{
 			Name:          "module_info",
-			EthtoolMode:   "-m",
 			Enabled:       config.ModuleInfo.CollectDiagnosticsAlarms || config.ModuleInfo.CollectDiagnosticsValues || config.ModuleInfo.CollectDiagnosticsWarnings || config.ModuleInfo.CollectVendor,
 			ParseFunc:     func(raw string) any { return module_info.ParseInfo(raw, &config.ModuleInfo) },
 			AbsentMetrics: config.ModuleInfoAbsentMetrics,
 		},
 		{
 			Name:          "statistics",
-			EthtoolMode:   "-S",
 			Enabled:       config.Statistics.General || config.Statistics.PerQueueGeneral || config.Statistics.PerQueuePerType,
 			ParseFunc:     func(raw string) any { return statistics.ParseInfo(raw, &config.Statistics) },
 			AbsentMetrics: config.StatisticsAbsentMetrics,
 		},
 	}
+}
+
+// Calls the function with parsed data of every enabled ethtool collector, read error is passed along
+func parseEthtoolData(interfaceName string, config CollectorConfig, function func(collector metricCollector, data any, readErr error)) error {
+	interfaceLogger := slog.With("interfaceName", interfaceName)
+	dataSource := config.getDataSource()
+	return runInEthtoolNetns(config, func() error {
+		for _, collector := range newMetricCollectors(config) {
+			collectorLogger := interfaceLogger.With("collector", collector.Name)
+			if !collector.Enabled {
+				collectorLogger.Debug("Metrics are disabled, skipping")
+				continue
+			}
+			collectorLogger.Debug("Collecting metrics")
+			dataRaw, err := dataSource.ReadEthtoolData(interfaceName, collector.Name)
+			if err != nil {
+				// Empty output is still parsed, so absent metrics are exposed
+				collectorLogger.Info("Cannot read ethtool data", "error", err)
+			}
+			collectorLogger.Debug("Got raw lines", "count", strings.Count(dataRaw, "\n"))
+			function(collector, collector.ParseFunc(dataRaw), err)
+		}
+		return nil
+	})
+}
 
+func CollectInterfaceMetrics(interfaceName string, config CollectorConfig) registry.Registry {
+	metricRegistry, _ := CollectInterfaceMetricsWithErrors(interfaceName, config)
+	return metricRegistry
+}
+
+// CollectInterfaceMetricsWithErrors also returns data source errors of ethtool collectors, keyed by collector name.
+// Error is nil if collector read its data. Metrics of failed collectors are still collected, as absent ones.
+func CollectInterfaceMetricsWithErrors(interfaceName string, config CollectorConfig) (registry.Registry, map[string]error) {
 	var metricRegistry registry.Registry
+	readErrors := map[string]error{}
 	interfaceLogger := slog.With("interfaceName", interfaceName)
 	deviceLabels := map[string]string{
 		"device": interfaceName,
 	}
 
-	for _, collector := range collectors {
-		collectorLogger := interfaceLogger.With("collector", collector.Name)
-		if !collector.Enabled {
-			collectorLogger.Debug("Metrics are disabled, skipping")
-			continue
-		}
-		collectorLogger.Debug("Collecting metrics")
+	err := parseEthtoolData(interfaceName, config, func(collector metricCollector, data any, readErr error) {
+		readErrors[collector.Name] = readErr
 		collectorLabels := map[string]string{
 			"collector": collector.Name,
 		}
-		dataRaw := readEthtoolData(interfaceName, collector.EthtoolMode, config.EthtoolPath, config.EthtoolTimeout)
-		collectorLogger.Debug("Got raw lines", "count", strings.Count(dataRaw, "\n"))
-		data := collector.ParseFunc(dataRaw)
 		before := len(metricRegistry)
 		metrics.MetricListFromStructs(data, &metricRegistry, []string{collector.Name}, deviceLabels, collector.AbsentMetrics, config.ListLabelFormat)
 		metricRegistry.AddLabelsToSomeMetrics(metrics.AbsentMetricDetailedName, collectorLabels)
-		collectorLogger.Debug("Final metrics", "count", len(metricRegistry)-before)
+		interfaceLogger.Debug("Final metrics", "collector", collector.Name, "count", len(metricRegistry)-before)
+	})
+	if err != nil {
+		interfaceLogger.Error("Cannot run ethtool in network namespace, skipping ethtool collectors", "netnsPath", config.EthtoolNetnsPath, "error", err)
+		for _, collector := range newMetricCollectors(config) {
+			if collector.Enabled {
+				readErrors[collector.Name] = err
+			}
+		}
 	}
 
+	if config.BondInfo.Collect {
+		metricRegistry = append(metricRegistry, collectBondInfo(interfaceName, config.BondInfo, config.BondInfoAbsentMetrics, config.ListLabelFormat)...)
+	}
 	interfaceLogger.Debug("Total metric count", "metricCount", len(metricRegistry))
-	return metricRegistry
+	return metricRegistry, readErrors
+}
+
+// CollectInterfaceData returns parsed go-ethtool-metrics structs of enabled collectors, keyed by collector name, eg for JSON output.
+// Bond info is only present for bond masters.
+func CollectInterfaceData(interfaceName string, config CollectorConfig) map[string]any {
+	interfaceData := map[string]any{}
+	err := parseEthtoolData(interfaceName, config, func(collector metricCollector, data any, _ error) {
+		interfaceData[collector.Name] = data
+	})
+	if err != nil {
+		slog.Error("Cannot run ethtool in network namespace, skipping ethtool collectors", "interfaceName", interfaceName, "netnsPath", config.EthtoolNetnsPath, "error", err)
+	}
+
+	if config.BondInfo.Collect {
+		bondData := readBondData(interfaceName, config.BondInfo)
+		if bondData != nil {
+			interfaceData[bondInfoTag] = bondData
+		}
+	}
+	return interfaceData
 }
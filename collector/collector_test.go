@@ -24,26 +24,31 @@ func TestReadEthtoolData(t *testing.T) {
 	assert.NoError(t, err)
 
 	// No mode
-	out = readEthtoolData("eth0", "", stubPath, timeout)
+	out, err = readEthtoolData("eth0", "", stubPath, timeout)
+	assert.NoError(t, err)
 	assert.Equal(t, "generic info for eth0\n", out)
 
 	// -i mode
-	out = readEthtoolData("eth0", "-i", stubPath, timeout)
+	out, err = readEthtoolData("eth0", "-i", stubPath, timeout)
+	assert.NoError(t, err)
 	assert.Equal(t, "driver info for eth0\n", out)
 
 	// -m mode
-	out = readEthtoolData("eth0", "-m", stubPath, timeout)
+	out, err = readEthtoolData("eth0", "-m", stubPath, timeout)
+	assert.NoError(t, err)
 	assert.Equal(t, "module info for eth0\n", out)
 
 	// -S mode
-	out = readEthtoolData("eth0", "-S", stubPath, timeout)
+	out, err = readEthtoolData("eth0", "-S", stubPath, timeout)
+	assert.NoError(t, err)
 	assert.Equal(t, "statistics for eth0\n", out)
 
 	// Timeout
 	tinyTimeout, err := time.ParseDuration("10ms")
 	assert.NoError(t, err)
 
-	out = readEthtoolData("eth0", "-S", stubPath, tinyTimeout)
+	out, err = readEthtoolData("eth0", "-S", stubPath, tinyTimeout)
+	assert.Error(t, err)
 	assert.Equal(t, "", out)
 }
 
@@ -53,17 +58,14 @@ func TestEmptyCollectInterfaceMetrics(t *testing.T) {
 	moduleInfoConfig := module_info.CollectConfig{}.Default()
 	statisticsConfig := statistics.CollectConfig{}.Default()
 
-	ethtoolPath := "../testdata/non_existed_ethtool.sh"
-	ethtoolTimeout := 1 * time.Second
-
 	collectorConfig := CollectorConfig{
 		GenericInfo: *genericinfoConfig,
 		DriverInfo:  *driverInfoConfig,
 		ModuleInfo:  *moduleInfoConfig,
 		Statistics:  *statisticsConfig,
 
-		EthtoolPath:     ethtoolPath,
-		EthtoolTimeout:  ethtoolTimeout,
+		// Every read fails
+		DataSource:      StaticDataSource{},
 		ListLabelFormat: "single-label",
 
 		GenericInfoAbsentMetrics: metrics.AbsentMetricsConfig{},
@@ -84,6 +86,10 @@ func TestGenericIntelCollectInterfaceMetrics(t *testing.T) {
 		t.Fatalf("Failed to read expected metrics: %v", err)
 	}
 	expectedMetricResult := string(expectedBytes)
+	genericInfoRaw, err := os.ReadFile("../testdata/eth4.generic_info.src")
+	if err != nil {
+		t.Fatalf("Failed to read ethtool output: %v", err)
+	}
 
 	genericinfoConfig := generic_info.CollectConfig{
 		CollectAdvertisedSettings: true,
@@ -107,17 +113,13 @@ func TestGenericIntelCollectInterfaceMetrics(t *testing.T) {
 		PerQueueGenerateMissingBytesMetrics: false,
 		PerQueueXdp:                         false,
 	}
-	ethtoolPath := "../testdata/ethtool.sh"
-	ethtoolTimeout := 1 * time.Second
-
 	collectorConfig := CollectorConfig{
 		GenericInfo: genericinfoConfig,
 		DriverInfo:  driverInfoConfig,
 		ModuleInfo:  moduleInfoConfig,
 		Statistics:  statisticsConfig,
 
-		EthtoolPath:     ethtoolPath,
-		EthtoolTimeout:  ethtoolTimeout,
+		DataSource:      StaticDataSource{"eth4": {"generic_info": string(genericInfoRaw)}},
 		ListLabelFormat: "single-label",
 
 		GenericInfoAbsentMetrics: metrics.AbsentMetricsConfig{},
@@ -130,3 +132,129 @@ func TestGenericIntelCollectInterfaceMetrics(t *testing.T) {
 
 	assert.Equal(t, expectedMetricResult, registry.FormatTextfileString())
 }
+
+func TestCollectInterfaceMetricsEthtoolNetns(t *testing.T) {
+	genericInfoRaw, err := os.ReadFile("../testdata/eth4.generic_info.src")
+	assert.NoError(t, err)
+	collectorConfig := CollectorConfig{
+		GenericInfo: generic_info.CollectConfig{
+			CollectSettings: true,
+		},
+		DataSource:      StaticDataSource{"eth4": {"generic_info": string(genericInfoRaw)}},
+		ListLabelFormat: "single-label",
+	}
+	expectedRegistry := CollectInterfaceMetrics("eth4", collectorConfig)
+	assert.NotEmpty(t, expectedRegistry)
+
+	// Ethtool collectors are skipped if namespace cannot be entered
+	collectorConfig.EthtoolNetnsPath = "non_existent_netns"
+	registry := CollectInterfaceMetrics("eth4", collectorConfig)
+	assert.Len(t, registry, 0)
+
+	// Entering own namespace still requires CAP_SYS_ADMIN
+	collectorConfig.EthtoolNetnsPath = "/proc/self/ns/net"
+	registry = CollectInterfaceMetrics("eth4", collectorConfig)
+	if len(registry) == 0 {
+		t.Skip("Cannot enter network namespace")
+	}
+	assert.Equal(t, expectedRegistry, registry)
+}
+
+func TestReadRawEthtoolData(t *testing.T) {
+	ethtoolOutput := map[string]string{
+		"driver_info":  "driver info for eth0\n",
+		"generic_info": "generic info for eth0\n",
+		"module_info":  "module info for eth0\n",
+		"statistics":   "statistics for eth0\n",
+	}
+	collectorConfig := CollectorConfig{
+		DataSource: StaticDataSource{"eth0": ethtoolOutput},
+	}
+	rawData, err := ReadRawEthtoolData("eth0", collectorConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, ethtoolOutput, rawData)
+
+	collectorConfig.EthtoolNetnsPath = "non_existent_netns"
+	_, err = ReadRawEthtoolData("eth0", collectorConfig)
+	assert.Error(t, err)
+}
+
+func TestReadRawEthtoolDataReplay(t *testing.T) {
+	expectedGenericInfo, err := os.ReadFile("../testdata/eth4.generic_info.src")
+	assert.NoError(t, err)
+	collectorConfig := CollectorConfig{
+		// Ethtool is never run in replay mode
+		EthtoolPath: "../testdata/non_existed_ethtool.sh",
+		DataSource:  ReplayDataSource{Directory: "../testdata/replay"},
+	}
+	rawData, err := ReadRawEthtoolData("eth4", collectorConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"driver_info":  "driver info for eth4\n",
+		"generic_info": string(expectedGenericInfo),
+		"module_info":  "module info for eth4\n",
+		"statistics":   "statistics for eth4\n",
+	}, rawData)
+
+	rawData, err = ReadRawEthtoolData("eth9", collectorConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"driver_info":  "",
+		"generic_info": "",
+		"module_info":  "",
+		"statistics":   "",
+	}, rawData)
+}
+
+func TestCollectInterfaceMetricsWithErrors(t *testing.T) {
+	collectorConfig := CollectorConfig{
+		DriverInfo: driver_info.CollectConfig{CollectCommon: true},
+		ModuleInfo: module_info.CollectConfig{CollectVendor: true},
+		DataSource: StaticDataSource{"eth0": {"driver_info": "driver: ixgbe\n"}},
+		BondInfo: BondInfoConfig{
+			Collect:            true,
+			ProcNetBondingPath: "../testdata/proc/net/bonding",
+			NetClassPath:       "../testdata/interfaces/sys/class/net",
+		},
+		ListLabelFormat: "single-label",
+	}
+
+	metricRegistry, readErrors := CollectInterfaceMetricsWithErrors("eth0", collectorConfig)
+	assert.Len(t, readErrors, 2)
+	assert.NoError(t, readErrors["driver_info"])
+	assert.ErrorContains(t, readErrors["module_info"], "no module_info output")
+	assert.NotEmpty(t, metricRegistry)
+}
+
+func TestRunInEthtoolNetns(t *testing.T) {
+	collectorConfig := CollectorConfig{
+		DriverInfo: driver_info.CollectConfig{CollectCommon: true},
+		DataSource: StaticDataSource{"eth0": {"driver_info": "driver: ixgbe\n"}},
+	}
+	calls := 0
+	RunInEthtoolNetns(collectorConfig, func(namespaceConfig CollectorConfig) {
+		calls++
+		assert.Equal(t, collectorConfig, namespaceConfig)
+	})
+	assert.Equal(t, 1, calls)
+
+	// Function is still called if namespace cannot be entered, but ethtool collectors fail
+	collectorConfig.EthtoolNetnsPath = "non_existent_netns"
+	RunInEthtoolNetns(collectorConfig, func(namespaceConfig CollectorConfig) {
+		calls++
+		metricRegistry, readErrors := CollectInterfaceMetricsWithErrors("eth0", namespaceConfig)
+		assert.Empty(t, metricRegistry)
+		assert.ErrorContains(t, readErrors["driver_info"], "non_existent_netns")
+	})
+	assert.Equal(t, 2, calls)
+
+	// Entering own namespace still requires CAP_SYS_ADMIN
+	collectorConfig.EthtoolNetnsPath = "/proc/self/ns/net"
+	var namespaceConfig CollectorConfig
+	RunInEthtoolNetns(collectorConfig, func(config CollectorConfig) { namespaceConfig = config })
+	if namespaceConfig.ethtoolNetnsErr != nil {
+		t.Skip("Cannot enter network namespace")
+	}
+	assert.Empty(t, namespaceConfig.EthtoolNetnsPath)
+	assert.NotEmpty(t, CollectInterfaceMetrics("eth0", namespaceConfig))
+}
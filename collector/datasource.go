@@ -0,0 +1,96 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DataSource provides raw ethtool output of the interface for the collector, eg `ethtool -m eth0` for `module_info`.
+// Output is parsed by go-ethtool-metrics, so it has to be in the same format ethtool prints.
+type DataSource interface {
+	ReadEthtoolData(interfaceName string, collectorName string) (string, error)
+}
+
+// ExecDataSource runs ethtool binary, it is used if `CollectorConfig.DataSource` is not set
+type ExecDataSource struct {
+	Path    string
+	Timeout time.Duration
+}
+
+func (source ExecDataSource) ReadEthtoolData(interfaceName string, collectorName string) (string, error) {
+	ethtoolMode, err := getEthtoolMode(collectorName)
+	if err != nil {
+		return "", err
+	}
+	return readEthtoolData(interfaceName, ethtoolMode, source.Path, source.Timeout)
+}
+
+// ReplayDataSource reads recorded output of extracted `report` archive,
+// `<Directory>/testdata/<vendor>/<driver>/<NN>_<interface>/src/<collector>`.
+// Layout is borrowed from go-ethtool-metrics testdata, but its instances are named by description
+// instead of interface, eg `02_yacloud_kernel_6_8`, so they cannot be replayed as is.
+type ReplayDataSource struct {
+	Directory string
+}
+
+func (source ReplayDataSource) ReadEthtoolData(interfaceName string, collectorName string) (string, error) {
+	instanceDirectories, err := filepath.Glob(path.Join(source.Directory, "testdata/*/*/*_*"))
+	if err != nil {
+		return "", err
+	}
+	for _, instanceDirectory := range instanceDirectories {
+		_, instanceName, found := strings.Cut(path.Base(instanceDirectory), "_")
+		if !found || instanceName != interfaceName {
+			continue
+		}
+		ethtoolOutputRaw, err := os.ReadFile(path.Join(instanceDirectory, "src", collectorName))
+		if err != nil {
+			return "", err
+		}
+		return string(ethtoolOutputRaw), nil
+	}
+	return "", fmt.Errorf("no recorded output for interface %s in %s", interfaceName, source.Directory)
+}
+
+// StaticDataSource keeps output in memory, keyed by interface and collector names, eg for tests without forking ethtool
+type StaticDataSource map[string]map[string]string
+
+func (source StaticDataSource) ReadEthtoolData(interfaceName string, collectorName string) (string, error) {
+	ethtoolOutput, found := source[interfaceName][collectorName]
+	if !found {
+		return "", fmt.Errorf("no %s output for interface %s", collectorName, interfaceName)
+	}
+	return ethtoolOutput, nil
+}
+
+func getEthtoolMode(collectorName string) (string, error) {
+	for _, mode := range EthtoolModes {
+		if mode.Collector == collectorName {
+			return mode.EthtoolMode, nil
+		}
+	}
+	return "", fmt.Errorf("unknown ethtool collector %s", collectorName)
+}
+
+func readEthtoolData(interfaceName string, ethtoolMode string, ethtoolPath string, ethtoolTimeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ethtoolTimeout)
+	defer cancel()
+
+	ethtoolArgs := []string{}
+	if ethtoolMode != "" {
+		ethtoolArgs = append(ethtoolArgs, ethtoolMode)
+	}
+	ethtoolArgs = append(ethtoolArgs, interfaceName)
+
+	ethtoolOutputRaw, err := exec.CommandContext(ctx, ethtoolPath, ethtoolArgs...).Output()
+	if err != nil {
+		return "", fmt.Errorf("cannot run %s %s: %w", ethtoolPath, strings.Join(ethtoolArgs, " "), err)
+	}
+	return string(ethtoolOutputRaw), nil
+}
@@ -0,0 +1,373 @@
+//go:build linux
+
+package collector
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	netlinkGenericHeaderSize = 4
+	netlinkReceiveBuffer     = 64 * 1024
+	nlaTypeMask              = ^uint16(unix.NLA_F_NESTED | unix.NLA_F_NET_BYTEORDER)
+	// Not defined in x/sys/unix, see include/uapi/linux/ethtool.h
+	ethtoolSpeedUnknown = 0xffffffff
+	ethtoolDuplexHalf   = 0
+	ethtoolDuplexFull   = 1
+	ethtoolXcvrExternal = 1
+)
+
+var (
+	// Named as ethtool prints them, eg `Port: FIBRE`
+	ethtoolPortNames = map[uint8]string{
+		0x00: "Twisted Pair",
+		0x01: "AUI",
+		0x02: "BNC",
+		0x03: "MII",
+		0x04: "FIBRE",
+		0x05: "Direct Attach Copper",
+		0xef: "None",
+		0xff: "Other",
+	}
+	// Link mode bits that are ports, in the order ethtool prints them in `Supported ports`
+	ethtoolPortModes    = []string{"TP", "AUI", "BNC", "MII", "FIBRE", "Backplane"}
+	ethtoolMessageNames = map[uint8]string{
+		unix.ETHTOOL_MSG_LINKINFO_GET:  "link info",
+		unix.ETHTOOL_MSG_LINKMODES_GET: "link modes",
+		unix.ETHTOOL_MSG_LINKSTATE_GET: "link state",
+	}
+	// Kernel bit names of FEC link modes and their ethtool names
+	ethtoolFecModes = map[string]string{"None": "None", "RS": "RS", "BASER": "BaseR", "LLRS": "LLRS"}
+)
+
+// NetlinkDataSource reads `generic_info` with ETHTOOL_MSG_LINKINFO_GET, ETHTOOL_MSG_LINKMODES_GET and ETHTOOL_MSG_LINKSTATE_GET,
+// formatted the same way `ethtool <interface>` prints them.
+// Other collectors need ioctl or module EEPROM decoding, so they are read from the Fallback source.
+type NetlinkDataSource struct {
+	Fallback DataSource
+	// Timeout of every netlink reply, zero means no timeout
+	Timeout time.Duration
+}
+
+type netlinkAttribute struct {
+	Type uint16
+	Data []byte
+}
+
+// Bit of ethtool verbose bitset, Value is not set for bits that are only present in the mask
+type ethtoolBit struct {
+	Name  string
+	Value bool
+}
+
+func (source NetlinkDataSource) ReadEthtoolData(interfaceName string, collectorName string) (string, error) {
+	if collectorName != "generic_info" {
+		if source.Fallback == nil {
+			return "", fmt.Errorf("%s cannot be read over netlink and there is no fallback data source", collectorName)
+		}
+		return source.Fallback.ReadEthtoolData(interfaceName, collectorName)
+	}
+
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_GENERIC)
+	if err != nil {
+		return "", fmt.Errorf("cannot create netlink socket: %w", err)
+	}
+	defer unix.Close(fd)
+	if source.Timeout > 0 {
+		timeout := unix.NsecToTimeval(source.Timeout.Nanoseconds())
+		err = unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &timeout)
+		if err != nil {
+			return "", fmt.Errorf("cannot set netlink socket timeout: %w", err)
+		}
+	}
+
+	familyAttributes, err := netlinkRequest(fd, unix.GENL_ID_CTRL, unix.CTRL_CMD_GETFAMILY, 1,
+		newNetlinkAttribute(unix.CTRL_ATTR_FAMILY_NAME, append([]byte(unix.ETHTOOL_GENL_NAME), 0)))
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve generic netlink family <%s>: %w", unix.ETHTOOL_GENL_NAME, err)
+	}
+	var familyID uint16
+	for _, attribute := range familyAttributes {
+		if attribute.Type == unix.CTRL_ATTR_FAMILY_ID && len(attribute.Data) >= 2 {
+			familyID = binary.NativeEndian.Uint16(attribute.Data)
+		}
+	}
+	if familyID == 0 {
+		return "", fmt.Errorf("generic netlink family <%s> has no ID", unix.ETHTOOL_GENL_NAME)
+	}
+
+	// Every ethtool request has the header with device name as its first attribute
+	header := newNetlinkAttribute(unix.ETHTOOL_A_HEADER_DEV_NAME, append([]byte(interfaceName), 0))
+	replies := map[uint8][]netlinkAttribute{}
+	for _, command := range []uint8{unix.ETHTOOL_MSG_LINKINFO_GET, unix.ETHTOOL_MSG_LINKMODES_GET, unix.ETHTOOL_MSG_LINKSTATE_GET} {
+		replies[command], err = netlinkRequest(fd, familyID, command, unix.ETHTOOL_GENL_VERSION, newNetlinkAttribute(1|unix.NLA_F_NESTED, header))
+		// Same as ethtool, settings that driver doesn't support are not printed, eg only link state of loopback
+		if errors.Is(err, unix.EOPNOTSUPP) {
+			continue
+		} else if err != nil {
+			return "", fmt.Errorf("cannot read %s over ethtool netlink for interface %s: %w", ethtoolMessageNames[command], interfaceName, err)
+		}
+	}
+	return formatNetlinkGenericInfo(interfaceName, replies[unix.ETHTOOL_MSG_LINKINFO_GET], replies[unix.ETHTOOL_MSG_LINKMODES_GET], replies[unix.ETHTOOL_MSG_LINKSTATE_GET]), nil
+}
+
+func netlinkAlign(length int) int {
+	return (length + unix.NLMSG_ALIGNTO - 1) & ^(unix.NLMSG_ALIGNTO - 1)
+}
+
+func newNetlinkAttribute(attributeType uint16, data []byte) []byte {
+	attributeLength := unix.SizeofNlAttr + len(data)
+	attribute := make([]byte, netlinkAlign(attributeLength))
+	binary.NativeEndian.PutUint16(attribute[0:2], uint16(attributeLength))
+	binary.NativeEndian.PutUint16(attribute[2:4], attributeType)
+	copy(attribute[unix.SizeofNlAttr:], data)
+	return attribute
+}
+
+func parseNetlinkAttributes(data []byte) []netlinkAttribute {
+	attributes := []netlinkAttribute{}
+	for len(data) >= unix.SizeofNlAttr {
+		attributeLength := int(binary.NativeEndian.Uint16(data[0:2]))
+		if attributeLength < unix.SizeofNlAttr || attributeLength > len(data) {
+			break
+		}
+		attributes = append(attributes, netlinkAttribute{
+			Type: binary.NativeEndian.Uint16(data[2:4]) & nlaTypeMask,
+			Data: data[unix.SizeofNlAttr:attributeLength],
+		})
+		data = data[min(netlinkAlign(attributeLength), len(data)):]
+	}
+	return attributes
+}
+
+// Sends generic netlink request and returns attributes of its only reply
+func netlinkRequest(fd int, familyID uint16, command uint8, version uint8, attributes []byte) ([]netlinkAttribute, error) {
+	payload := append([]byte{command, version, 0, 0}, attributes...)
+	messageLength := unix.SizeofNlMsghdr + len(payload)
+	request := make([]byte, netlinkAlign(messageLength))
+	binary.NativeEndian.PutUint32(request[0:4], uint32(messageLength))
+	binary.NativeEndian.PutUint16(request[4:6], familyID)
+	binary.NativeEndian.PutUint16(request[6:8], unix.NLM_F_REQUEST)
+	binary.NativeEndian.PutUint32(request[8:12], uint32(command))
+	copy(request[unix.SizeofNlMsghdr:], payload)
+	err := unix.Sendto(fd, request, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK})
+	if err != nil {
+		return nil, fmt.Errorf("cannot send netlink request: %w", err)
+	}
+
+	buffer := make([]byte, netlinkReceiveBuffer)
+	bytesRead, _, err := unix.Recvfrom(fd, buffer, 0)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read netlink reply: %w", err)
+	}
+	reply := buffer[:bytesRead]
+	if len(reply) < unix.SizeofNlMsghdr {
+		return nil, fmt.Errorf("truncated netlink reply, %d bytes", len(reply))
+	}
+	replyLength := int(binary.NativeEndian.Uint32(reply[0:4]))
+	if replyLength < unix.SizeofNlMsghdr || replyLength > len(reply) {
+		return nil, fmt.Errorf("invalid netlink reply length %d, %d bytes read", replyLength, len(reply))
+	}
+	replyData := reply[unix.SizeofNlMsghdr:replyLength]
+	if binary.NativeEndian.Uint16(reply[4:6]) == unix.NLMSG_ERROR {
+		if len(replyData) < 4 {
+			return nil, errors.New("truncated netlink error message")
+		}
+		return nil, unix.Errno(-int32(binary.NativeEndian.Uint32(replyData[0:4])))
+	}
+	if len(replyData) < netlinkGenericHeaderSize {
+		return nil, fmt.Errorf("truncated generic netlink reply, %d bytes", len(replyData))
+	}
+	return parseNetlinkAttributes(replyData[netlinkGenericHeaderSize:]), nil
+}
+
+// Verbose bitsets are sent as bits are not requested compact, with names of all the bits in mask
+func parseEthtoolBitset(data []byte) []ethtoolBit {
+	bits := []ethtoolBit{}
+	noMask := false
+	for _, attribute := range parseNetlinkAttributes(data) {
+		switch attribute.Type {
+		case unix.ETHTOOL_A_BITSET_NOMASK:
+			noMask = true
+		case unix.ETHTOOL_A_BITSET_BITS:
+			for _, bitAttribute := range parseNetlinkAttributes(attribute.Data) {
+				if bitAttribute.Type != unix.ETHTOOL_A_BITSET_BITS_BIT {
+					continue
+				}
+				bit := ethtoolBit{}
+				for _, field := range parseNetlinkAttributes(bitAttribute.Data) {
+					switch field.Type {
+					case unix.ETHTOOL_A_BITSET_BIT_NAME:
+						bit.Name = strings.TrimRight(string(field.Data), "\x00")
+					case unix.ETHTOOL_A_BITSET_BIT_VALUE:
+						bit.Value = true
+					}
+				}
+				bits = append(bits, bit)
+			}
+		}
+	}
+	if noMask {
+		for bitIndex := range bits {
+			bits[bitIndex].Value = true
+		}
+	}
+	return bits
+}
+
+func netlinkAttributeUint8(attributes []netlinkAttribute, attributeType uint16) (uint8, bool) {
+	for _, attribute := range attributes {
+		if attribute.Type == attributeType && len(attribute.Data) >= 1 {
+			return attribute.Data[0], true
+		}
+	}
+	return 0, false
+}
+
+func formatYesNo(value bool) string {
+	if value {
+		return "Yes"
+	}
+	return "No"
+}
+
+func formatPauseFrameUse(pause bool, asymmetricPause bool) string {
+	switch {
+	case pause && asymmetricPause:
+		return "Symmetric Receive-only"
+	case pause:
+		return "Symmetric"
+	case asymmetricPause:
+		return "Transmit-only"
+	}
+	return "No"
+}
+
+func formatModes(modes []string) string {
+	if len(modes) == 0 {
+		return "Not reported"
+	}
+	return strings.Join(modes, " ")
+}
+
+// Supported modes are the mask of `ours` bitset, advertised ones are its value
+func formatLinkModes(lines []string, bits []ethtoolBit, prefix string, supported bool) []string {
+	linkModes := []string{}
+	fecModes := []string{}
+	pause := false
+	asymmetricPause := false
+	autoNegotiation := false
+	for _, bit := range bits {
+		if !supported && !bit.Value {
+			continue
+		}
+		fecName, isFec := ethtoolFecModes[bit.Name]
+		switch {
+		case strings.Contains(bit.Name, "/"):
+			linkModes = append(linkModes, bit.Name)
+		case isFec:
+			fecModes = append(fecModes, fecName)
+		case bit.Name == "Pause":
+			pause = true
+		case bit.Name == "Asym_Pause":
+			asymmetricPause = true
+		case bit.Name == "Autoneg":
+			autoNegotiation = true
+		}
+	}
+	lines = append(lines,
+		fmt.Sprintf("\t%s link modes:   %s", prefix, formatModes(linkModes)),
+		fmt.Sprintf("\t%s pause frame use: %s", prefix, formatPauseFrameUse(pause, asymmetricPause)),
+	)
+	if supported {
+		lines = append(lines, fmt.Sprintf("\tSupports auto-negotiation: %s", formatYesNo(autoNegotiation)))
+	} else {
+		lines = append(lines, fmt.Sprintf("\tAdvertised auto-negotiation: %s", formatYesNo(autoNegotiation)))
+	}
+	return append(lines, fmt.Sprintf("\t%s FEC modes: %s", prefix, formatModes(fecModes)))
+}
+
+// Only fields parsed by go-ethtool-metrics are printed, missing attributes are skipped the same way ethtool does
+func formatNetlinkGenericInfo(interfaceName string, linkInfo []netlinkAttribute, linkModes []netlinkAttribute, linkState []netlinkAttribute) string {
+	lines := []string{fmt.Sprintf("Settings for %s:", interfaceName)}
+
+	var ourBits []ethtoolBit
+	for _, attribute := range linkModes {
+		if attribute.Type == unix.ETHTOOL_A_LINKMODES_OURS {
+			ourBits = parseEthtoolBitset(attribute.Data)
+		}
+	}
+	if ourBits != nil {
+		ports := []string{}
+		for _, portMode := range ethtoolPortModes {
+			for _, bit := range ourBits {
+				if bit.Name == portMode {
+					ports = append(ports, portMode)
+				}
+			}
+		}
+		lines = append(lines, fmt.Sprintf("\tSupported ports: [ %s ]", strings.Join(ports, " ")))
+		lines = formatLinkModes(lines, ourBits, "Supported", true)
+		lines = formatLinkModes(lines, ourBits, "Advertised", false)
+	}
+
+	for _, attribute := range linkModes {
+		if attribute.Type != unix.ETHTOOL_A_LINKMODES_SPEED || len(attribute.Data) < 4 {
+			continue
+		}
+		speed := binary.NativeEndian.Uint32(attribute.Data)
+		if speed == ethtoolSpeedUnknown {
+			lines = append(lines, "\tSpeed: Unknown!")
+		} else {
+			lines = append(lines, fmt.Sprintf("\tSpeed: %dMb/s", speed))
+		}
+	}
+	if duplex, found := netlinkAttributeUint8(linkModes, unix.ETHTOOL_A_LINKMODES_DUPLEX); found {
+		switch duplex {
+		case ethtoolDuplexHalf:
+			lines = append(lines, "\tDuplex: Half")
+		case ethtoolDuplexFull:
+			lines = append(lines, "\tDuplex: Full")
+		default:
+			lines = append(lines, "\tDuplex: Unknown!")
+		}
+	}
+	if port, found := netlinkAttributeUint8(linkInfo, unix.ETHTOOL_A_LINKINFO_PORT); found {
+		portName, known := ethtoolPortNames[port]
+		if !known {
+			portName = fmt.Sprintf("Unknown! (%d)", port)
+		}
+		lines = append(lines, fmt.Sprintf("\tPort: %s", portName))
+	}
+	if phyAddress, found := netlinkAttributeUint8(linkInfo, unix.ETHTOOL_A_LINKINFO_PHYADDR); found {
+		lines = append(lines, fmt.Sprintf("\tPHYAD: %d", phyAddress))
+	}
+	if transceiver, found := netlinkAttributeUint8(linkInfo, unix.ETHTOOL_A_LINKINFO_TRANSCEIVER); found {
+		if transceiver == ethtoolXcvrExternal {
+			lines = append(lines, "\tTransceiver: external")
+		} else {
+			lines = append(lines, "\tTransceiver: internal")
+		}
+	}
+	if autoNegotiation, found := netlinkAttributeUint8(linkModes, unix.ETHTOOL_A_LINKMODES_AUTONEG); found {
+		if autoNegotiation != 0 {
+			lines = append(lines, "\tAuto-negotiation: on")
+		} else {
+			lines = append(lines, "\tAuto-negotiation: off")
+		}
+	}
+	if link, found := netlinkAttributeUint8(linkState, unix.ETHTOOL_A_LINKSTATE_LINK); found {
+		if link != 0 {
+			lines = append(lines, "\tLink detected: yes")
+		} else {
+			lines = append(lines, "\tLink detected: no")
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,91 @@
+//go:build linux
+
+package collector
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+
+	"github.com/newrushbolt/go-ethtool-metrics/pkg/metrics/generic_info"
+)
+
+func newEthtoolBitset(bits []ethtoolBit) []byte {
+	bitAttributes := []byte{}
+	for _, bit := range bits {
+		fields := newNetlinkAttribute(unix.ETHTOOL_A_BITSET_BIT_NAME, append([]byte(bit.Name), 0))
+		if bit.Value {
+			fields = append(fields, newNetlinkAttribute(unix.ETHTOOL_A_BITSET_BIT_VALUE, nil)...)
+		}
+		bitAttributes = append(bitAttributes, newNetlinkAttribute(unix.ETHTOOL_A_BITSET_BITS_BIT|unix.NLA_F_NESTED, fields)...)
+	}
+	return newNetlinkAttribute(unix.ETHTOOL_A_BITSET_BITS|unix.NLA_F_NESTED, bitAttributes)
+}
+
+func TestFormatNetlinkGenericInfo(t *testing.T) {
+	linkInfo := append(newNetlinkAttribute(unix.ETHTOOL_A_LINKINFO_PORT, []byte{0x04}), newNetlinkAttribute(unix.ETHTOOL_A_LINKINFO_PHYADDR, []byte{0})...)
+	linkInfo = append(linkInfo, newNetlinkAttribute(unix.ETHTOOL_A_LINKINFO_TRANSCEIVER, []byte{0})...)
+	ourBits := newEthtoolBitset([]ethtoolBit{
+		{Name: "Autoneg", Value: true},
+		{Name: "FIBRE", Value: false},
+		{Name: "Pause", Value: false},
+		{Name: "1000baseT/Full", Value: false},
+		{Name: "10000baseSR/Full", Value: true},
+	})
+	linkModes := append(newNetlinkAttribute(unix.ETHTOOL_A_LINKMODES_AUTONEG, []byte{0}), newNetlinkAttribute(unix.ETHTOOL_A_LINKMODES_OURS|unix.NLA_F_NESTED, ourBits)...)
+	linkModes = append(linkModes, newNetlinkAttribute(unix.ETHTOOL_A_LINKMODES_SPEED, binary.NativeEndian.AppendUint32(nil, 10000))...)
+	linkModes = append(linkModes, newNetlinkAttribute(unix.ETHTOOL_A_LINKMODES_DUPLEX, []byte{ethtoolDuplexFull})...)
+	linkState := newNetlinkAttribute(unix.ETHTOOL_A_LINKSTATE_LINK, []byte{1})
+
+	output := formatNetlinkGenericInfo("eth4", parseNetlinkAttributes(linkInfo), parseNetlinkAttributes(linkModes), parseNetlinkAttributes(linkState))
+	expectedOutput := `Settings for eth4:
+	Supported ports: [ FIBRE ]
+	Supported link modes:   1000baseT/Full 10000baseSR/Full
+	Supported pause frame use: Symmetric
+	Supports auto-negotiation: Yes
+	Supported FEC modes: Not reported
+	Advertised link modes:   10000baseSR/Full
+	Advertised pause frame use: No
+	Advertised auto-negotiation: Yes
+	Advertised FEC modes: Not reported
+	Speed: 10000Mb/s
+	Duplex: Full
+	Port: FIBRE
+	PHYAD: 0
+	Transceiver: internal
+	Auto-negotiation: off
+	Link detected: yes
+`
+	assert.Equal(t, expectedOutput, output)
+
+	genericInfo := generic_info.ParseInfo(output, &generic_info.CollectConfig{CollectSettings: true, CollectSupportedSettings: true})
+	assert.Equal(t, 1e10, *genericInfo.Settings.SpeedBits)
+	assert.Equal(t, "FIBRE", genericInfo.Settings.Port)
+	assert.True(t, genericInfo.Settings.LinkDetected)
+	assert.Equal(t, []string{"1000baseT/Full", "10000baseSR/Full"}, genericInfo.SupportedSettings.LinkModes)
+
+	// Virtual devices may have no link modes
+	unknownSpeed := newNetlinkAttribute(unix.ETHTOOL_A_LINKMODES_SPEED, binary.NativeEndian.AppendUint32(nil, ethtoolSpeedUnknown))
+	output = formatNetlinkGenericInfo("veth0", nil, parseNetlinkAttributes(unknownSpeed), nil)
+	assert.Equal(t, "Settings for veth0:\n\tSpeed: Unknown!\n", output)
+}
+
+func TestNetlinkDataSource(t *testing.T) {
+	source := NetlinkDataSource{
+		Fallback: StaticDataSource{"eth0": {"driver_info": "driver: ixgbe\n"}},
+		Timeout:  time.Second,
+	}
+	output, err := source.ReadEthtoolData("eth0", "driver_info")
+	assert.NoError(t, err)
+	assert.Equal(t, "driver: ixgbe\n", output)
+
+	_, err = source.ReadEthtoolData("non_existent0", "generic_info")
+	assert.Error(t, err)
+
+	source.Fallback = nil
+	_, err = source.ReadEthtoolData("eth0", "driver_info")
+	assert.Error(t, err)
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package collector
+
+import (
+	"errors"
+	"time"
+)
+
+type NetlinkDataSource struct {
+	Fallback DataSource
+	Timeout  time.Duration
+}
+
+func (source NetlinkDataSource) ReadEthtoolData(interfaceName string, collectorName string) (string, error) {
+	return "", errors.New("ethtool netlink is only supported on Linux")
+}
@@ -0,0 +1,73 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/newrushbolt/go-ethtool-metrics/pkg/metrics/driver_info"
+)
+
+func TestExecDataSource(t *testing.T) {
+	source := ExecDataSource{Path: "../testdata/ethtool.sh", Timeout: 1 * time.Second}
+	out, err := source.ReadEthtoolData("eth0", "module_info")
+	assert.NoError(t, err)
+	assert.Equal(t, "module info for eth0\n", out)
+
+	_, err = source.ReadEthtoolData("eth0", "bond_info")
+	assert.Error(t, err)
+
+	source.Path = "../testdata/non_existed_ethtool.sh"
+	_, err = source.ReadEthtoolData("eth0", "module_info")
+	assert.Error(t, err)
+}
+
+func TestReplayDataSource(t *testing.T) {
+	source := ReplayDataSource{Directory: "../testdata/replay"}
+	out, err := source.ReadEthtoolData("eth4", "statistics")
+	assert.NoError(t, err)
+	assert.Equal(t, "statistics for eth4\n", out)
+
+	_, err = source.ReadEthtoolData("eth4", "non_existent")
+	assert.Error(t, err)
+	_, err = source.ReadEthtoolData("eth9", "statistics")
+	assert.Error(t, err)
+}
+
+func TestStaticDataSource(t *testing.T) {
+	source := StaticDataSource{"eth0": {"driver_info": "driver: ixgbe\n"}}
+	out, err := source.ReadEthtoolData("eth0", "driver_info")
+	assert.NoError(t, err)
+	assert.Equal(t, "driver: ixgbe\n", out)
+
+	_, err = source.ReadEthtoolData("eth0", "statistics")
+	assert.Error(t, err)
+	_, err = source.ReadEthtoolData("eth1", "driver_info")
+	assert.Error(t, err)
+}
+
+func TestCollectInterfaceData(t *testing.T) {
+	collectorConfig := CollectorConfig{
+		DriverInfo: driver_info.CollectConfig{CollectCommon: true},
+		DataSource: StaticDataSource{"bond0": {"driver_info": "driver: bonding\n"}},
+		BondInfo: BondInfoConfig{
+			Collect:            true,
+			ProcNetBondingPath: "../testdata/proc/net/bonding",
+			NetClassPath:       "../testdata/interfaces/sys/class/net",
+		},
+	}
+
+	interfaceData := CollectInterfaceData("bond0", collectorConfig)
+
+	assert.Len(t, interfaceData, 2)
+	driverInfo := interfaceData["driver_info"].(*driver_info.DriverInfo)
+	assert.Equal(t, "bonding", driverInfo.Common.DriverName)
+	bondData := interfaceData["bond_info"].(*BondData)
+	assert.NotNil(t, bondData.Bond)
+	assert.NotEmpty(t, bondData.Slaves)
+
+	interfaceData = CollectInterfaceData("eth0", collectorConfig)
+	assert.Len(t, interfaceData, 1)
+	assert.NotContains(t, interfaceData, "bond_info")
+}
@@ -0,0 +1,188 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/newrushbolt/go-ethtool-exporter/registry"
+)
+
+const (
+	EventsTotalMetricName         = "link_events_total"
+	EventsLastTimestampMetricName = "link_events_last_timestamp_seconds"
+)
+
+type EventType string
+
+const (
+	LinkAdded    EventType = "link_added"
+	LinkRemoved  EventType = "link_removed"
+	LinkRenamed  EventType = "link_renamed"
+	LinkUp       EventType = "link_up"
+	LinkDown     EventType = "link_down"
+	SpeedChange  EventType = "speed_change"
+	ModuleChange EventType = "module_change"
+)
+
+type LinkEvent struct {
+	Device string
+	Type   EventType
+	Time   time.Time
+	// Name before the rename, only set for LinkRenamed
+	PreviousDevice string
+}
+
+// EventSource produces link events until the context is cancelled.
+// Run should return only on fatal errors or when the context is done.
+type EventSource interface {
+	Run(ctx context.Context, events chan<- LinkEvent) error
+}
+
+type eventState struct {
+	Count    uint64
+	LastSeen time.Time
+}
+
+type Watcher struct {
+	source EventSource
+	mutex  sync.Mutex
+	// Device name -> event type -> state
+	devices     map[string]map[EventType]*eventState
+	subscribers []chan<- LinkEvent
+}
+
+func NewWatcher(source EventSource) *Watcher {
+	return &Watcher{
+		source:  source,
+		devices: map[string]map[EventType]*eventState{},
+	}
+}
+
+// Subscribe allows other subsystems to get every event after it was recorded.
+// Events are dropped for subscribers that are not ready to receive them.
+func (watcher *Watcher) Subscribe(subscriber chan<- LinkEvent) {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+	watcher.subscribers = append(watcher.subscribers, subscriber)
+}
+
+// Start runs the event source in background, restarting it after failures
+func (watcher *Watcher) Start(ctx context.Context, restartInterval time.Duration) {
+	eventsChan := make(chan LinkEvent, 64)
+	go func() {
+		for {
+			err := watcher.source.Run(ctx, eventsChan)
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Error("Link event source failed, restarting", "error", err, "restartInterval", restartInterval)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(restartInterval):
+			}
+		}
+	}()
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-eventsChan:
+				watcher.Record(event)
+			}
+		}
+	}()
+}
+
+// Record counts the event and passes it to subscribers.
+// State of removed and renamed devices is dropped, so veth or container churn doesn't grow it without bound.
+func (watcher *Watcher) Record(event LinkEvent) {
+	slog.Debug("Got link event", "device", event.Device, "event", event.Type, "time", event.Time)
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	switch event.Type {
+	case LinkRemoved:
+		delete(watcher.devices, event.Device)
+	case LinkRenamed:
+		delete(watcher.devices, event.PreviousDevice)
+	}
+	if event.Type != LinkRemoved {
+		watcher.countEvent(event)
+	}
+
+	for _, subscriber := range watcher.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			slog.Debug("Subscriber is not ready, dropping link event", "device", event.Device, "event", event.Type)
+		}
+	}
+}
+
+// Mutex must be already held by the caller
+func (watcher *Watcher) countEvent(event LinkEvent) {
+	deviceEvents, ok := watcher.devices[event.Device]
+	if !ok {
+		deviceEvents = map[EventType]*eventState{}
+		watcher.devices[event.Device] = deviceEvents
+	}
+	state, ok := deviceEvents[event.Type]
+	if !ok {
+		state = &eventState{}
+		deviceEvents[event.Type] = state
+	}
+	state.Count++
+	if event.Time.After(state.LastSeen) {
+		state.LastSeen = event.Time
+	}
+}
+
+// GetDeviceRegistry returns event counters and timestamps for the device.
+// Devices without any recorded events get an empty registry.
+func (watcher *Watcher) GetDeviceRegistry(device string) registry.Registry {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	metricRegistry := registry.Registry{}
+	deviceEvents, ok := watcher.devices[device]
+	if !ok {
+		return metricRegistry
+	}
+	for _, eventType := range slices.Sorted(maps.Keys(deviceEvents)) {
+		state := deviceEvents[eventType]
+		labels := map[string]string{
+			"device": device,
+			"event":  string(eventType),
+		}
+		metricRegistry = append(metricRegistry, registry.MetricRecord{
+			Name:   EventsTotalMetricName,
+			Labels: labels,
+			Value:  float64(state.Count),
+		})
+		metricRegistry = append(metricRegistry, registry.MetricRecord{
+			Name:   EventsLastTimestampMetricName,
+			Labels: maps.Clone(labels),
+			Value:  float64(state.LastSeen.UnixMilli()) / 1000,
+		})
+	}
+	return metricRegistry
+}
+
+// GetRegistryCollection returns registries for all devices that had any events
+func (watcher *Watcher) GetRegistryCollection() registry.RegistryCollection {
+	watcher.mutex.Lock()
+	devices := slices.Collect(maps.Keys(watcher.devices))
+	watcher.mutex.Unlock()
+
+	collection := registry.RegistryCollection{}
+	for _, device := range devices {
+		collection[device] = watcher.GetDeviceRegistry(device)
+	}
+	return collection
+}
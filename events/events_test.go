@@ -0,0 +1,104 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSource struct {
+	events []LinkEvent
+	err    error
+}
+
+func (source *fakeSource) Run(ctx context.Context, events chan<- LinkEvent) error {
+	for _, event := range source.events {
+		events <- event
+	}
+	if source.err != nil {
+		return source.err
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestWatcherRecord(t *testing.T) {
+	expectedMetrics := `link_events_total{device="eth0",event="link_down"} 2
+link_events_last_timestamp_seconds{device="eth0",event="link_down"} 1.7e+09
+link_events_total{device="eth0",event="link_up"} 1
+link_events_last_timestamp_seconds{device="eth0",event="link_up"} 1.7000000015e+09`
+
+	watcher := NewWatcher(&fakeSource{})
+	watcher.Record(LinkEvent{Device: "eth0", Type: LinkDown, Time: time.Unix(1700000000, 0)})
+	watcher.Record(LinkEvent{Device: "eth0", Type: LinkUp, Time: time.Unix(1700000001, 500000000)})
+	// Older events should not move timestamp back
+	watcher.Record(LinkEvent{Device: "eth0", Type: LinkDown, Time: time.Unix(1600000000, 0)})
+
+	metricRegistry := watcher.GetDeviceRegistry("eth0")
+	assert.Equal(t, expectedMetrics, metricRegistry.FormatTextfileString())
+	assert.Empty(t, watcher.GetDeviceRegistry("eth1"))
+}
+
+func TestWatcherForgetsRemovedDevices(t *testing.T) {
+	watcher := NewWatcher(&fakeSource{})
+	subscriber := make(chan LinkEvent, 1)
+	watcher.Subscribe(subscriber)
+	watcher.Record(LinkEvent{Device: "veth0", Type: LinkAdded, Time: time.Unix(1700000000, 0)})
+	watcher.Record(LinkEvent{Device: "eth0", Type: LinkDown, Time: time.Unix(1700000000, 0)})
+	watcher.Record(LinkEvent{Device: "uplink0", Type: LinkRenamed, Time: time.Unix(1700000001, 0), PreviousDevice: "eth0"})
+	assert.Len(t, watcher.GetRegistryCollection(), 2)
+	assert.Empty(t, watcher.GetDeviceRegistry("eth0"))
+	assert.Len(t, watcher.GetDeviceRegistry("uplink0"), 2)
+
+	<-subscriber
+	removedEvent := LinkEvent{Device: "veth0", Type: LinkRemoved, Time: time.Unix(1700000002, 0)}
+	watcher.Record(removedEvent)
+	// Subscribers still get the event
+	assert.Equal(t, removedEvent, <-subscriber)
+	assert.Empty(t, watcher.GetDeviceRegistry("veth0"))
+	assert.Len(t, watcher.GetRegistryCollection(), 1)
+}
+
+func TestWatcherStart(t *testing.T) {
+	source := &fakeSource{
+		events: []LinkEvent{
+			{Device: "eth0", Type: LinkUp, Time: time.Unix(1700000000, 0)},
+			{Device: "eth1", Type: SpeedChange, Time: time.Unix(1700000000, 0)},
+		},
+	}
+	watcher := NewWatcher(source)
+	subscriber := make(chan LinkEvent, 2)
+	watcher.Subscribe(subscriber)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watcher.Start(ctx, time.Second)
+
+	assert.Equal(t, source.events[0], <-subscriber)
+	assert.Equal(t, source.events[1], <-subscriber)
+	collection := watcher.GetRegistryCollection()
+	assert.Len(t, collection, 2)
+	assert.Len(t, collection["eth1"], 2)
+}
+
+func TestWatcherRestartsFailedSource(t *testing.T) {
+	source := &fakeSource{
+		events: []LinkEvent{{Device: "eth0", Type: ModuleChange, Time: time.Unix(1700000000, 0)}},
+		err:    errors.New("socket closed"),
+	}
+	watcher := NewWatcher(source)
+	subscriber := make(chan LinkEvent, 2)
+	watcher.Subscribe(subscriber)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watcher.Start(ctx, 10*time.Millisecond)
+
+	<-subscriber
+	<-subscriber
+	metricRegistry := watcher.GetDeviceRegistry("eth0")
+	assert.GreaterOrEqual(t, metricRegistry[0].Value, float64(2))
+}
@@ -0,0 +1,429 @@
+//go:build linux
+
+package events
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// Not defined in x/sys/unix
+	ifOperUnknown = 0
+	ifOperUp      = 6
+	nlaTypeMask   = ^uint16(unix.NLA_F_NESTED | unix.NLA_F_NET_BYTEORDER)
+	// Every ethtool netlink message has the request header as the first attribute
+	ethtoolHeaderAttribute = 1
+	ethtoolSpeedUnknown    = 0xffffffff
+	genericHeaderSize      = 4
+	linkDumpSequence       = 1
+	netlinkReceiveBuffer   = 64 * 1024
+	netlinkPollTimeout     = 1000
+)
+
+type netlinkMessage struct {
+	Header unix.NlMsghdr
+	Data   []byte
+}
+
+type netlinkAttribute struct {
+	Type uint16
+	Data []byte
+}
+
+type linkInfo struct {
+	Index int32
+	Name  string
+	Up    bool
+}
+
+// NetlinkSource subscribes to RTNLGRP_LINK notifications and, optionally, to ethtool netlink monitor group.
+// Link states are seeded from RTM_GETLINK dump, so only changes after the start are reported.
+type NetlinkSource struct {
+	// Subscribe to ethtool netlink "monitor" multicast group in addition to RTNLGRP_LINK
+	EthtoolMonitor bool
+	// Used to read current link speed after the link goes up
+	NetClassPath string
+
+	links  map[int32]linkInfo
+	speeds map[string]uint64
+}
+
+func netlinkAlign(length int) int {
+	return (length + unix.NLMSG_ALIGNTO - 1) & ^(unix.NLMSG_ALIGNTO - 1)
+}
+
+func parseNetlinkMessages(data []byte) ([]netlinkMessage, error) {
+	messages := []netlinkMessage{}
+	for len(data) >= unix.SizeofNlMsghdr {
+		header := unix.NlMsghdr{
+			Len:   binary.NativeEndian.Uint32(data[0:4]),
+			Type:  binary.NativeEndian.Uint16(data[4:6]),
+			Flags: binary.NativeEndian.Uint16(data[6:8]),
+			Seq:   binary.NativeEndian.Uint32(data[8:12]),
+			Pid:   binary.NativeEndian.Uint32(data[12:16]),
+		}
+		if header.Len < unix.SizeofNlMsghdr || int(header.Len) > len(data) {
+			return messages, fmt.Errorf("invalid netlink message length %d, %d bytes left", header.Len, len(data))
+		}
+		messages = append(messages, netlinkMessage{
+			Header: header,
+			Data:   data[unix.SizeofNlMsghdr:header.Len],
+		})
+		data = data[min(netlinkAlign(int(header.Len)), len(data)):]
+	}
+	return messages, nil
+}
+
+func parseNetlinkAttributes(data []byte) []netlinkAttribute {
+	attributes := []netlinkAttribute{}
+	for len(data) >= unix.SizeofNlAttr {
+		attributeLength := int(binary.NativeEndian.Uint16(data[0:2]))
+		attributeType := binary.NativeEndian.Uint16(data[2:4])
+		if attributeLength < unix.SizeofNlAttr || attributeLength > len(data) {
+			break
+		}
+		attributes = append(attributes, netlinkAttribute{
+			Type: attributeType & nlaTypeMask,
+			Data: data[unix.SizeofNlAttr:attributeLength],
+		})
+		data = data[min(netlinkAlign(attributeLength), len(data)):]
+	}
+	return attributes
+}
+
+func netlinkAttributeString(attribute netlinkAttribute) string {
+	return strings.TrimRight(string(attribute.Data), "\x00")
+}
+
+func newNetlinkAttribute(attributeType uint16, data []byte) []byte {
+	attributeLength := unix.SizeofNlAttr + len(data)
+	attribute := make([]byte, netlinkAlign(attributeLength))
+	binary.NativeEndian.PutUint16(attribute[0:2], uint16(attributeLength))
+	binary.NativeEndian.PutUint16(attribute[2:4], attributeType)
+	copy(attribute[unix.SizeofNlAttr:], data)
+	return attribute
+}
+
+func newNetlinkRequest(messageType uint16, flags uint16, sequence uint32, payload []byte) []byte {
+	messageLength := unix.SizeofNlMsghdr + len(payload)
+	message := make([]byte, netlinkAlign(messageLength))
+	binary.NativeEndian.PutUint32(message[0:4], uint32(messageLength))
+	binary.NativeEndian.PutUint16(message[4:6], messageType)
+	binary.NativeEndian.PutUint16(message[6:8], flags|unix.NLM_F_REQUEST)
+	binary.NativeEndian.PutUint32(message[8:12], sequence)
+	copy(message[unix.SizeofNlMsghdr:], payload)
+	return message
+}
+
+func parseNetlinkError(message netlinkMessage) error {
+	if len(message.Data) < 4 {
+		return errors.New("truncated netlink error message")
+	}
+	errorCode := int32(binary.NativeEndian.Uint32(message.Data[0:4]))
+	if errorCode == 0 {
+		return nil
+	}
+	return unix.Errno(-errorCode)
+}
+
+func parseLinkMessage(data []byte) (linkInfo, error) {
+	link := linkInfo{}
+	if len(data) < unix.SizeofIfInfomsg {
+		return link, fmt.Errorf("truncated link message, %d bytes", len(data))
+	}
+	link.Index = int32(binary.NativeEndian.Uint32(data[4:8]))
+	flags := binary.NativeEndian.Uint32(data[8:12])
+	link.Up = flags&unix.IFF_LOWER_UP != 0
+
+	for _, attribute := range parseNetlinkAttributes(data[unix.SizeofIfInfomsg:]) {
+		switch attribute.Type {
+		case unix.IFLA_IFNAME:
+			link.Name = netlinkAttributeString(attribute)
+		case unix.IFLA_OPERSTATE:
+			// Virtual devices often report "unknown", so only trust flags for them
+			if len(attribute.Data) > 0 && attribute.Data[0] != ifOperUnknown {
+				link.Up = attribute.Data[0] == ifOperUp
+			}
+		}
+	}
+	if link.Name == "" {
+		return link, fmt.Errorf("link message for index %d has no name", link.Index)
+	}
+	return link, nil
+}
+
+func openNetlinkSocket(protocol int, groups uint32) (int, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, protocol)
+	if err != nil {
+		return -1, fmt.Errorf("cannot create netlink socket: %w", err)
+	}
+	err = unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: groups})
+	if err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("cannot bind netlink socket: %w", err)
+	}
+	return fd, nil
+}
+
+// Resolves generic netlink family ID and multicast group ID by their names
+func resolveGenericGroup(fd int, familyName string, groupName string) (uint16, uint32, error) {
+	payload := []byte{unix.CTRL_CMD_GETFAMILY, 1, 0, 0}
+	payload = append(payload, newNetlinkAttribute(unix.CTRL_ATTR_FAMILY_NAME, append([]byte(familyName), 0))...)
+	request := newNetlinkRequest(unix.GENL_ID_CTRL, 0, 1, payload)
+	err := unix.Sendto(fd, request, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK})
+	if err != nil {
+		return 0, 0, fmt.Errorf("cannot send generic netlink family request: %w", err)
+	}
+
+	buffer := make([]byte, netlinkReceiveBuffer)
+	bytesRead, _, err := unix.Recvfrom(fd, buffer, 0)
+	if err != nil {
+		return 0, 0, fmt.Errorf("cannot read generic netlink family reply: %w", err)
+	}
+	messages, err := parseNetlinkMessages(buffer[:bytesRead])
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, message := range messages {
+		if message.Header.Type == unix.NLMSG_ERROR {
+			return 0, 0, fmt.Errorf("cannot resolve generic netlink family <%s>: %w", familyName, parseNetlinkError(message))
+		}
+		if message.Header.Type != unix.GENL_ID_CTRL || len(message.Data) < genericHeaderSize {
+			continue
+		}
+		var familyID uint16
+		var groupID uint32
+		for _, attribute := range parseNetlinkAttributes(message.Data[genericHeaderSize:]) {
+			switch attribute.Type {
+			case unix.CTRL_ATTR_FAMILY_ID:
+				if len(attribute.Data) < 2 {
+					continue
+				}
+				familyID = binary.NativeEndian.Uint16(attribute.Data)
+			case unix.CTRL_ATTR_MCAST_GROUPS:
+				for _, group := range parseNetlinkAttributes(attribute.Data) {
+					var name string
+					var id uint32
+					for _, groupAttribute := range parseNetlinkAttributes(group.Data) {
+						switch groupAttribute.Type {
+						case unix.CTRL_ATTR_MCAST_GRP_NAME:
+							name = netlinkAttributeString(groupAttribute)
+						case unix.CTRL_ATTR_MCAST_GRP_ID:
+							if len(groupAttribute.Data) < 4 {
+								continue
+							}
+							id = binary.NativeEndian.Uint32(groupAttribute.Data)
+						}
+					}
+					if name == groupName {
+						groupID = id
+					}
+				}
+			}
+		}
+		if familyID == 0 || groupID == 0 {
+			return 0, 0, fmt.Errorf("generic netlink family <%s> has no multicast group <%s>", familyName, groupName)
+		}
+		return familyID, groupID, nil
+	}
+	return 0, 0, fmt.Errorf("no reply for generic netlink family <%s>", familyName)
+}
+
+func (source *NetlinkSource) readSpeed(device string) uint64 {
+	speedPath := path.Join(source.NetClassPath, device, "speed")
+	speedRaw, err := os.ReadFile(speedPath)
+	if err != nil {
+		slog.Debug("Cannot read link speed", "device", device, "error", err)
+		return 0
+	}
+	speed, err := strconv.ParseInt(strings.TrimSpace(string(speedRaw)), 10, 64)
+	// Unknown speed is exposed as -1
+	if err != nil || speed <= 0 {
+		return 0
+	}
+	return uint64(speed)
+}
+
+func (source *NetlinkSource) updateSpeed(device string, speed uint64, now time.Time, emit func(LinkEvent)) {
+	if speed == 0 {
+		return
+	}
+	previousSpeed, known := source.speeds[device]
+	source.speeds[device] = speed
+	if known && previousSpeed != speed {
+		emit(LinkEvent{Device: device, Type: SpeedChange, Time: now})
+	}
+}
+
+func (source *NetlinkSource) handleRouteMessage(message netlinkMessage, now time.Time, emit func(LinkEvent)) {
+	if message.Header.Type != unix.RTM_NEWLINK && message.Header.Type != unix.RTM_DELLINK {
+		return
+	}
+	link, err := parseLinkMessage(message.Data)
+	if err != nil {
+		slog.Debug("Cannot parse link message", "error", err)
+		return
+	}
+	// Replies to our own dump only seed the state, notifications always have zero sequence
+	seeding := message.Header.Seq == linkDumpSequence
+	previous, known := source.links[link.Index]
+
+	if message.Header.Type == unix.RTM_DELLINK {
+		delete(source.links, link.Index)
+		delete(source.speeds, link.Name)
+		if !seeding {
+			emit(LinkEvent{Device: link.Name, Type: LinkRemoved, Time: now})
+		}
+		return
+	}
+
+	source.links[link.Index] = link
+	if seeding {
+		if link.Up {
+			source.updateSpeed(link.Name, source.readSpeed(link.Name), now, emit)
+		}
+		return
+	}
+	if !known {
+		emit(LinkEvent{Device: link.Name, Type: LinkAdded, Time: now})
+	} else if previous.Name != link.Name {
+		delete(source.speeds, previous.Name)
+		emit(LinkEvent{Device: link.Name, Type: LinkRenamed, Time: now, PreviousDevice: previous.Name})
+	}
+	if previous.Up != link.Up {
+		if link.Up {
+			emit(LinkEvent{Device: link.Name, Type: LinkUp, Time: now})
+			source.updateSpeed(link.Name, source.readSpeed(link.Name), now, emit)
+		} else if known {
+			emit(LinkEvent{Device: link.Name, Type: LinkDown, Time: now})
+		}
+	}
+}
+
+// Kernel has no dedicated transceiver plug notification, module one is the closest.
+// Link info notifications are about port type, transceiver or MDI settings, not the module itself.
+func (source *NetlinkSource) handleEthtoolMessage(message netlinkMessage, now time.Time, emit func(LinkEvent)) {
+	if len(message.Data) < genericHeaderSize {
+		return
+	}
+	command := message.Data[0]
+	var device string
+	var speed uint64
+	for _, attribute := range parseNetlinkAttributes(message.Data[genericHeaderSize:]) {
+		switch {
+		case attribute.Type == ethtoolHeaderAttribute:
+			for _, headerAttribute := range parseNetlinkAttributes(attribute.Data) {
+				if headerAttribute.Type == unix.ETHTOOL_A_HEADER_DEV_NAME {
+					device = netlinkAttributeString(headerAttribute)
+				}
+			}
+		case command == unix.ETHTOOL_MSG_LINKMODES_NTF && attribute.Type == unix.ETHTOOL_A_LINKMODES_SPEED && len(attribute.Data) >= 4:
+			rawSpeed := binary.NativeEndian.Uint32(attribute.Data)
+			if rawSpeed != ethtoolSpeedUnknown {
+				speed = uint64(rawSpeed)
+			}
+		}
+	}
+	if device == "" {
+		return
+	}
+	switch command {
+	case unix.ETHTOOL_MSG_LINKMODES_NTF:
+		source.updateSpeed(device, speed, now, emit)
+	case unix.ETHTOOL_MSG_MODULE_NTF:
+		emit(LinkEvent{Device: device, Type: ModuleChange, Time: now})
+	}
+}
+
+func (source *NetlinkSource) Run(ctx context.Context, events chan<- LinkEvent) error {
+	source.links = map[int32]linkInfo{}
+	source.speeds = map[string]uint64{}
+
+	routeFd, err := openNetlinkSocket(unix.NETLINK_ROUTE, unix.RTMGRP_LINK)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(routeFd)
+	dumpRequest := newNetlinkRequest(unix.RTM_GETLINK, unix.NLM_F_DUMP, linkDumpSequence, make([]byte, unix.SizeofIfInfomsg))
+	err = unix.Sendto(routeFd, dumpRequest, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK})
+	if err != nil {
+		return fmt.Errorf("cannot request link dump: %w", err)
+	}
+	pollFds := []unix.PollFd{{Fd: int32(routeFd), Events: unix.POLLIN}}
+
+	ethtoolFamilyID := uint16(0)
+	if source.EthtoolMonitor {
+		ethtoolFd, err := openNetlinkSocket(unix.NETLINK_GENERIC, 0)
+		if err != nil {
+			return err
+		}
+		defer unix.Close(ethtoolFd)
+		var groupID uint32
+		ethtoolFamilyID, groupID, err = resolveGenericGroup(ethtoolFd, unix.ETHTOOL_GENL_NAME, unix.ETHTOOL_MCGRP_MONITOR_NAME)
+		if err != nil {
+			return err
+		}
+		err = unix.SetsockoptInt(ethtoolFd, unix.SOL_NETLINK, unix.NETLINK_ADD_MEMBERSHIP, int(groupID))
+		if err != nil {
+			return fmt.Errorf("cannot join ethtool monitor group: %w", err)
+		}
+		pollFds = append(pollFds, unix.PollFd{Fd: int32(ethtoolFd), Events: unix.POLLIN})
+	}
+
+	emit := func(event LinkEvent) {
+		select {
+		case events <- event:
+		case <-ctx.Done():
+		}
+	}
+	buffer := make([]byte, netlinkReceiveBuffer)
+	slog.Info("Watching link events", "ethtoolMonitor", source.EthtoolMonitor)
+	for ctx.Err() == nil {
+		_, err := unix.Poll(pollFds, netlinkPollTimeout)
+		if err != nil && !errors.Is(err, unix.EINTR) {
+			return fmt.Errorf("cannot poll netlink sockets: %w", err)
+		}
+		for _, pollFd := range pollFds {
+			if pollFd.Revents&unix.POLLIN == 0 {
+				continue
+			}
+			bytesRead, _, err := unix.Recvfrom(int(pollFd.Fd), buffer, unix.MSG_DONTWAIT)
+			if errors.Is(err, unix.ENOBUFS) {
+				slog.Warn("Netlink socket buffer overrun, some link events are lost")
+				continue
+			} else if err != nil {
+				if errors.Is(err, unix.EAGAIN) {
+					continue
+				}
+				return fmt.Errorf("cannot read netlink socket: %w", err)
+			}
+			messages, err := parseNetlinkMessages(buffer[:bytesRead])
+			if err != nil {
+				slog.Warn("Cannot parse netlink messages", "error", err)
+			}
+			now := time.Now()
+			for _, message := range messages {
+				switch {
+				case message.Header.Type == unix.NLMSG_ERROR:
+					slog.Warn("Got netlink error", "error", parseNetlinkError(message))
+				case int(pollFd.Fd) == routeFd:
+					source.handleRouteMessage(message, now, emit)
+				case message.Header.Type == ethtoolFamilyID:
+					source.handleEthtoolMessage(message, now, emit)
+				}
+			}
+		}
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,90 @@
+//go:build linux
+
+package events
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func newLinkMessage(messageType uint16, sequence uint32, index int32, name string, operState byte) netlinkMessage {
+	payload := make([]byte, unix.SizeofIfInfomsg)
+	binary.NativeEndian.PutUint32(payload[4:8], uint32(index))
+	payload = append(payload, newNetlinkAttribute(unix.IFLA_IFNAME, append([]byte(name), 0))...)
+	payload = append(payload, newNetlinkAttribute(unix.IFLA_OPERSTATE, []byte{operState})...)
+	rawMessage := newNetlinkRequest(messageType, 0, sequence, payload)
+	messages, _ := parseNetlinkMessages(rawMessage)
+	return messages[0]
+}
+
+func TestParseLinkMessage(t *testing.T) {
+	message := newLinkMessage(unix.RTM_NEWLINK, 0, 4, "eth0", ifOperUp)
+	link, err := parseLinkMessage(message.Data)
+	assert.NoError(t, err)
+	assert.Equal(t, linkInfo{Index: 4, Name: "eth0", Up: true}, link)
+
+	_, err = parseLinkMessage(message.Data[:8])
+	assert.Error(t, err)
+}
+
+func TestHandleRouteMessages(t *testing.T) {
+	source := &NetlinkSource{
+		NetClassPath: "../testdata/interfaces/sys/class/net",
+		links:        map[int32]linkInfo{},
+		speeds:       map[string]uint64{},
+	}
+	emitted := []EventType{}
+	var renamedFrom string
+	emit := func(event LinkEvent) {
+		emitted = append(emitted, event.Type)
+		if event.Type == LinkRenamed {
+			renamedFrom = event.PreviousDevice
+		}
+	}
+	now := time.Now()
+
+	// Dump replies only seed state
+	source.handleRouteMessage(newLinkMessage(unix.RTM_NEWLINK, linkDumpSequence, 2, "eth0", ifOperUp), now, emit)
+	assert.Empty(t, emitted)
+
+	source.handleRouteMessage(newLinkMessage(unix.RTM_NEWLINK, 0, 2, "eth0", ifOperUp), now, emit)
+	source.handleRouteMessage(newLinkMessage(unix.RTM_NEWLINK, 0, 2, "eth0", 2), now, emit)
+	source.handleRouteMessage(newLinkMessage(unix.RTM_NEWLINK, 0, 2, "uplink0", 2), now, emit)
+	source.handleRouteMessage(newLinkMessage(unix.RTM_NEWLINK, 0, 3, "eth1", ifOperUp), now, emit)
+	source.handleRouteMessage(newLinkMessage(unix.RTM_DELLINK, 0, 3, "eth1", 2), now, emit)
+
+	assert.Equal(t, []EventType{LinkDown, LinkRenamed, LinkAdded, LinkUp, LinkRemoved}, emitted)
+	assert.Equal(t, "eth0", renamedFrom)
+}
+
+func TestHandleEthtoolMessages(t *testing.T) {
+	source := &NetlinkSource{
+		links:  map[int32]linkInfo{},
+		speeds: map[string]uint64{},
+	}
+	emitted := []EventType{}
+	emit := func(event LinkEvent) { emitted = append(emitted, event.Type) }
+	newEthtoolMessage := func(command uint8, speed uint32) netlinkMessage {
+		header := newNetlinkAttribute(unix.ETHTOOL_A_HEADER_DEV_NAME, []byte("eth0\x00"))
+		payload := []byte{command, 1, 0, 0}
+		payload = append(payload, newNetlinkAttribute(ethtoolHeaderAttribute|unix.NLA_F_NESTED, header)...)
+		if speed != 0 {
+			speedBytes := binary.NativeEndian.AppendUint32(nil, speed)
+			payload = append(payload, newNetlinkAttribute(unix.ETHTOOL_A_LINKMODES_SPEED, speedBytes)...)
+		}
+		messages, _ := parseNetlinkMessages(newNetlinkRequest(30, 0, 0, payload))
+		return messages[0]
+	}
+
+	source.handleEthtoolMessage(newEthtoolMessage(unix.ETHTOOL_MSG_LINKMODES_NTF, 10000), time.Now(), emit)
+	source.handleEthtoolMessage(newEthtoolMessage(unix.ETHTOOL_MSG_LINKMODES_NTF, ethtoolSpeedUnknown), time.Now(), emit)
+	source.handleEthtoolMessage(newEthtoolMessage(unix.ETHTOOL_MSG_LINKMODES_NTF, 25000), time.Now(), emit)
+	source.handleEthtoolMessage(newEthtoolMessage(unix.ETHTOOL_MSG_MODULE_NTF, 0), time.Now(), emit)
+	source.handleEthtoolMessage(newEthtoolMessage(unix.ETHTOOL_MSG_LINKINFO_NTF, 0), time.Now(), emit)
+
+	assert.Equal(t, []EventType{SpeedChange, ModuleChange}, emitted)
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package events
+
+import (
+	"context"
+	"errors"
+)
+
+type NetlinkSource struct {
+	EthtoolMonitor bool
+	NetClassPath   string
+}
+
+func (source *NetlinkSource) Run(ctx context.Context, events chan<- LinkEvent) error {
+	return errors.New("netlink link events are only supported on Linux")
+}
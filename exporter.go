@@ -1,19 +1,27 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
+	"maps"
 	"os"
 	"path"
 	"runtime/debug"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/alecthomas/kingpin/v2"
 
 	"github.com/newrushbolt/go-ethtool-exporter/collector"
+	"github.com/newrushbolt/go-ethtool-exporter/events"
 	"github.com/newrushbolt/go-ethtool-exporter/interfaces"
 	"github.com/newrushbolt/go-ethtool-exporter/metrics"
+	"github.com/newrushbolt/go-ethtool-exporter/netns"
 	"github.com/newrushbolt/go-ethtool-exporter/registry"
 	"github.com/newrushbolt/go-ethtool-metrics/pkg/metrics/driver_info"
 	"github.com/newrushbolt/go-ethtool-metrics/pkg/metrics/generic_info"
@@ -21,6 +29,18 @@ import (
 	"github.com/newrushbolt/go-ethtool-metrics/pkg/metrics/statistics"
 )
 
+const (
+	discoveryRefreshesMetricName = "discovery_refreshes_total"
+	discoveryErrorMetricName     = "discovery_error"
+	netnsLabelName               = "netns"
+)
+
+// Only set when link events watcher is enabled
+var linkWatcher *events.Watcher
+
+// Only set when discovery cache is enabled
+var discoveryCache *interfaces.DiscoveryCache
+
 func initLogger() {
 	var level slog.Level
 	envLevel := os.Getenv("GO_ETHTOOL_EXPORTER_LOG_LEVEL")
@@ -34,10 +54,28 @@ func initLogger() {
 // Composes PortDiscoveryOptions from kingpin cmd options
 func createDiscoveryConfig() (config interfaces.PortDiscoveryOptions) {
 	config = interfaces.PortDiscoveryOptions{
-		PortsRegexp:          *discoverPortsRegexp,
-		DiscoverAllPorts:     *discoverAllPorts,
-		DiscoverBondSlaves:   *discoverBondSlaves,
-		DiscoverBridgeSlaves: *discoverBridgeSlaves,
+		PortsRegexp:           *discoverPortsRegexp,
+		DiscoverAllPorts:      *discoverAllPorts,
+		DiscoverBondSlaves:    *discoverBondSlaves,
+		DiscoverBondMasters:   *discoverBondMasters,
+		DiscoverBridgeSlaves:  *discoverBridgeSlaves,
+		DiscoverOvsSlaves:     *discoverOvsSlaves,
+		OvsSkipVirtualPorts:   *discoverOvsSkipVirtual,
+		DiscoverPhysicalPorts: *discoverPhysicalPorts,
+
+		DiscoverVirtualFunctions: *discoverVirtualFunctions,
+		DiscoverRepresentors:     *discoverRepresentors,
+		SkipVirtualFunctions:     *discoverSkipVfs,
+		SkipRepresentors:         *discoverSkipRepresentors,
+
+		PortsExcludeRegexp: *discoverPortsExclude,
+		AllowedOperStates:  parseCommaSeparatedList(*discoverOperStates),
+		SkipOperStates:     parseCommaSeparatedList(*discoverSkipOperStates),
+		RequireCarrier:     *discoverRequireCarrier,
+		DriversRegexp:      *discoverDriversRegexp,
+
+		DiscoverTeamSlaves: *discoverTeamSlaves,
+		ExpandLowerDevices: *discoverExpandLower,
 	}
 	return
 }
@@ -59,6 +97,33 @@ func parseAllowedInterfaceTypes(typesStr string) []int {
 	return types
 }
 
+func parseCommaSeparatedList(listStr string) []string {
+	list := []string{}
+	for _, rawItem := range strings.Split(listStr, ",") {
+		item := strings.TrimSpace(rawItem)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func parseDeviceInfoLabels(labelsStr string) []string {
+	labelNames := []string{}
+	for _, rawLabel := range strings.Split(labelsStr, ",") {
+		labelName := strings.TrimSpace(rawLabel)
+		if labelName == "" {
+			continue
+		}
+		if !slices.Contains(interfaces.DeviceInfoLabelNames, labelName) {
+			slog.Error("Invalid label in label-device-info, must be comma separated", "label-device-info", labelsStr, "label", labelName, "allowed-labels", interfaces.DeviceInfoLabelNames)
+			continue
+		}
+		labelNames = append(labelNames, labelName)
+	}
+	return labelNames
+}
+
 func getExporterVersion(readBuildInfo func() (*debug.BuildInfo, bool)) string {
 	buildInfo, ok := readBuildInfo()
 	if !ok {
@@ -91,14 +156,7 @@ func getExporterVersion(readBuildInfo func() (*debug.BuildInfo, bool)) string {
 	return strings.Join(versionLines, "\n")
 }
 
-// TODO: to be covered by some kind of tests
-func collectMetrics() registry.RegistryCollection {
-	allMetricRegistries := registry.RegistryCollection{}
-
-	allowedTypes := parseAllowedInterfaceTypes(*discoverAllowedPortTypes)
-	discoverConfig := createDiscoveryConfig()
-	interfaces := interfaces.GetInterfacesList(*linuxNetClassPath, discoverConfig, allowedTypes)
-
+func createCollectorConfig() collector.CollectorConfig {
 	// Format configs
 	driverInfoConfig := driver_info.CollectConfig{
 		CollectCommon:   *collectDriverInfoCommon,
@@ -122,15 +180,17 @@ func collectMetrics() registry.RegistryCollection {
 		PerQueueGenerateMissingBytesMetrics: *statisticsGenerateMissingPerQueueMetrics,
 		PerQueueXdp:                         *collectStatisticsPerQueueXdp,
 	}
-	collectorConfig := collector.CollectorConfig{
+	return collector.CollectorConfig{
 		DriverInfo:  driverInfoConfig,
 		GenericInfo: genericinfoConfig,
 		ModuleInfo:  moduleInfoConfig,
 		Statistics:  statisticsConfig,
 
-		EthtoolPath:     *ethtoolPath,
-		EthtoolTimeout:  *ethtoolTimeout,
-		ListLabelFormat: *listLabelFormat,
+		DataSource:       createDataSource(),
+		EthtoolPath:      *ethtoolPath,
+		EthtoolTimeout:   *ethtoolTimeout,
+		EthtoolNetnsPath: getEthtoolNetnsPath(),
+		ListLabelFormat:  *listLabelFormat,
 
 		DriverInfoAbsentMetrics: metrics.AbsentMetricsConfig{
 			ExposeNan:          *absentMetricsDriverInfoExposeNan,
@@ -152,22 +212,369 @@ func collectMetrics() registry.RegistryCollection {
 			ExposeTotalCounter: *absentMetricsStatisticsExposeTotalCounter,
 			ExposeDetailedInfo: *absentMetricsStatisticsExposeDetailedInfo,
 		},
-	}
 
-	for _, interfaceName := range interfaces {
-		// TODO: allow parallel gather
-		interfaceRegistry := collector.CollectInterfaceMetrics(interfaceName, collectorConfig)
-		allMetricRegistries[interfaceName] = interfaceRegistry
+		BondInfo: collector.BondInfoConfig{
+			Collect:            *collectBondInfo,
+			ProcNetBondingPath: getProcNetBondingPath(),
+			NetClassPath:       getNetClassPath(),
+		},
+		BondInfoAbsentMetrics: metrics.AbsentMetricsConfig{
+			ExposeNan:          *absentMetricsBondInfoExposeNan,
+			ExposeTotalCounter: *absentMetricsBondInfoExposeTotalCounter,
+			ExposeDetailedInfo: *absentMetricsBondInfoExposeDetailedInfo,
+		},
 	}
+}
 
+// Result of a single collection, exporter-wide metrics are kept apart from per-device ones
+type collectedMetrics struct {
+	// Keyed by device, or `<netns>/<device>` for other network namespaces
+	Interfaces registry.RegistryCollection
+	// Metrics of the exporter itself, eg discovery errors and cache refreshes
+	Exporter registry.Registry
+}
+
+// Output formats don't care about devices, so exporter-wide metrics are rendered along with interfaces ones.
+// Device names are never empty, so they cannot clash with exporter-wide registry.
+func (metrics collectedMetrics) all() registry.RegistryCollection {
+	allMetricRegistries := maps.Clone(metrics.Interfaces)
+	if allMetricRegistries == nil {
+		allMetricRegistries = registry.RegistryCollection{}
+	}
+	if len(metrics.Exporter) > 0 {
+		allMetricRegistries[""] = metrics.Exporter
+	}
 	return allMetricRegistries
 }
 
-func writeAllMetricsToTextfiles(metricRegistries registry.RegistryCollection) {
-	textFileName := "ethtool_exporter.prom"
-	textFilePath := path.Join(*textfileDirectory, textFileName)
-	allMetricsString := metricRegistries.GetAllMetricsText()
-	registry.MustWriteTextfile(textFilePath, allMetricsString)
+// TODO: to be covered by some kind of tests
+func collectMetrics() collectedMetrics {
+	configLock.RLock()
+	defer configLock.RUnlock()
+	return collectMetricsLocked()
+}
+
+// The same as collectMetrics, but config read lock must be already held by the caller
+func collectMetricsLocked() collectedMetrics {
+	discoveredInterfaces, discoveryErr := discoverInterfaces()
+	metrics, _ := collectMetricsWithErrors(discoveredInterfaces, discoveryErr, createCollectorConfig())
+	return metrics
+}
+
+// Read errors are keyed by collector name, see mergeReadErrors.
+// Discovery error is only exposed as exporter metric, ports of other network namespaces are still collected.
+func collectMetricsWithErrors(discoveredInterfaces []string, discoveryErr error, collectorConfig collector.CollectorConfig) (collectedMetrics, map[string]error) {
+	allMetricRegistries, readErrors := collectInterfacesMetrics(discoveredInterfaces, getNetClassPath(), collectorConfig, linkWatcher)
+	// Recorded data has no network namespaces
+	if (*collectNamedNetns || *collectProcessNetns) && *replayDirectory == "" {
+		namespaceRegistries, namespaceReadErrors := collectNamespacesMetrics(collectorConfig)
+		maps.Insert(allMetricRegistries, maps.All(namespaceRegistries))
+		mergeReadErrors(readErrors, namespaceReadErrors)
+	}
+	return collectedMetrics{Interfaces: allMetricRegistries, Exporter: collectExporterMetrics(discoveryErr)}, readErrors
+}
+
+// Collector error is only kept if it failed for every port, eg ethtool timed out everywhere.
+// Failures of some ports are normal, eg `ethtool -m` of copper ports.
+func mergeReadErrors(allReadErrors map[string]error, readErrors map[string]error) {
+	for collectorName, err := range readErrors {
+		previousErr, found := allReadErrors[collectorName]
+		if !found || previousErr != nil {
+			allReadErrors[collectorName] = err
+		}
+	}
+}
+
+func collectExporterMetrics(discoveryErr error) registry.Registry {
+	discoveryError := 0.0
+	if discoveryErr != nil {
+		discoveryError = 1
+	}
+	exporterRegistry := registry.Registry{{
+		Name:   discoveryErrorMetricName,
+		Labels: map[string]string{},
+		Value:  discoveryError,
+	}}
+	if discoveryCache != nil {
+		exporterRegistry = append(exporterRegistry, registry.MetricRecord{
+			Name:   discoveryRefreshesMetricName,
+			Labels: map[string]string{},
+			Value:  float64(discoveryCache.Refreshes()),
+		})
+	}
+	return exporterRegistry
+}
+
+// Sysfs paths differ between network namespaces, watcher is only set for the exporter's own namespace
+func collectInterfacesMetrics(discoveredInterfaces []string, netClassPath string, collectorConfig collector.CollectorConfig, watcher *events.Watcher) (registry.RegistryCollection, map[string]error) {
+	metricRegistries := registry.RegistryCollection{}
+	readErrors := map[string]error{}
+	deviceInfoLabels := parseDeviceInfoLabels(*labelDeviceInfo)
+	sriovTopology := interfaces.NewSriovTopology(netClassPath)
+	collector.RunInEthtoolNetns(collectorConfig, func(collectorConfig collector.CollectorConfig) {
+		for _, interfaceName := range discoveredInterfaces {
+			// TODO: allow parallel gather
+			interfaceConfig := selectCollectorConfig(interfaceName, netClassPath, collectorConfig, interfaceOverrides)
+			interfaceRegistry, interfaceReadErrors := collector.CollectInterfaceMetricsWithErrors(interfaceName, interfaceConfig)
+			mergeReadErrors(readErrors, interfaceReadErrors)
+			if watcher != nil {
+				interfaceRegistry = append(interfaceRegistry, watcher.GetDeviceRegistry(interfaceName)...)
+			}
+			if *collectDeviceInfo || len(deviceInfoLabels) > 0 {
+				deviceInfo := interfaces.GetDeviceInfo(netClassPath, interfaceName)
+				interfaceRegistry.AddLabelsToDeviceMetrics(interfaceName, deviceInfo.SomeLabels(deviceInfoLabels))
+				if *collectDeviceInfo {
+					metricLabels := map[string]string{"device": interfaceName}
+					maps.Insert(metricLabels, maps.All(deviceInfo.Labels()))
+					interfaceRegistry = append(interfaceRegistry, registry.MetricRecord{
+						Name:   interfaces.DeviceInfoMetricName,
+						Labels: metricLabels,
+						Value:  1,
+					})
+				}
+			}
+			if *labelSriovTopology {
+				sriovInfo := sriovTopology.GetSriovInfo(interfaceName)
+				interfaceRegistry.AddLabelsToDeviceMetrics(interfaceName, sriovInfo.Labels())
+			}
+			metricRegistries[interfaceName] = interfaceRegistry
+		}
+	})
+	return metricRegistries, readErrors
+}
+
+// Calls the function inside every network namespace with discovery decisions of its ports.
+// Namespaces that cannot be entered are skipped, so they don't break collection in the exporter's own namespace.
+func forEachNetns(function func(namespace netns.Namespace, paths netns.Paths, decisions []interfaces.PortDecision)) {
+	runNetnsDirectory := ""
+	if *collectNamedNetns {
+		runNetnsDirectory = *runNetnsPath
+	}
+	namespaces, err := netns.ListNamespaces(runNetnsDirectory, *procfsPath, *collectProcessNetns)
+	if err != nil {
+		slog.Error("Cannot list network namespaces", "error", err)
+		return
+	}
+
+	allowedTypes := parseAllowedInterfaceTypes(*discoverAllowedPortTypes)
+	discoverConfig := createDiscoveryConfig()
+	for _, namespace := range namespaces {
+		err := namespace.Run(func(paths netns.Paths) error {
+			decisions, err := interfaces.ExplainInterfaces(paths.NetClassPath, discoverConfig, allowedTypes)
+			if err != nil {
+				return err
+			}
+			function(namespace, paths, decisions)
+			return nil
+		})
+		if err != nil {
+			slog.Error("Cannot discover ports in network namespace", "netns", namespace.Name, "path", namespace.Path, "error", err)
+		}
+	}
+}
+
+func createNamespaceCollectorConfig(collectorConfig collector.CollectorConfig, paths netns.Paths) collector.CollectorConfig {
+	namespaceConfig := collectorConfig
+	// Ethtool has to run in the namespace of the thread, not in the host one
+	namespaceConfig.EthtoolNetnsPath = ""
+	namespaceConfig.BondInfo.NetClassPath = paths.NetClassPath
+	namespaceConfig.BondInfo.ProcNetBondingPath = paths.ProcNetBondingPath
+	return namespaceConfig
+}
+
+// Registries are keyed by `<netns>/<device>`, as devices in different namespaces may have the same name
+func collectNamespacesMetrics(collectorConfig collector.CollectorConfig) (registry.RegistryCollection, map[string]error) {
+	metricRegistries := registry.RegistryCollection{}
+	readErrors := map[string]error{}
+	forEachNetns(func(namespace netns.Namespace, paths netns.Paths, decisions []interfaces.PortDecision) {
+		discoveredInterfaces := interfaces.AcceptedPorts(decisions)
+		slog.Debug("Discovered following interfaces in network namespace", "netns", namespace.Name, "interfaces", discoveredInterfaces)
+		namespaceConfig := createNamespaceCollectorConfig(collectorConfig, paths)
+		namespaceRegistries, namespaceReadErrors := collectInterfacesMetrics(discoveredInterfaces, paths.NetClassPath, namespaceConfig, nil)
+		for interfaceName, interfaceRegistry := range namespaceRegistries {
+			// Bond slaves are always in the same namespace as their master, so the label is valid for all records
+			interfaceRegistry.AddLabelsToAllMetrics(map[string]string{netnsLabelName: namespace.Name})
+			metricRegistries[namespace.Name+"/"+interfaceName] = interfaceRegistry
+		}
+		mergeReadErrors(readErrors, namespaceReadErrors)
+	})
+	return metricRegistries, readErrors
+}
+
+// Specific path flags override paths derived from sysfs and procfs roots
+func getNetClassPath() string {
+	if *linuxNetClassPath != "" {
+		return *linuxNetClassPath
+	}
+	return path.Join(getSysfsPath(), "class/net")
+}
+
+func getProcNetBondingPath() string {
+	if *procNetBondingPath != "" {
+		return *procNetBondingPath
+	}
+	return path.Join(getProcfsPath(), "net/bonding")
+}
+
+// Used instead of running ethtool binary if set, eg in-memory output in tests
+var ethtoolDataSource collector.DataSource
+
+func createDataSource() collector.DataSource {
+	if *replayDirectory != "" {
+		return collector.ReplayDataSource{Directory: *replayDirectory}
+	}
+	if ethtoolDataSource != nil {
+		return ethtoolDataSource
+	}
+	execDataSource := collector.ExecDataSource{Path: *ethtoolPath, Timeout: *ethtoolTimeout}
+	if *ethtoolNetlink {
+		return collector.NetlinkDataSource{Fallback: execDataSource, Timeout: *ethtoolTimeout}
+	}
+	return execDataSource
+}
+
+// Replay mode uses recorded sysfs and procfs trees instead of the mountpoints
+func getSysfsPath() string {
+	if *replayDirectory != "" {
+		return path.Join(*replayDirectory, "sysfs")
+	}
+	return *sysfsPath
+}
+
+func getProcfsPath() string {
+	if *replayDirectory != "" {
+		return path.Join(*replayDirectory, "procfs")
+	}
+	return *procfsPath
+}
+
+// Host PID 1 is only visible with host procfs, eg mounted to `/host/proc`, or with `hostPID: true`
+func getEthtoolNetnsPath() string {
+	if !*ethtoolHostNetns || *replayDirectory != "" {
+		return ""
+	}
+	return path.Join(*procfsPath, "1/ns/net")
+}
+
+// Uses discovery cache if it's enabled. Error is logged, so callers only need it to report failed discovery
+func discoverInterfaces() ([]string, error) {
+	var discoveredInterfaces []string
+	var err error
+	if discoveryCache == nil {
+		allowedTypes := parseAllowedInterfaceTypes(*discoverAllowedPortTypes)
+		discoverConfig := createDiscoveryConfig()
+		discoveredInterfaces, err = interfaces.ListInterfaces(getNetClassPath(), discoverConfig, allowedTypes)
+	} else {
+		discoveredInterfaces, err = discoveryCache.GetInterfacesList()
+	}
+	if err != nil {
+		slog.Error("Cannot discover ports", "netClassPath", getNetClassPath(), "error", err)
+	}
+	return discoveredInterfaces, err
+}
+
+func startDiscoveryCache() {
+	if !*discoveryCacheEnabled {
+		return
+	}
+	discoveryCache = &interfaces.DiscoveryCache{
+		NetClassDirectory: getNetClassPath(),
+		Options:           createDiscoveryConfig(),
+		AllowedTypes:      parseAllowedInterfaceTypes(*discoverAllowedPortTypes),
+	}
+	err := discoveryCache.Watch(context.Background())
+	if err != nil {
+		slog.Warn("Cannot watch link changes, ports will be rediscovered on every collection", "error", err)
+	}
+}
+
+func startLinkWatcher() {
+	if !*watchLinkEvents {
+		return
+	}
+	source := &events.NetlinkSource{
+		EthtoolMonitor: *watchLinkEventsEthtoolMonitor,
+		NetClassPath:   getNetClassPath(),
+	}
+	linkWatcher = events.NewWatcher(source)
+	linkWatcher.Start(context.Background(), 10*time.Second)
+}
+
+// Textfile of the collector is named `<name>_<collector>.prom`, empty collector name is the textfile itself
+func getTextfileName(collectorName string) string {
+	if collectorName == "" {
+		return *textfileName
+	}
+	extension := path.Ext(*textfileName)
+	return strings.TrimSuffix(*textfileName, extension) + "_" + collectorName + extension
+}
+
+func renderTextfile(metricRegistries registry.RegistryCollection) (string, error) {
+	if *textfileFormat == "influx" || *textfileFormat == "graphite" {
+		return renderLineMetrics(metricRegistries, *textfileFormat, nil, time.Now())
+	}
+	return metricRegistries.GetAllMetricsText(), nil
+}
+
+func writeTextfile(collectorName string, metricRegistries registry.RegistryCollection, options registry.TextfileOptions) error {
+	content, err := renderTextfile(metricRegistries)
+	if err != nil {
+		return err
+	}
+	return registry.WriteTextfile(path.Join(*textfileDirectory, getTextfileName(collectorName)), content, options)
+}
+
+func getTextfileOptions() registry.TextfileOptions {
+	mode, err := parseTextfileMode(*textfileMode)
+	if err != nil {
+		panic(err)
+	}
+	return registry.TextfileOptions{Mode: mode, Fsync: *textfileFsync}
+}
+
+// Collects metrics and writes them to textfiles, config read lock must be already held by the caller
+func updateTextfilesLocked() {
+	if *textfilePerCollector {
+		discoveredInterfaces, discoveryErr := discoverInterfaces()
+		writeTextfilesPerCollector(collectMetricsWithErrors(discoveredInterfaces, discoveryErr, createCollectorConfig()))
+		return
+	}
+	writeAllMetricsToTextfiles(collectMetricsLocked())
+}
+
+func writeAllMetricsToTextfiles(metrics collectedMetrics) {
+	err := writeTextfile("", metrics.all(), getTextfileOptions())
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Metrics are collected once and split by collector, so discovery, ethtool runs and namespace switches are not repeated per textfile.
+// Textfile keeps previous content if the collector failed for every port or the file cannot be written,
+// otherwise it's written even empty, so metrics which disappeared are not left stale.
+// Textfile of all metrics is removed, or node_exporter would read duplicate series from it.
+func writeTextfilesPerCollector(metrics collectedMetrics, readErrors map[string]error) {
+	allMetricsTextfile := path.Join(*textfileDirectory, getTextfileName(""))
+	err := os.Remove(allMetricsTextfile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		slog.Error("Cannot remove textfile of all metrics", "textfileName", getTextfileName(""), "error", err)
+	}
+
+	options := getTextfileOptions()
+	collectorParts := metrics.Interfaces.SplitByCollector(metricCollectorNames(), textfileOtherCollectorName)
+	// Exporter-wide metrics have no collector, so they go along with other metrics, eg link events
+	collectorParts[textfileOtherCollectorName] = collectedMetrics{Interfaces: collectorParts[textfileOtherCollectorName], Exporter: metrics.Exporter}.all()
+	for _, collectorName := range append(metricCollectorNames(), textfileOtherCollectorName) {
+		textfileLogger := slog.With("collector", collectorName, "textfileName", getTextfileName(collectorName))
+		if readErrors[collectorName] != nil {
+			textfileLogger.Error("Cannot read data of any port, keeping previous textfile", "error", readErrors[collectorName])
+			continue
+		}
+		err := writeTextfile(collectorName, collectorParts[collectorName], options)
+		if err != nil {
+			textfileLogger.Error("Cannot write textfile", "error", err)
+		}
+	}
 }
 
 func MustDirectoryExist(dirPath *string) {
@@ -184,6 +591,62 @@ func MustDirectoryExist(dirPath *string) {
 func init() {
 	// Moved to separate `init()` in order to work both in exporter and tests
 	initLogger()
+	setFlagEnvars(kingpin.CommandLine)
+	kingpin.CommandLine.Validate(validateFlags)
+}
+
+func validateFlags(app *kingpin.Application) error {
+	err := validateEthtoolPath(app)
+	if err != nil {
+		return err
+	}
+	err = validateNetClassPath(app)
+	if err != nil {
+		return err
+	}
+	return validateTextfileFlags(app)
+}
+
+// Netclass path is derived from several flags, so it's validated after all of them are parsed
+func validateNetClassPath(*kingpin.Application) error {
+	netClassStat, err := os.Stat(getNetClassPath())
+	if err != nil || !netClassStat.IsDir() {
+		return fmt.Errorf("netclass directory '%s' does not exist", getNetClassPath())
+	}
+	return nil
+}
+
+func parseTextfileMode(mode string) (os.FileMode, error) {
+	parsedMode, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || parsedMode > 0o777 {
+		return 0, fmt.Errorf("textfile mode '%s' is not octal permissions, eg '0644'", mode)
+	}
+	return os.FileMode(parsedMode), nil
+}
+
+// Textfile name is joined with directory and collector names, so it cannot point to another directory
+func validateTextfileFlags(*kingpin.Application) error {
+	if *textfileName == "" || *textfileName == "." || *textfileName == ".." || strings.Contains(*textfileName, "/") {
+		return fmt.Errorf("textfile name '%s' is not a file name", *textfileName)
+	}
+	_, err := parseTextfileMode(*textfileMode)
+	return err
+}
+
+// Runs after every parse, including config reload, so invalid paths are rolled back like other flag errors
+func validateEthtoolPath(*kingpin.Application) error {
+	if *replayDirectory != "" {
+		replayStat, err := os.Stat(*replayDirectory)
+		if err != nil || !replayStat.IsDir() {
+			return fmt.Errorf("replay directory '%s' does not exist", *replayDirectory)
+		}
+		return nil
+	}
+	ethtoolStat, err := os.Stat(*ethtoolPath)
+	if err != nil || ethtoolStat.IsDir() {
+		return fmt.Errorf("ethtool path '%s' does not exist", *ethtoolPath)
+	}
+	return nil
 }
 
 func enableAllMetricCollectionFlags() {
@@ -200,6 +663,8 @@ func enableAllMetricCollectionFlags() {
 	*collectStatisticsPerQueueGeneral = true
 	*collectStatisticsPerQueuePerType = true
 	*collectStatisticsPerQueueXdp = true
+	*collectBondInfo = true
+	*collectDeviceInfo = true
 }
 
 func main() {
@@ -214,7 +679,8 @@ func main() {
 	}
 
 	kingpin.Version(getExporterVersion(debug.ReadBuildInfo))
-	exporterCommand := kingpin.Parse()
+	exporterCommand, err := parseFlags(kingpin.CommandLine, os.Args[1:])
+	kingpin.FatalIfError(err, "")
 
 	if *collectAllMetrics {
 		slog.Warn("Flag --collect-all-metrics is set, ignoring all other --collect-* flags")
@@ -224,10 +690,20 @@ func main() {
 	switch exporterCommand {
 	case discoverPortsCommand.FullCommand():
 		runDiscoverPortsCommand()
+	case reportCommand.FullCommand():
+		runReportCommand()
+	case dumpCommand.FullCommand():
+		runDumpCommand()
+	case checkCommand.FullCommand():
+		runCheckCommand()
 	case singleTextfileCommand.FullCommand():
 		runSingleTextfileCommand()
 	case loopTextfileCommand.FullCommand():
 		runLoopTextfileCommand()
+	case pushCommand.FullCommand():
+		runPushCommand()
+	case otlpCommand.FullCommand():
+		runOtlpCommand()
 	case httpServerCommand.FullCommand():
 		runHttpServerCommand()
 	default:
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/newrushbolt/go-ethtool-exporter/interfaces"
+	"github.com/newrushbolt/go-ethtool-exporter/registry"
+)
+
+// Standard monitoring plugin exit codes
+const (
+	checkStateOk       = 0
+	checkStateWarning  = 1
+	checkStateCritical = 2
+	checkStateUnknown  = 3
+)
+
+var checkStateNames = map[int]string{
+	checkStateOk:       "OK",
+	checkStateWarning:  "WARNING",
+	checkStateCritical: "CRITICAL",
+	checkStateUnknown:  "UNKNOWN",
+}
+
+// Like in monitoring-plugins, CRITICAL is worse than WARNING, which is worse than UNKNOWN
+var checkStateSeverity = map[int]int{
+	checkStateOk:       0,
+	checkStateUnknown:  1,
+	checkStateWarning:  2,
+	checkStateCritical: 3,
+}
+
+const (
+	checkAlarmsPrefix   = "module_info_diagnostics_alarms_"
+	checkWarningsPrefix = "module_info_diagnostics_warnings_"
+	checkSpeedName      = "generic_info_settings_speed_bits"
+)
+
+type checkResult struct {
+	State    int
+	Problems []string
+	Perfdata []string
+	Ports    int
+}
+
+func (result *checkResult) addProblem(state int, problem string) {
+	if checkStateSeverity[state] > checkStateSeverity[result.State] {
+		result.State = state
+	}
+	result.Problems = append(result.Problems, fmt.Sprintf("%s: %s", checkStateNames[state], problem))
+}
+
+// Port is expected to have module diagnostics the same way as in NetworkTransceiverMissingData alert:
+// it is a FIBRE port and its driver supports self-test
+func isModuleDataExpected(interfaceRegistry registry.Registry) bool {
+	fibrePort := false
+	driverTest := false
+	for _, metricRecord := range interfaceRegistry {
+		switch metricRecord.Name {
+		case "generic_info_settings_info":
+			fibrePort = metricRecord.Labels["Port"] == "FIBRE"
+		case "driver_info_features_test":
+			driverTest = metricRecord.Value == 1
+		}
+	}
+	return fibrePort && driverTest
+}
+
+// Plain threshold means "warn above", while speed warns below the minimum, that is `<min>:` range.
+// Empty if minimum speed is not set.
+func speedWarningRange(minSpeedBits float64) string {
+	if minSpeedBits <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.0f:", minSpeedBits)
+}
+
+// Mirrors alerts/sfp_warnings_alarms.rules.yaml, without `for` durations, as check has no history
+func checkInterface(result *checkResult, deviceName string, interfaceRegistry registry.Registry, minSpeedBits float64) {
+	alarms := 0
+	warnings := 0
+	diagnosticsFound := false
+	diagnosticsMissing := false
+	for _, metricRecord := range interfaceRegistry {
+		isAlarm := strings.HasPrefix(metricRecord.Name, checkAlarmsPrefix)
+		isWarning := strings.HasPrefix(metricRecord.Name, checkWarningsPrefix)
+		switch {
+		case (isAlarm || isWarning) && math.IsNaN(metricRecord.Value):
+			diagnosticsMissing = true
+		case isAlarm:
+			diagnosticsFound = true
+			if metricRecord.Value > 0 {
+				alarms++
+				result.addProblem(checkStateCritical, fmt.Sprintf("%s transceiver alarm %s", deviceName, strings.TrimPrefix(metricRecord.Name, checkAlarmsPrefix)))
+			}
+		case isWarning:
+			diagnosticsFound = true
+			if metricRecord.Value > 0 {
+				warnings++
+				result.addProblem(checkStateWarning, fmt.Sprintf("%s transceiver warning %s", deviceName, strings.TrimPrefix(metricRecord.Name, checkWarningsPrefix)))
+			}
+		case metricRecord.Name == checkSpeedName && !math.IsNaN(metricRecord.Value):
+			result.Perfdata = append(result.Perfdata, fmt.Sprintf("'%s_speed'=%.0fb;%s;;0", deviceName, metricRecord.Value, speedWarningRange(minSpeedBits)))
+			if metricRecord.Value < minSpeedBits {
+				result.addProblem(checkStateWarning, fmt.Sprintf("%s link speed %.0fMb/s is below %.0fMb/s", deviceName, metricRecord.Value/1e6, minSpeedBits/1e6))
+			}
+		}
+	}
+	if (diagnosticsMissing || !diagnosticsFound) && isModuleDataExpected(interfaceRegistry) {
+		result.addProblem(checkStateUnknown, fmt.Sprintf("%s transceiver is missing diagnostic data", deviceName))
+	}
+	result.Perfdata = append(result.Perfdata,
+		fmt.Sprintf("'%s_alarms'=%d;;0;0", deviceName, alarms),
+		fmt.Sprintf("'%s_warnings'=%d;0;;0", deviceName, warnings),
+	)
+}
+
+func evaluateCheck(metricRegistries registry.RegistryCollection, minSpeedBits float64) checkResult {
+	result := checkResult{State: checkStateOk}
+	for _, deviceName := range slices.Sorted(maps.Keys(metricRegistries)) {
+		result.Ports++
+		checkInterface(&result, deviceName, metricRegistries[deviceName], minSpeedBits)
+	}
+	if result.Ports == 0 {
+		result.addProblem(checkStateUnknown, "no ports discovered")
+	}
+	return result
+}
+
+// First line is the summary with perfdata, problems follow as long output
+func (result checkResult) String() string {
+	summary := fmt.Sprintf("ETHTOOL %s - %d ports checked", checkStateNames[result.State], result.Ports)
+	if len(result.Problems) > 0 {
+		summary = fmt.Sprintf("ETHTOOL %s - %d problems on %d ports", checkStateNames[result.State], len(result.Problems), result.Ports)
+	}
+	lines := []string{summary}
+	if len(result.Perfdata) > 0 {
+		lines[0] += " | " + strings.Join(result.Perfdata, " ")
+	}
+	lines = append(lines, result.Problems...)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Collectors required by the check are always enabled, other flags and overrides are kept
+func collectCheckMetrics() (registry.RegistryCollection, error) {
+	discoveredInterfaces, err := interfaces.ListInterfaces(getNetClassPath(), createDiscoveryConfig(), parseAllowedInterfaceTypes(*discoverAllowedPortTypes))
+	if err != nil {
+		return nil, err
+	}
+	collectorConfig := createCollectorConfig()
+	collectorConfig.GenericInfo.CollectSettings = true
+	collectorConfig.DriverInfo.CollectFeatures = true
+	collectorConfig.ModuleInfo.CollectDiagnosticsAlarms = true
+	collectorConfig.ModuleInfo.CollectDiagnosticsWarnings = true
+	collectorConfig.ModuleInfoAbsentMetrics.ExposeNan = true
+	metricRegistries, _ := collectInterfacesMetrics(discoveredInterfaces, getNetClassPath(), collectorConfig, nil)
+	return metricRegistries, nil
+}
+
+func runCheckCommand() {
+	metricRegistries, err := collectCheckMetrics()
+	if err != nil {
+		fmt.Printf("ETHTOOL UNKNOWN - cannot discover ports: %s\n", err)
+		os.Exit(checkStateUnknown)
+	}
+	result := evaluateCheck(metricRegistries, float64(*checkMinSpeed)*1e6)
+	fmt.Print(result.String())
+	os.Exit(result.State)
+}
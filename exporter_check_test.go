@@ -0,0 +1,138 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrushbolt/go-ethtool-exporter/registry"
+)
+
+func checkMetric(name string, value float64, labels map[string]string) registry.MetricRecord {
+	return registry.MetricRecord{Name: name, Labels: labels, Value: value}
+}
+
+func fibrePortRegistry(extraMetrics ...registry.MetricRecord) registry.Registry {
+	return append(registry.Registry{
+		checkMetric("generic_info_settings_info", 1, map[string]string{"Port": "FIBRE"}),
+		checkMetric("driver_info_features_test", 1, map[string]string{}),
+		checkMetric("generic_info_settings_speed_bits", 1e10, map[string]string{}),
+	}, extraMetrics...)
+}
+
+func TestEvaluateCheck(t *testing.T) {
+	testCases := []struct {
+		name          string
+		registries    registry.RegistryCollection
+		minSpeedBits  float64
+		expectedState int
+		expectedLines int
+		// Not checked if nil
+		expectedPerfdata []string
+	}{
+		{
+			name: "healthy",
+			registries: registry.RegistryCollection{
+				"eth0": fibrePortRegistry(checkMetric("module_info_diagnostics_alarms_bias_high", 0, nil), checkMetric("module_info_diagnostics_warnings_bias_high", 0, nil)),
+			},
+			expectedState: checkStateOk,
+			expectedLines: 1,
+		},
+		{
+			name: "alarm wins over warning",
+			registries: registry.RegistryCollection{
+				"eth0": fibrePortRegistry(checkMetric("module_info_diagnostics_alarms_bias_high", 1, nil)),
+				"eth1": fibrePortRegistry(checkMetric("module_info_diagnostics_warnings_bias_high", 1, nil)),
+			},
+			expectedState: checkStateCritical,
+			expectedLines: 3,
+			expectedPerfdata: []string{
+				"'eth0_speed'=10000000000b;;;0", "'eth0_alarms'=1;;0;0", "'eth0_warnings'=0;0;;0",
+				"'eth1_speed'=10000000000b;;;0", "'eth1_alarms'=0;;0;0", "'eth1_warnings'=1;0;;0",
+			},
+		},
+		{
+			name: "low speed",
+			registries: registry.RegistryCollection{
+				"eth0": fibrePortRegistry(checkMetric("module_info_diagnostics_alarms_bias_high", 0, nil)),
+			},
+			minSpeedBits:  25e9,
+			expectedState: checkStateWarning,
+			expectedLines: 2,
+		},
+		{
+			name: "missing module data",
+			registries: registry.RegistryCollection{
+				"eth0": fibrePortRegistry(),
+			},
+			expectedState: checkStateUnknown,
+			expectedLines: 2,
+		},
+		{
+			name: "nan module data",
+			registries: registry.RegistryCollection{
+				"eth0": fibrePortRegistry(checkMetric("module_info_diagnostics_alarms_bias_high", math.NaN(), nil)),
+			},
+			expectedState: checkStateUnknown,
+			expectedLines: 2,
+		},
+		{
+			name: "missing module data of copper port",
+			registries: registry.RegistryCollection{
+				"eth0": {checkMetric("generic_info_settings_info", 1, map[string]string{"Port": "Twisted Pair"}), checkMetric("driver_info_features_test", 1, nil)},
+			},
+			expectedState: checkStateOk,
+			expectedLines: 1,
+		},
+		{
+			name:          "no ports",
+			registries:    registry.RegistryCollection{},
+			expectedState: checkStateUnknown,
+			expectedLines: 2,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result := evaluateCheck(testCase.registries, testCase.minSpeedBits)
+			assert.Equal(t, testCase.expectedState, result.State)
+			assert.Len(t, result.Problems, testCase.expectedLines-1)
+			if testCase.expectedPerfdata != nil {
+				assert.Equal(t, testCase.expectedPerfdata, result.Perfdata)
+			}
+		})
+	}
+}
+
+func TestCheckResultString(t *testing.T) {
+	expectedOutput := `ETHTOOL CRITICAL - 1 problems on 1 ports | 'eth0_speed'=10000000000b;;;0 'eth0_alarms'=1;;0;0 'eth0_warnings'=0;0;;0
+CRITICAL: eth0 transceiver alarm bias_high
+`
+	result := evaluateCheck(registry.RegistryCollection{
+		"eth0": fibrePortRegistry(checkMetric("module_info_diagnostics_alarms_bias_high", 1, nil)),
+	}, 0)
+	assert.Equal(t, expectedOutput, result.String())
+
+	result = evaluateCheck(registry.RegistryCollection{
+		"eth0": fibrePortRegistry(checkMetric("module_info_diagnostics_alarms_bias_high", 0, nil)),
+	}, 25e9)
+	assert.Contains(t, result.String(), "'eth0_speed'=10000000000b;25000000000:;;0")
+	assert.Contains(t, result.String(), "WARNING: eth0 link speed 10000Mb/s is below 25000Mb/s")
+}
+
+func TestCollectCheckMetrics(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	replayDirectory = ptr("testdata/replay")
+	metricRegistries, err := collectCheckMetrics()
+	require.NoError(t, err)
+
+	result := evaluateCheck(metricRegistries, 1e10)
+	assert.Equal(t, checkStateOk, result.State, result.String())
+	result = evaluateCheck(metricRegistries, 25e9)
+	assert.Equal(t, checkStateWarning, result.State, result.String())
+
+	linuxNetClassPath = ptr("non_existent_testdata/interfaces/sys/class/net")
+	_, err = collectCheckMetrics()
+	assert.Error(t, err)
+}
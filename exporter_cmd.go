@@ -4,19 +4,44 @@ import (
 	"github.com/alecthomas/kingpin/v2"
 )
 
-// TODO: add flag (--report?) for creating full metrics report archive,
-// containing output of all supporter metrics modes for all interfaces,
-// and all meta for interface discovery: bond modes, interface types, etc
-// https://github.com/newrushbolt/go-ethtool-metrics/blob/main/testdata/README.md
-
 var (
 	discoverPortsCommand = kingpin.Command("discover-ports", "Show discovered ports and exit")
+	discoverPortsExplain = discoverPortsCommand.Flag("explain", "Show a table of all the netclass entries with their type, master, driver, operstate and discovery filters chain").Default("false").Bool()
+	discoverPortsOutput  = discoverPortsCommand.Flag("output", "Output format, 'json' always includes all the details shown by --explain").Default("text").Enum("text", "json")
+
+	// Archive layout follows https://github.com/newrushbolt/go-ethtool-metrics/blob/main/testdata/README.md
+	reportCommand  = kingpin.Command("report", "Writes diagnostic tar.gz archive with raw ethtool output for all discovered ports, sysfs attributes, exporter version, flags and metrics, and exit")
+	reportFilePath = reportCommand.Flag("report-file", "Path of the report archive, defaults to 'go-ethtool-exporter-report-<hostname>-<timestamp>.tar.gz' in current directory").Default("").String()
+
+	dumpCommand = kingpin.Command("dump", "Writes all metrics to stdout ONCE. Usefull for troubleshooting, Ansible facts and CI smoke tests")
+	dumpFormat  = dumpCommand.Flag("dump-format", "Output format, 'json' keeps parsed structures of every collector, keyed by device and collector name. 'influx' and 'graphite' are the same as in 'textfile-format'").Default("prometheus").Enum("prometheus", "openmetrics", "json", "influx", "graphite")
+
+	checkCommand  = kingpin.Command("check", "Checks transceivers and link speed ONCE, like Nagios/Icinga plugin. Exit code is 0 for OK, 1 for WARNING, 2 for CRITICAL and 3 for UNKNOWN")
+	checkMinSpeed = checkCommand.Flag("check-min-speed", "Link speed in Mb/s, lower speed of a port is WARNING. Ports without known speed are not checked").Default("0").Int()
 
 	singleTextfileCommand = kingpin.Command("single-textfile", "Writes all metrics to textfile ONCE. Usefull for testing or crons")
 
 	loopTextfileCommand        = kingpin.Command("loop-textfile", "Writes all metrics to textfile every loop-interval")
 	loopTextfileUpdateInterval = loopTextfileCommand.Flag("loop-textfile-update-interval", "Interval between textfile updates").Default("30s").Duration()
 
+	pushCommand      = kingpin.Command("push", "Pushes all metrics to Pushgateway or Prometheus remote-write endpoint, ONCE or every push-interval. Usefull for short-lived hosts, that cannot be scraped")
+	pushUrl          = pushCommand.Flag("push-url", "Pushgateway base URL, eg 'http://pushgateway:9091', remote-write URL, eg 'http://prometheus:9090/api/v1/write', or 'tcp://' and 'udp://' address for 'influx' and 'graphite', eg 'tcp://graphite:2003'").Default("").String()
+	pushProtocol     = pushCommand.Flag("push-protocol", "Push protocol, 'pushgateway' replaces metrics of the group with PUT, 'remote-write' sends snappy-compressed protobuf, 'influx' and 'graphite' send lines in 'textfile-format' formats").Default("pushgateway").Enum("pushgateway", "remote-write", "influx", "graphite")
+	pushJob          = pushCommand.Flag("push-job", "Value of 'job' label, Pushgateway grouping key always starts with it").Default("go-ethtool-exporter").String()
+	pushGroupingKey  = pushCommand.Flag("push-grouping-key", "Comma-separated list of extra 'label=value' grouping key pairs. They are added as labels to every sample with other protocols").Default("instance=<hostname>").String()
+	pushInterval     = pushCommand.Flag("push-interval", "Interval between pushes, '0s' pushes once and exits with non-zero code if push failed").Default("0s").Duration()
+	pushTimeout      = pushCommand.Flag("push-timeout", "Timeout of a single push request").Default("10s").Duration()
+	pushRetries      = pushCommand.Flag("push-retries", "Number of retries after connection errors, 5xx and 429 responses. Other responses are not retried").Default("3").Int()
+	pushRetryBackoff = pushCommand.Flag("push-retry-backoff", "Delay before the first retry, doubled for every next one").Default("1s").Duration()
+
+	// Protocol names and headers format are the same as in OTEL_EXPORTER_OTLP_* variables of OpenTelemetry SDKs
+	otlpCommand  = kingpin.Command("otlp", "Exports all metrics to OpenTelemetry collector over OTLP, every otlp-interval or ONCE")
+	otlpEndpoint = otlpCommand.Flag("otlp-endpoint", "Collector URL, 'http://' scheme disables TLS. Path '/v1/metrics' is used by default with 'http/protobuf', eg 'http://collector:4318'").Default("http://localhost:4318").String()
+	otlpProtocol = otlpCommand.Flag("otlp-protocol", "OTLP transport").Default("http/protobuf").Enum("http/protobuf", "grpc")
+	otlpHeaders  = otlpCommand.Flag("otlp-headers", "Comma-separated list of 'key=value' headers (or gRPC metadata) sent with every export, eg for authentication").Default("").String()
+	otlpInterval = otlpCommand.Flag("otlp-interval", "Interval between exports, '0s' exports once and exits with non-zero code if export failed").Default("30s").Duration()
+	otlpTimeout  = otlpCommand.Flag("otlp-timeout", "Timeout of a single export, including retries of the OTLP exporter").Default("10s").Duration()
+
 	httpServerCommand = kingpin.Command("http-server", "Starts HTTP server of scraping metrics over HTTP(S), like node-exporter does")
 	httpListenAddress = httpServerCommand.Flag("web.listen-address", "Address on which to expose metrics").Default(":9417").String()
 	// Without caching it seems like 2 requests is enough. And +1 for /health method
@@ -25,15 +50,35 @@ var (
 	// - in-memory metric cache and it's max ttl
 	// - TLS-related stuff
 
-	// TODO: add env support???
+	// FLAG GROUP START: Configuration file settings
+	configFile = kingpin.Flag("config.file", "YAML configuration file with discovery, collectors and output settings, see README. Flags given in command line or environment override values from the file. Reloaded on SIGHUP or POST to '/-/reload'").Default("").String()
+	// FLAG GROUP END
+
 	// FLAG GROUP START: Ethtool settings
-	ethtoolPath    = kingpin.Flag("path.ethtool", "").Default("/usr/sbin/ethtool").ExistingFile()
+	// Existence is checked in `validateEthtoolPath`, as it is not needed in replay mode
+	ethtoolPath    = kingpin.Flag("path.ethtool", "").Default("/usr/sbin/ethtool").String()
 	ethtoolTimeout = kingpin.Flag("ethtool-timeout", "Timeout for ethtool command execution.").Default("5s").Duration()
+	// Sysfs is not affected by network namespace of the thread, so only ethtool needs it
+	ethtoolHostNetns = kingpin.Flag("ethtool-host-netns", "Run ethtool in the network namespace of PID 1 from 'path.procfs', eg when exporter runs in a container without host network. Requires CAP_SYS_ADMIN").Default("false").Bool()
+	ethtoolNetlink   = kingpin.Flag("ethtool-netlink", "Read link settings ('generic_info_*' metrics) over ethtool netlink instead of running ethtool. Other collectors still run ethtool").Default("false").Bool()
+	replayDirectory  = kingpin.Flag("replay-dir", "Read recorded ethtool output instead of running ethtool, and use '<replay-dir>/sysfs' and '<replay-dir>/procfs' as 'path.sysfs' and 'path.procfs'. Expects extracted 'report' archive, go-ethtool-metrics testdata is not supported as its instances are not named by interfaces and have no sysfs").Default("").String()
 	// FLAG GROUP END
 
 	// FLAG GROUP START: Various paths settings
-	linuxNetClassPath = kingpin.Flag("path.sysfs.net.class", "").Default("/sys/class/net").ExistingDir()
-	textfileDirectory = kingpin.Flag("path.textfile-directory", "Path to the node_exporter textfile directory. Only used in 'single-textfile' and 'loop-textfile' modes").Default("/var/lib/node-exporter/textfiles").String()
+	sysfsPath          = kingpin.Flag("path.sysfs", "Sysfs mountpoint, eg '/host/sys' in a container. Root for all the sysfs paths below").Default("/sys").ExistingDir()
+	procfsPath         = kingpin.Flag("path.procfs", "Procfs mountpoint, eg '/host/proc' in a container. Root for all the procfs paths below").Default("/proc").ExistingDir()
+	linuxNetClassPath  = kingpin.Flag("path.sysfs.net.class", "Overrides '<path.sysfs>/class/net'").Default("").String()
+	procNetBondingPath = kingpin.Flag("path.procfs.net.bonding", "Directory with bonding driver status files, used by bond-info collector. Overrides '<path.procfs>/net/bonding'").Default("").String()
+	runNetnsPath       = kingpin.Flag("path.run.netns", "Directory with named network namespaces, the same as used by 'ip netns'").Default("/run/netns").String()
+	textfileDirectory  = kingpin.Flag("path.textfile-directory", "Path to the node_exporter textfile directory. Only used in 'single-textfile' and 'loop-textfile' modes").Default("/var/lib/node-exporter/textfiles").String()
+	// FLAG GROUP END
+
+	// FLAG GROUP START: Textfile settings
+	// Checked in `validateTextfileFlags`
+	textfileName         = kingpin.Flag("textfile-name", "Textfile name inside 'path.textfile-directory'. node_exporter only reads '*.prom' files").Default("ethtool_exporter.prom").String()
+	textfileMode         = kingpin.Flag("textfile-mode", "Octal permissions of textfiles, eg '0640'. Umask is not applied").Default("0644").String()
+	textfileFsync        = kingpin.Flag("textfile-fsync", "Sync textfiles and textfile directory to disk after every update").Default("false").Bool()
+	textfilePerCollector = kingpin.Flag("textfile-per-collector", "Write metrics of every collector to a separate '<name>_<collector>.prom' file, so failed update of one file doesn't affect others. Metrics not related to collectors go to '<name>_exporter.prom'").Default("false").Bool()
 	// FLAG GROUP END
 
 	// FLAG GROUP START: Collectors, enabled by default
@@ -53,6 +98,8 @@ var (
 	collectStatisticsPerQueueGeneral   = kingpin.Flag("collect-statistics-per-queue-general", "").Default("false").Bool()
 	collectStatisticsPerQueuePerType   = kingpin.Flag("collect-statistics-per-queue-per-type", "").Default("false").Bool()
 	collectStatisticsPerQueueXdp       = kingpin.Flag("collect-statistics-per-queue-xdp", "").Default("false").Bool()
+	collectBondInfo                    = kingpin.Flag("collect-bond-info", "Collect bonding mode, MII status, link failures and LACP state of bond masters and their slaves. Only makes sense with bond masters discovered").Default("false").Bool()
+	collectDeviceInfo                  = kingpin.Flag("collect-device-info", "Expose 'device_info' metric with PCI address, vendor and device IDs, NUMA node, permanent MAC, phys_port_id, driver and ifalias from sysfs").Default("false").Bool()
 	// FLAG GROUP END
 
 	// FLAG GROUP START: Port detection settings
@@ -60,11 +107,25 @@ var (
 	discoverAllowedPortTypes = kingpin.Flag("discover-allowed-port-types", "Comma-separated list of allowed interface types (see if_arp.h). Set to empty ('') to allow all port types").Default("1,").String()
 	discoverAllPorts         = kingpin.Flag("discover-all-ports", "Force discover all the ports, ignoring all the other discover flags, EXCEPT for 'discover-allowed-port-types' and 'discover-ports-regexp'").Default("false").Bool()
 	discoverBondSlaves       = kingpin.Flag("discover-bond-slaves", "Whether to discover ports that are enslaved by bonds").Default("true").Bool()
+	discoverBondMasters      = kingpin.Flag("discover-bond-masters", "Whether to discover bond master ports").Default("false").Bool()
 	discoverBridgeSlaves     = kingpin.Flag("discover-bridge-slaves", "Whether to discover ports that are enslaved by bridges").Default("false").Bool()
+	discoverTeamSlaves       = kingpin.Flag("discover-team-slaves", "Whether to discover ports that are enslaved by libteam devices").Default("false").Bool()
+	discoverExpandLower      = kingpin.Flag("discover-expand-lower-devices", "Replace discovered VLAN, team, macvlan and other stacked ports with their lowest devices (via 'lower_*' links), eg VLAN over bond expands to bond slaves. Bond masters discovered via 'discover-bond-masters' are kept").Default("false").Bool()
+	discoverOvsSlaves        = kingpin.Flag("discover-ovs-slaves", "Whether to discover ports that are attached to Open vSwitch datapath (their master is 'ovs-system')").Default("false").Bool()
+	discoverOvsSkipVirtual   = kingpin.Flag("discover-ovs-skip-virtual-ports", "Skip OVS ports that are not backed by a hardware device, such as internal, tap and tunnel ports").Default("true").Bool()
+	discoverPhysicalPorts    = kingpin.Flag("discover-physical-ports", "Whether to discover ports backed by a PCI or platform device, regardless of bond or bridge membership. Virtual devices (veth, tun, dummy, etc) are never physical").Default("false").Bool()
+	discoverVirtualFunctions = kingpin.Flag("discover-virtual-functions", "Whether to discover SR-IOV virtual functions (ports with 'device/physfn' link)").Default("false").Bool()
+	discoverRepresentors     = kingpin.Flag("discover-representors", "Whether to discover switchdev representors of VFs, SFs and PFs (ports with 'phys_port_name' like 'pf0vf1')").Default("false").Bool()
+	discoverSkipVfs          = kingpin.Flag("discover-skip-virtual-functions", "Never discover SR-IOV virtual functions, even if they pass other discover flags").Default("false").Bool()
+	discoverSkipRepresentors = kingpin.Flag("discover-skip-representors", "Never discover switchdev representors, even if they pass other discover flags").Default("false").Bool()
 	discoverPortsRegexp      = kingpin.Flag("discover-ports-regexp", "Only discover ports with names matching this regexp").Default(".+").Regexp()
+	discoverPortsExclude     = optionalRegexp(kingpin.Flag("discover-ports-exclude-regexp", "Never discover ports with names matching this regexp"))
+	discoverOperStates       = kingpin.Flag("discover-allowed-operstates", "Comma-separated list of allowed operstates (up, down, dormant, notpresent, lowerlayerdown, testing, unknown). Empty allows all operstates").Default("").String()
+	discoverSkipOperStates   = kingpin.Flag("discover-skip-operstates", "Comma-separated list of operstates to never discover, eg 'notpresent,lowerlayerdown'").Default("").String()
+	discoverRequireCarrier   = kingpin.Flag("discover-require-carrier", "Only discover ports with carrier, eg with cable connected and link up").Default("false").Bool()
+	discoverDriversRegexp    = optionalRegexp(kingpin.Flag("discover-drivers-regexp", "Only discover ports with driver names (from 'device/driver' link) matching this regexp. Virtual ports have empty driver name"))
+	discoveryCacheEnabled    = kingpin.Flag("discovery-cache", "Cache discovered ports until netlink (or inotify) reports link changes. Only used in 'http-server', 'loop-textfile', and looped 'push' and 'otlp' modes").Default("false").Bool()
 	// Not yet implemented
-	// discoverOvsSlaves
-	// discoverBondMasters
 	// Detect aliases and naming types?
 	// FLAG GROUP END
 
@@ -86,6 +147,24 @@ var (
 	absentMetricsStatisticsExposeNan           = kingpin.Flag("absent-metrics-statistics-expose-nan", "").Default("false").Bool()
 	absentMetricsStatisticsExposeTotalCounter  = kingpin.Flag("absent-metrics-statistics-expose-total-counter", "").Default("false").Bool()
 	absentMetricsStatisticsExposeDetailedInfo  = kingpin.Flag("absent-metrics-statistics-expose-detailed-info", "").Default("false").Bool()
+	absentMetricsBondInfoExposeNan             = kingpin.Flag("absent-metrics-bond-info-expose-nan", "").Default("false").Bool()
+	absentMetricsBondInfoExposeTotalCounter    = kingpin.Flag("absent-metrics-bond-info-expose-total-counter", "").Default("false").Bool()
+	absentMetricsBondInfoExposeDetailedInfo    = kingpin.Flag("absent-metrics-bond-info-expose-detailed-info", "").Default("false").Bool()
+	// FLAG GROUP END
+
+	// FLAG GROUP START: Link events settings
+	watchLinkEvents               = kingpin.Flag("watch-link-events", "Subscribe to netlink link notifications and expose link up/down, speed and module change counters. Only used in 'http-server', 'loop-textfile', and looped 'push' and 'otlp' modes").Default("false").Bool()
+	watchLinkEventsEthtoolMonitor = kingpin.Flag("watch-link-events-ethtool-monitor", "Also subscribe to ethtool netlink monitor notifications for speed and module changes").Default("true").Bool()
+	// FLAG GROUP END
+
+	// FLAG GROUP START: Network namespaces settings
+	collectNamedNetns   = kingpin.Flag("collect-named-netns", "Also discover ports and collect metrics inside named network namespaces (see 'path.run.netns'). Metrics are labeled with 'netns'. Requires CAP_SYS_ADMIN").Default("false").Bool()
+	collectProcessNetns = kingpin.Flag("collect-process-netns", "Also discover ports and collect metrics inside network namespaces of all the processes, eg containers. Such namespaces are labeled as 'pid/<lowest pid>'").Default("false").Bool()
+	// FLAG GROUP END
+
+	// FLAG GROUP START: Output format settings
+	textfileFormat       = kingpin.Flag("textfile-format", "Textfile format, 'influx' is Influx line protocol with 'device' and 'collector' tags, 'graphite' is Graphite plaintext protocol with 'graphite-path-template' paths. Only 'prometheus' is supported by node_exporter").Default("prometheus").Enum("prometheus", "influx", "graphite")
+	graphitePathTemplate = kingpin.Flag("graphite-path-template", "Graphite path, '{name}' is metric name, '{hostname}' is hostname, other placeholders are label values. Labels not used in the template become Graphite tags").Default("ethtool.{hostname}.{device}.{name}").String()
 	// FLAG GROUP END
 
 	// FLAG GROUP START: Metrics processing settings
@@ -93,5 +172,7 @@ var (
 	// https://github.com/newrushbolt/go-ethtool-metrics/blob/9c84000a5e0736e721630447958639d09cc532d1/pkg/metrics/statistics/statistics_structs.go#L6
 	statisticsGenerateMissingPerQueueMetrics = kingpin.Flag("statistics-generate-missing-per-queue-metrics", "Generate missing metrics per queue if missing (eg in Broadcom bnxt_en driver)").Default("true").Bool()
 	listLabelFormat                          = kingpin.Flag("list-label-format", "How to transform lists of strings to prometheus labels").Default("multi-label").Enum("single-label", "multi-label", "both")
+	labelSriovTopology                       = kingpin.Flag("label-sriov-topology", "Add 'sriov_role' (pf, vf or representor) and 'pf_device' labels to every metric of SR-IOV ports").Default("false").Bool()
+	labelDeviceInfo                          = kingpin.Flag("label-device-info", "Comma-separated list of 'device_info' labels to add to every metric, eg 'pci_address,permanent_mac'. Beware of 16 labels limit per metric").Default("").String()
 	// FLAG GROUP END
 )
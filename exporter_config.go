@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/alecthomas/kingpin/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Every leaf of the config file sets a global flag, named after `flag` tags joined along the path.
+// Leaves are pointers, so values missing in the file keep flag defaults.
+type fileConfig struct {
+	Ethtool    ethtoolFileConfig    `yaml:"ethtool"`
+	Paths      pathsFileConfig      `yaml:"paths"`
+	Discovery  discoveryFileConfig  `yaml:"discovery"`
+	Collectors collectorsFileConfig `yaml:"collectors"`
+	LinkEvents linkEventsFileConfig `yaml:"link_events"`
+	Netns      netnsFileConfig      `yaml:"netns"`
+	Output     outputFileConfig     `yaml:"output"`
+	Textfile   textfileFileConfig   `yaml:"textfile"`
+	// Not a flag, see exporter_overrides.go
+	InterfaceOverrides []interfaceOverrideFileConfig `yaml:"interface_overrides" flag:"-"`
+}
+
+type ethtoolFileConfig struct {
+	Path      *string `yaml:"path" flag:"path.ethtool"`
+	Timeout   *string `yaml:"timeout" flag:"ethtool-timeout"`
+	HostNetns *bool   `yaml:"host_netns" flag:"ethtool-host-netns"`
+	Netlink   *bool   `yaml:"netlink" flag:"ethtool-netlink"`
+	ReplayDir *string `yaml:"replay_dir" flag:"replay-dir"`
+}
+
+type pathsFileConfig struct {
+	Sysfs             *string `yaml:"sysfs" flag:"path.sysfs"`
+	Procfs            *string `yaml:"procfs" flag:"path.procfs"`
+	SysfsNetClass     *string `yaml:"sysfs_net_class" flag:"path.sysfs.net.class"`
+	ProcfsNetBonding  *string `yaml:"procfs_net_bonding" flag:"path.procfs.net.bonding"`
+	RunNetns          *string `yaml:"run_netns" flag:"path.run.netns"`
+	TextfileDirectory *string `yaml:"textfile_directory" flag:"path.textfile-directory"`
+}
+
+type discoveryFileConfig struct {
+	AllowedPortTypes     *[]string `yaml:"allowed_port_types" flag:"discover-allowed-port-types"`
+	AllPorts             *bool     `yaml:"all_ports" flag:"discover-all-ports"`
+	BondSlaves           *bool     `yaml:"bond_slaves" flag:"discover-bond-slaves"`
+	BondMasters          *bool     `yaml:"bond_masters" flag:"discover-bond-masters"`
+	BridgeSlaves         *bool     `yaml:"bridge_slaves" flag:"discover-bridge-slaves"`
+	TeamSlaves           *bool     `yaml:"team_slaves" flag:"discover-team-slaves"`
+	ExpandLowerDevices   *bool     `yaml:"expand_lower_devices" flag:"discover-expand-lower-devices"`
+	OvsSlaves            *bool     `yaml:"ovs_slaves" flag:"discover-ovs-slaves"`
+	OvsSkipVirtualPorts  *bool     `yaml:"ovs_skip_virtual_ports" flag:"discover-ovs-skip-virtual-ports"`
+	PhysicalPorts        *bool     `yaml:"physical_ports" flag:"discover-physical-ports"`
+	VirtualFunctions     *bool     `yaml:"virtual_functions" flag:"discover-virtual-functions"`
+	Representors         *bool     `yaml:"representors" flag:"discover-representors"`
+	SkipVirtualFunctions *bool     `yaml:"skip_virtual_functions" flag:"discover-skip-virtual-functions"`
+	SkipRepresentors     *bool     `yaml:"skip_representors" flag:"discover-skip-representors"`
+	PortsRegexp          *string   `yaml:"ports_regexp" flag:"discover-ports-regexp"`
+	PortsExcludeRegexp   *string   `yaml:"ports_exclude_regexp" flag:"discover-ports-exclude-regexp"`
+	AllowedOperStates    *[]string `yaml:"allowed_operstates" flag:"discover-allowed-operstates"`
+	SkipOperStates       *[]string `yaml:"skip_operstates" flag:"discover-skip-operstates"`
+	RequireCarrier       *bool     `yaml:"require_carrier" flag:"discover-require-carrier"`
+	DriversRegexp        *string   `yaml:"drivers_regexp" flag:"discover-drivers-regexp"`
+	Cache                *bool     `yaml:"cache" flag:"discovery-cache"`
+}
+
+type collectorsFileConfig struct {
+	All         *bool                 `yaml:"all" flag:"collect-all-metrics"`
+	GenericInfo genericInfoFileConfig `yaml:"generic_info"`
+	DriverInfo  driverInfoFileConfig  `yaml:"driver_info"`
+	ModuleInfo  moduleInfoFileConfig  `yaml:"module_info"`
+	Statistics  statisticsFileConfig  `yaml:"statistics"`
+	BondInfo    bondInfoFileConfig    `yaml:"bond_info"`
+	DeviceInfo  deviceInfoFileConfig  `yaml:"device_info"`
+}
+
+type genericInfoFileConfig struct {
+	Settings      *bool                   `yaml:"settings" flag:"collect-generic-info-settings"`
+	Modes         *bool                   `yaml:"modes" flag:"collect-generic-info-modes"`
+	AbsentMetrics absentMetricsFileConfig `yaml:"absent_metrics" flag:"absent-metrics-generic-info-"`
+}
+
+type driverInfoFileConfig struct {
+	Common        *bool                   `yaml:"common" flag:"collect-driver-info-common"`
+	Features      *bool                   `yaml:"features" flag:"collect-driver-info-features"`
+	AbsentMetrics absentMetricsFileConfig `yaml:"absent_metrics" flag:"absent-metrics-driver-info-"`
+}
+
+type moduleInfoFileConfig struct {
+	DiagnosticsAlarms   *bool                   `yaml:"diagnostics_alarms" flag:"collect-module-info-diagnostics-alarms"`
+	DiagnosticsWarnings *bool                   `yaml:"diagnostics_warnings" flag:"collect-module-info-diagnostics-warnings"`
+	DiagnosticsValues   *bool                   `yaml:"diagnostics_values" flag:"collect-module-info-diagnostics-values"`
+	Vendor              *bool                   `yaml:"vendor" flag:"collect-module-info-vendor"`
+	AbsentMetrics       absentMetricsFileConfig `yaml:"absent_metrics" flag:"absent-metrics-module-info-"`
+}
+
+type statisticsFileConfig struct {
+	General                        *bool                   `yaml:"general" flag:"collect-statistics-general"`
+	PerQueueGeneral                *bool                   `yaml:"per_queue_general" flag:"collect-statistics-per-queue-general"`
+	PerQueuePerType                *bool                   `yaml:"per_queue_per_type" flag:"collect-statistics-per-queue-per-type"`
+	PerQueueXdp                    *bool                   `yaml:"per_queue_xdp" flag:"collect-statistics-per-queue-xdp"`
+	GenerateMissingPerQueueMetrics *bool                   `yaml:"generate_missing_per_queue_metrics" flag:"statistics-generate-missing-per-queue-metrics"`
+	AbsentMetrics                  absentMetricsFileConfig `yaml:"absent_metrics" flag:"absent-metrics-statistics-"`
+}
+
+type bondInfoFileConfig struct {
+	Enabled       *bool                   `yaml:"enabled" flag:"collect-bond-info"`
+	AbsentMetrics absentMetricsFileConfig `yaml:"absent_metrics" flag:"absent-metrics-bond-info-"`
+}
+
+type deviceInfoFileConfig struct {
+	Enabled *bool `yaml:"enabled" flag:"collect-device-info"`
+}
+
+type linkEventsFileConfig struct {
+	Enabled        *bool `yaml:"enabled" flag:"watch-link-events"`
+	EthtoolMonitor *bool `yaml:"ethtool_monitor" flag:"watch-link-events-ethtool-monitor"`
+}
+
+type netnsFileConfig struct {
+	Named     *bool `yaml:"named" flag:"collect-named-netns"`
+	Processes *bool `yaml:"processes" flag:"collect-process-netns"`
+}
+
+type outputFileConfig struct {
+	ListLabelFormat      *string   `yaml:"list_label_format" flag:"list-label-format"`
+	LabelSriovTopology   *bool     `yaml:"label_sriov_topology" flag:"label-sriov-topology"`
+	LabelDeviceInfo      *[]string `yaml:"label_device_info" flag:"label-device-info"`
+	TextfileFormat       *string   `yaml:"textfile_format" flag:"textfile-format"`
+	GraphitePathTemplate *string   `yaml:"graphite_path_template" flag:"graphite-path-template"`
+}
+
+type textfileFileConfig struct {
+	Name         *string `yaml:"name" flag:"textfile-name"`
+	Mode         *string `yaml:"mode" flag:"textfile-mode"`
+	Fsync        *bool   `yaml:"fsync" flag:"textfile-fsync"`
+	PerCollector *bool   `yaml:"per_collector" flag:"textfile-per-collector"`
+}
+
+type absentMetricsFileConfig struct {
+	ExposeNan          *bool `yaml:"expose_nan" flag:"expose-nan"`
+	ExposeTotalCounter *bool `yaml:"expose_total_counter" flag:"expose-total-counter"`
+	ExposeDetailedInfo *bool `yaml:"expose_detailed_info" flag:"expose-detailed-info"`
+}
+
+const (
+	configFileFlagName = "config.file"
+	flagEnvarPrefix    = "GO_ETHTOOL_EXPORTER_"
+)
+
+var flagEnvarNameRegexp = regexp.MustCompile(`[^A-Z0-9]+`)
+
+var (
+	// Held for writing while flags are re-parsed, collection holds it for reading
+	configLock sync.RWMutex
+	// Last config file values that passed validation, used to roll back broken reloads
+	appliedConfigArgs []string
+)
+
+// Unknown keys are errors, so typos don't silently fall back to defaults
+func readConfigFile(configPath string) (fileConfig, error) {
+	var config fileConfig
+	configRaw, err := os.ReadFile(configPath)
+	if err != nil {
+		return config, fmt.Errorf("cannot read config file: %w", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(configRaw))
+	decoder.KnownFields(true)
+	err = decoder.Decode(&config)
+	// Empty file is a valid config
+	if err != nil && !errors.Is(err, io.EOF) {
+		return config, fmt.Errorf("cannot parse config file %s: %w", configPath, err)
+	}
+	return config, nil
+}
+
+// Returns command line arguments for every value set in the config
+func configFlagArgs(value reflect.Value, flagPrefix string) []string {
+	args := []string{}
+	for fieldIndex := range value.NumField() {
+		flagTag := value.Type().Field(fieldIndex).Tag.Get("flag")
+		if flagTag == "-" {
+			continue
+		}
+		flagName := flagPrefix + flagTag
+		fieldValue := value.Field(fieldIndex)
+		if fieldValue.Kind() == reflect.Struct {
+			args = append(args, configFlagArgs(fieldValue, flagName)...)
+			continue
+		}
+		if fieldValue.IsNil() {
+			continue
+		}
+		switch typedValue := fieldValue.Elem().Interface().(type) {
+		case bool:
+			if typedValue {
+				args = append(args, "--"+flagName)
+			} else {
+				args = append(args, "--no-"+flagName)
+			}
+		case []string:
+			args = append(args, fmt.Sprintf("--%s=%s", flagName, strings.Join(typedValue, ",")))
+		default:
+			args = append(args, fmt.Sprintf("--%s=%v", flagName, typedValue))
+		}
+	}
+	return args
+}
+
+// Derived the same way in utils/generate_exporter_help.go, eg `path.sysfs` is set via `GO_ETHTOOL_EXPORTER_PATH_SYSFS`
+func flagEnvarName(flagName string) string {
+	return flagEnvarPrefix + flagEnvarNameRegexp.ReplaceAllString(strings.ToUpper(flagName), "_")
+}
+
+// Command flags are prefixed with command name, unless their name already starts with it, eg `--push-url`.
+// So the same envar cannot set flags of different commands.
+func commandFlagEnvarName(commandName string, flagName string) string {
+	envarName := flagEnvarName(flagName)
+	if strings.HasPrefix(envarName, flagEnvarName(commandName)+"_") {
+		return envarName
+	}
+	return flagEnvarName(commandName + "-" + flagName)
+}
+
+// Every flag, including command ones, can be set via environment variable.
+// Help and version flags are skipped, as well as hidden completion flags.
+func setFlagEnvars(app *kingpin.Application) {
+	for _, flagModel := range app.Model().Flags {
+		if flagModel.Hidden || flagModel.Name == "help" || flagModel.Name == "version" {
+			continue
+		}
+		app.GetFlag(flagModel.Name).Envar(flagEnvarName(flagModel.Name))
+	}
+	for _, commandModel := range app.Model().Commands {
+		command := app.GetCommand(commandModel.Name)
+		for _, flagModel := range commandModel.Flags {
+			command.GetFlag(flagModel.Name).Envar(commandFlagEnvarName(commandModel.Name, flagModel.Name))
+		}
+	}
+}
+
+// Flags given in command line or environment with their raw values, they override config file values
+func userFlagValues(app *kingpin.Application, args []string) (map[string]string, error) {
+	parseContext, err := app.ParseContext(args)
+	if err != nil {
+		return nil, err
+	}
+	flagValues := map[string]string{}
+	for _, element := range parseContext.Elements {
+		if flagClause, ok := element.Clause.(*kingpin.FlagClause); ok {
+			flagValue := ""
+			if element.Value != nil {
+				flagValue = *element.Value
+			}
+			flagValues[flagClause.Model().Name] = flagValue
+		}
+	}
+	for _, flagModel := range app.Model().Flags {
+		if _, found := flagValues[flagModel.Name]; found || flagModel.Envar == "" {
+			continue
+		}
+		// Kingpin ignores empty variables as well
+		if envarValue := os.Getenv(flagModel.Envar); envarValue != "" {
+			flagValues[flagModel.Name] = envarValue
+		}
+	}
+	return flagValues, nil
+}
+
+// Kingpin refuses repeated flags, so user flags are dropped from config args
+func withoutUserFlags(configArgs []string, userFlags map[string]string) []string {
+	return slices.DeleteFunc(slices.Clone(configArgs), func(arg string) bool {
+		flagName, _, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		_, isUserFlag := userFlags[flagName]
+		_, isUserBoolFlag := userFlags[strings.TrimPrefix(flagName, "no-")]
+		return isUserFlag || isUserBoolFlag
+	})
+}
+
+// Kingpin only resets flags with defaults on parse, so optional regexps get empty default, which unsets them.
+// Otherwise the regexp removed from config file would be kept after reload.
+type optionalRegexpValue struct {
+	value **regexp.Regexp
+}
+
+func (flagValue optionalRegexpValue) Set(rawValue string) error {
+	if rawValue == "" {
+		*flagValue.value = nil
+		return nil
+	}
+	compiledValue, err := regexp.Compile(rawValue)
+	if err != nil {
+		return err
+	}
+	*flagValue.value = compiledValue
+	return nil
+}
+
+func (flagValue optionalRegexpValue) String() string {
+	if *flagValue.value == nil {
+		return ""
+	}
+	return (*flagValue.value).String()
+}
+
+// Same as `Regexp()`, but empty value means no regexp
+func optionalRegexp(flag *kingpin.FlagClause) **regexp.Regexp {
+	value := new(*regexp.Regexp)
+	flag.Default("").SetValue(optionalRegexpValue{value: value})
+	return value
+}
+
+// Parses command line, applying config file values first if the file is given.
+// Config file is read before any flag validation, so it may also fix invalid defaults, eg ethtool path.
+// On reload flags are parsed again from scratch, so values removed from the file get back to defaults.
+// That's why every flag must have a default, see optionalRegexp.
+// Flag values are validated by kingpin, previous values are restored if validation fails.
+func parseFlags(app *kingpin.Application, args []string) (string, error) {
+	userFlags, err := userFlagValues(app, args)
+	if err != nil {
+		return "", err
+	}
+	configPath := userFlags[configFileFlagName]
+	if configPath == "" {
+		return app.Parse(args)
+	}
+	config, err := readConfigFile(configPath)
+	if err != nil {
+		return "", err
+	}
+	overrides, err := compileInterfaceOverrides(config.InterfaceOverrides)
+	if err != nil {
+		return "", fmt.Errorf("invalid config file %s: %w", configPath, err)
+	}
+	configArgs := withoutUserFlags(configFlagArgs(reflect.ValueOf(config), ""), userFlags)
+	slog.Debug("Applying config file", "configPath", configPath, "configArgs", configArgs, "interfaceOverrides", len(overrides))
+
+	command, err := app.Parse(append(slices.Clone(configArgs), args...))
+	if err != nil {
+		if appliedConfigArgs != nil {
+			_, restoreErr := app.Parse(append(slices.Clone(appliedConfigArgs), args...))
+			if restoreErr != nil {
+				slog.Error("Cannot restore previous config", "error", restoreErr)
+			}
+		}
+		return "", fmt.Errorf("invalid config file %s: %w", configPath, err)
+	}
+	appliedConfigArgs = configArgs
+	interfaceOverrides = overrides
+	return command, nil
+}
+
+// Link events watcher, HTTP server and OTLP exporter are not restarted, their settings require exporter restart.
+// Loop modes hold config read lock while metrics are collected and rendered, so they never see half-applied flags.
+// Pushes, OTLP exports and their retries are sent without it, so reload does not wait for slow endpoints.
+func reloadConfig() error {
+	configLock.Lock()
+	defer configLock.Unlock()
+
+	_, err := parseFlags(kingpin.CommandLine, os.Args[1:])
+	if err != nil {
+		return err
+	}
+	if *collectAllMetrics {
+		enableAllMetricCollectionFlags()
+	}
+	if discoveryCache != nil {
+		discoveryCache.SetOptions(getNetClassPath(), createDiscoveryConfig(), parseAllowedInterfaceTypes(*discoverAllowedPortTypes))
+	}
+	slog.Info("Config file reloaded", "configPath", *configFile)
+	return nil
+}
+
+func startReloadOnSighup() {
+	if *configFile == "" {
+		return
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			err := reloadConfig()
+			if err != nil {
+				slog.Error("Cannot reload config file, keeping previous config", "error", err)
+			}
+		}
+	}()
+}
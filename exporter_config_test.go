@@ -0,0 +1,243 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadConfigFileAllKeys(t *testing.T) {
+	config, err := readConfigFile("testdata/config.yaml")
+	assert.NoError(t, err)
+	assert.Len(t, config.InterfaceOverrides, 2)
+	_, err = compileInterfaceOverrides(config.InterfaceOverrides)
+	assert.NoError(t, err)
+	configArgs := configFlagArgs(reflect.ValueOf(config), "")
+	assert.Len(t, configArgs, 76)
+	assert.Contains(t, configArgs, "--absent-metrics-module-info-expose-nan")
+	assert.Contains(t, configArgs, "--no-discover-all-ports")
+	assert.Contains(t, configArgs, "--discover-allowed-port-types=1")
+	assert.Contains(t, configArgs, "--label-device-info=")
+	// Every key sets an existing global flag
+	configFlagNames := []string{}
+	for _, configArg := range configArgs {
+		flagName, _, _ := strings.Cut(strings.TrimPrefix(configArg, "--"), "=")
+		if kingpin.CommandLine.GetFlag(flagName) == nil {
+			flagName = strings.TrimPrefix(flagName, "no-")
+		}
+		assert.NotNil(t, kingpin.CommandLine.GetFlag(flagName), configArg)
+		configFlagNames = append(configFlagNames, flagName)
+	}
+	// And every global flag has a key
+	for _, flagModel := range kingpin.CommandLine.Model().Flags {
+		if flagModel.Hidden || slices.Contains([]string{"help", "version", configFileFlagName}, flagModel.Name) {
+			continue
+		}
+		assert.Contains(t, configFlagNames, flagModel.Name)
+	}
+}
+
+func TestReadConfigFileErrors(t *testing.T) {
+	configDirectory := t.TempDir()
+	emptyConfigPath := path.Join(configDirectory, "empty.yaml")
+	assert.NoError(t, os.WriteFile(emptyConfigPath, []byte{}, 0644))
+	config, err := readConfigFile(emptyConfigPath)
+	assert.NoError(t, err)
+	assert.Empty(t, configFlagArgs(reflect.ValueOf(config), ""))
+
+	_, err = readConfigFile(path.Join(configDirectory, "non_existent.yaml"))
+	assert.Error(t, err)
+
+	unknownKeyConfigPath := path.Join(configDirectory, "unknown.yaml")
+	assert.NoError(t, os.WriteFile(unknownKeyConfigPath, []byte("discovery:\n  all_port: true\n"), 0644))
+	_, err = readConfigFile(unknownKeyConfigPath)
+	assert.ErrorContains(t, err, "field all_port not found")
+
+	wrongTypeConfigPath := path.Join(configDirectory, "wrong_type.yaml")
+	assert.NoError(t, os.WriteFile(wrongTypeConfigPath, []byte("discovery:\n  all_ports: sure\n"), 0644))
+	_, err = readConfigFile(wrongTypeConfigPath)
+	assert.Error(t, err)
+}
+
+func TestParseFlagsWithConfigFile(t *testing.T) {
+	t.Cleanup(func() {
+		appliedConfigArgs = nil
+		interfaceOverrides = nil
+	})
+	app := kingpin.New("test", "")
+	app.Flag(configFileFlagName, "").String()
+	testPortsRegexp := app.Flag("discover-ports-regexp", "").Default(".+").Regexp()
+	testAllPorts := app.Flag("discover-all-ports", "").Default("false").Bool()
+	testListLabelFormat := app.Flag("list-label-format", "").Default("multi-label").Enum("single-label", "multi-label", "both")
+	app.Command("run", "")
+
+	configPath := path.Join(t.TempDir(), "config.yaml")
+	args := []string{"--config.file=" + configPath, "--discover-ports-regexp=eth4", "run"}
+
+	// Command line flags override the file
+	assert.NoError(t, os.WriteFile(configPath, []byte("discovery:\n  all_ports: true\n  ports_regexp: eth0\noutput:\n  list_label_format: both\n"), 0644))
+	command, err := parseFlags(app, args)
+	assert.NoError(t, err)
+	assert.Equal(t, "run", command)
+	assert.Equal(t, "eth4", (*testPortsRegexp).String())
+	assert.True(t, *testAllPorts)
+	assert.Equal(t, "both", *testListLabelFormat)
+
+	// Invalid values are rejected and previous ones are kept
+	assert.NoError(t, os.WriteFile(configPath, []byte("output:\n  list_label_format: weird\n"), 0644))
+	_, err = parseFlags(app, args)
+	assert.ErrorContains(t, err, "weird")
+	assert.True(t, *testAllPorts)
+	assert.Equal(t, "both", *testListLabelFormat)
+
+	// Values removed from the file get back to defaults
+	assert.NoError(t, os.WriteFile(configPath, []byte("output:\n  list_label_format: single-label\n"), 0644))
+	_, err = parseFlags(app, args)
+	assert.NoError(t, err)
+	assert.False(t, *testAllPorts)
+	assert.Equal(t, "single-label", *testListLabelFormat)
+
+	// Interface overrides are validated as well
+	assert.NoError(t, os.WriteFile(configPath, []byte("interface_overrides:\n  - name_regexp: eth[\n"), 0644))
+	_, err = parseFlags(app, args)
+	assert.ErrorContains(t, err, "invalid name_regexp")
+	assert.NoError(t, os.WriteFile(configPath, []byte("interface_overrides:\n  - name_regexp: eth0\n"), 0644))
+	_, err = parseFlags(app, args)
+	assert.NoError(t, err)
+	assert.Len(t, interfaceOverrides, 1)
+}
+
+func TestParseFlagsReloadRemovesOptionalRegexp(t *testing.T) {
+	t.Cleanup(func() { appliedConfigArgs = nil })
+	app := kingpin.New("test", "")
+	app.Flag(configFileFlagName, "").String()
+	testPortsExclude := optionalRegexp(app.Flag("discover-ports-exclude-regexp", ""))
+	testDriversRegexp := optionalRegexp(app.Flag("discover-drivers-regexp", ""))
+
+	configPath := path.Join(t.TempDir(), "config.yaml")
+	args := []string{"--config.file=" + configPath}
+	assert.NoError(t, os.WriteFile(configPath, []byte("discovery:\n  ports_exclude_regexp: eth1\n  drivers_regexp: ixgbe\n"), 0644))
+	_, err := parseFlags(app, args)
+	assert.NoError(t, err)
+	require.NotNil(t, *testPortsExclude)
+	assert.Equal(t, "eth1", (*testPortsExclude).String())
+	require.NotNil(t, *testDriversRegexp)
+
+	// Reload without the keys drops the regexps
+	assert.NoError(t, os.WriteFile(configPath, []byte("discovery: {}\n"), 0644))
+	_, err = parseFlags(app, args)
+	assert.NoError(t, err)
+	assert.Nil(t, *testPortsExclude)
+	assert.Nil(t, *testDriversRegexp)
+
+	assert.NoError(t, os.WriteFile(configPath, []byte("discovery:\n  ports_exclude_regexp: eth[\n"), 0644))
+	_, err = parseFlags(app, args)
+	assert.Error(t, err)
+}
+
+func TestParseFlagsWithoutConfigFile(t *testing.T) {
+	app := kingpin.New("test", "")
+	app.Flag(configFileFlagName, "").String()
+	testAllPorts := app.Flag("discover-all-ports", "").Default("false").Bool()
+	command, err := parseFlags(app, []string{"--discover-all-ports"})
+	assert.NoError(t, err)
+	assert.Equal(t, "", command)
+	assert.True(t, *testAllPorts)
+
+	_, err = parseFlags(app, []string{"--unknown-flag"})
+	assert.Error(t, err)
+}
+
+func TestWithoutUserFlags(t *testing.T) {
+	configArgs := []string{"--discover-all-ports", "--no-discover-bond-slaves", "--list-label-format=both", "--discover-ports-regexp=eth0"}
+	userFlags := map[string]string{"discover-bond-slaves": "", "discover-ports-regexp": "eth4"}
+	assert.Equal(t, []string{"--discover-all-ports", "--list-label-format=both"}, withoutUserFlags(configArgs, userFlags))
+}
+
+func TestExporterReloadHandler(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	handler := loggingAndFilterMiddleware(http.HandlerFunc(reloadHandler))
+
+	// Reload is only triggered by POST
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", reloadPath, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", reloadPath, nil))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// Metrics are still GET-only
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/metrics", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
+
+func TestFlagEnvarName(t *testing.T) {
+	assert.Equal(t, "GO_ETHTOOL_EXPORTER_PATH_SYSFS_NET_CLASS", flagEnvarName("path.sysfs.net.class"))
+	assert.Equal(t, "GO_ETHTOOL_EXPORTER_DISCOVER_ALL_PORTS", flagEnvarName("discover-all-ports"))
+	assert.Equal(t, "GO_ETHTOOL_EXPORTER_WEB_LISTEN_ADDRESS", flagEnvarName("web.listen-address"))
+
+	assert.Equal(t, "GO_ETHTOOL_EXPORTER_HTTP_SERVER_WEB_LISTEN_ADDRESS", commandFlagEnvarName("http-server", "web.listen-address"))
+	assert.Equal(t, "GO_ETHTOOL_EXPORTER_DISCOVER_PORTS_OUTPUT", commandFlagEnvarName("discover-ports", "output"))
+	assert.Equal(t, "GO_ETHTOOL_EXPORTER_PUSH_URL", commandFlagEnvarName("push", "push-url"))
+	assert.Equal(t, "GO_ETHTOOL_EXPORTER_LOOP_TEXTFILE_UPDATE_INTERVAL", commandFlagEnvarName("loop-textfile", "loop-textfile-update-interval"))
+}
+
+func TestExporterFlagEnvars(t *testing.T) {
+	// The same envar must not set different flags
+	envarFlags := map[string]string{}
+	for _, flagModel := range kingpin.CommandLine.Model().Flags {
+		if flagModel.Hidden || flagModel.Name == "help" || flagModel.Name == "version" {
+			assert.Empty(t, flagModel.Envar, flagModel.Name)
+			continue
+		}
+		assert.Equal(t, flagEnvarName(flagModel.Name), flagModel.Envar)
+		envarFlags[flagModel.Envar] = flagModel.Name
+	}
+	for _, commandModel := range kingpin.CommandLine.Model().Commands {
+		for _, flagModel := range commandModel.Flags {
+			assert.Equal(t, commandFlagEnvarName(commandModel.Name, flagModel.Name), flagModel.Envar)
+			assert.NotContains(t, envarFlags, flagModel.Envar, commandModel.Name+" --"+flagModel.Name)
+			envarFlags[flagModel.Envar] = flagModel.Name
+		}
+	}
+}
+
+func TestParseFlagsWithEnvars(t *testing.T) {
+	t.Cleanup(func() { appliedConfigArgs = nil })
+	app := kingpin.New("test", "")
+	app.Flag(configFileFlagName, "").String()
+	testAllPorts := app.Flag("discover-all-ports", "").Default("false").Bool()
+	testListLabelFormat := app.Flag("list-label-format", "").Default("multi-label").Enum("single-label", "multi-label", "both")
+	testCommand := app.Command("run", "")
+	testInterval := testCommand.Flag("loop-textfile-update-interval", "").Default("30s").Duration()
+	setFlagEnvars(app)
+
+	configPath := path.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(configPath, []byte("discovery:\n  all_ports: true\noutput:\n  list_label_format: both\n"), 0644))
+	t.Setenv("GO_ETHTOOL_EXPORTER_CONFIG_FILE", configPath)
+	t.Setenv("GO_ETHTOOL_EXPORTER_LIST_LABEL_FORMAT", "single-label")
+	t.Setenv("GO_ETHTOOL_EXPORTER_RUN_LOOP_TEXTFILE_UPDATE_INTERVAL", "10s")
+
+	// Environment overrides config file, command line overrides both
+	_, err := parseFlags(app, []string{"run"})
+	assert.NoError(t, err)
+	assert.True(t, *testAllPorts)
+	assert.Equal(t, "single-label", *testListLabelFormat)
+	assert.Equal(t, 10*time.Second, *testInterval)
+
+	_, err = parseFlags(app, []string{"--list-label-format=multi-label", "run"})
+	assert.NoError(t, err)
+	assert.Equal(t, "multi-label", *testListLabelFormat)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/newrushbolt/go-ethtool-exporter/interfaces"
+	"github.com/newrushbolt/go-ethtool-exporter/netns"
+)
+
+// Netns is empty for ports in the exporter's own namespace
+type explainedPort struct {
+	Netns string `json:"netns,omitempty"`
+	interfaces.PortDecision
+	interfaces.PortDescription
+}
+
+func explainPorts(netClassPath string, namespaceName string, decisions []interfaces.PortDecision) []explainedPort {
+	explainedPorts := []explainedPort{}
+	for _, decision := range decisions {
+		explainedPorts = append(explainedPorts, explainedPort{
+			Netns:           namespaceName,
+			PortDecision:    decision,
+			PortDescription: interfaces.DescribePort(netClassPath, decision.Name),
+		})
+	}
+	return explainedPorts
+}
+
+func runDiscoverPortsCommand() {
+	// Discover ports mode
+	allowedTypes := parseAllowedInterfaceTypes(*discoverAllowedPortTypes)
+	discoverConfig := createDiscoveryConfig()
+	decisions, err := interfaces.ExplainInterfaces(getNetClassPath(), discoverConfig, allowedTypes)
+	if err != nil {
+		panic(err.Error())
+	}
+	explainedPorts := explainPorts(getNetClassPath(), "", decisions)
+	if *collectNamedNetns || *collectProcessNetns {
+		// Sysfs of the namespace is only mounted inside the function, so ports are described right there
+		forEachNetns(func(namespace netns.Namespace, paths netns.Paths, decisions []interfaces.PortDecision) {
+			explainedPorts = append(explainedPorts, explainPorts(paths.NetClassPath, namespace.Name, decisions)...)
+		})
+	}
+
+	switch {
+	case *discoverPortsOutput == "json":
+		err = printPortsJson(os.Stdout, explainedPorts)
+	case *discoverPortsExplain:
+		err = printPortsTable(os.Stdout, explainedPorts)
+	default:
+		printPortsByNetns(os.Stdout, explainedPorts)
+	}
+	if err != nil {
+		panic(err.Error())
+	}
+}
+
+func printPortsJson(writer io.Writer, explainedPorts []explainedPort) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(explainedPorts)
+}
+
+// Failed filters are shown with their reasons, eg `+regexp -type(type is not one of [1])`
+func formatDecisionChain(chain []interfaces.FilterResult) string {
+	chainSteps := []string{}
+	for _, result := range chain {
+		if result.Passed {
+			chainSteps = append(chainSteps, "+"+result.Filter)
+		} else {
+			chainSteps = append(chainSteps, fmt.Sprintf("-%s(%s)", result.Filter, result.Reason))
+		}
+	}
+	return strings.Join(chainSteps, " ")
+}
+
+// NETNS column is only shown if there are ports from other namespaces
+func printPortsTable(writer io.Writer, explainedPorts []explainedPort) error {
+	showNetns := slices.ContainsFunc(explainedPorts, func(port explainedPort) bool { return port.Netns != "" })
+	tableWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	header := []string{"PORT", "TYPE", "MASTER", "DRIVER", "OPERSTATE", "DECISION", "CHAIN"}
+	if showNetns {
+		header = append([]string{"NETNS"}, header...)
+	}
+	fmt.Fprintln(tableWriter, strings.Join(header, "\t"))
+	for _, port := range explainedPorts {
+		columns := []string{port.Name, "", "", port.Driver, port.OperState, "rejected", formatDecisionChain(port.Chain)}
+		if len(port.ExpandedTo) > 0 {
+			columns[6] += " => " + strings.Join(port.ExpandedTo, ",")
+		}
+		if port.Type >= 0 {
+			columns[1] = fmt.Sprint(port.Type)
+		}
+		if port.Master != "" {
+			columns[2] = fmt.Sprintf("%s(%s)", port.Master, port.MasterKind)
+		}
+		if port.Accepted {
+			columns[5] = "accepted"
+		}
+		if showNetns {
+			columns = append([]string{port.Netns}, columns...)
+		}
+		for columnIndex, column := range columns {
+			if column == "" {
+				columns[columnIndex] = "-"
+			}
+		}
+		fmt.Fprintln(tableWriter, strings.Join(columns, "\t"))
+	}
+	return tableWriter.Flush()
+}
+
+func printPortsByNetns(writer io.Writer, explainedPorts []explainedPort) {
+	namespaceNames := []string{}
+	namespaceDecisions := map[string][]interfaces.PortDecision{"": {}}
+	for _, port := range explainedPorts {
+		if _, found := namespaceDecisions[port.Netns]; !found && port.Netns != "" {
+			namespaceNames = append(namespaceNames, port.Netns)
+		}
+		namespaceDecisions[port.Netns] = append(namespaceDecisions[port.Netns], port.PortDecision)
+	}
+	printPortDecisions(writer, namespaceDecisions[""])
+	for _, namespaceName := range namespaceNames {
+		fmt.Fprintf(writer, "\nIn network namespace %s:\n", namespaceName)
+		printPortDecisions(writer, namespaceDecisions[namespaceName])
+	}
+}
+
+// Shows the filter that accepted or rejected every port
+func printPortDecisions(writer io.Writer, decisions []interfaces.PortDecision) {
+	acceptedLines := []string{}
+	rejectedLines := []string{}
+	for _, decision := range decisions {
+		if decision.Accepted {
+			acceptedLine := fmt.Sprintf("  - %s (accepted by %s)", decision.Name, strings.Join(decision.PassedFilters(), ", "))
+			if len(decision.ExpandedTo) > 0 {
+				acceptedLine = fmt.Sprintf("  - %s (accepted by %s, expanded to %s)", decision.Name, strings.Join(decision.PassedFilters(), ", "), strings.Join(decision.ExpandedTo, ", "))
+			}
+			acceptedLines = append(acceptedLines, acceptedLine)
+		} else {
+			rejectedBy := decision.RejectedBy()
+			rejectedLines = append(rejectedLines, fmt.Sprintf("  - %s (rejected by %s: %s)", decision.Name, rejectedBy.Filter, rejectedBy.Reason))
+		}
+	}
+	if len(acceptedLines) == 0 {
+		fmt.Fprintln(writer, "No ports discovered, re-run with `--explain` to check the discovery logic")
+	} else {
+		fmt.Fprintln(writer, "Discovered following ports:")
+		fmt.Fprintln(writer, strings.Join(acceptedLines, "\n"))
+	}
+	if len(rejectedLines) > 0 {
+		fmt.Fprintln(writer, "Rejected following ports:")
+		fmt.Fprintln(writer, strings.Join(rejectedLines, "\n"))
+	}
+}
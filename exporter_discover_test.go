@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/newrushbolt/go-ethtool-exporter/interfaces"
+	"github.com/stretchr/testify/assert"
+)
+
+func explainTestPorts(t *testing.T) []explainedPort {
+	setupHttpHandlerFlags(t)
+	discoverPortsRegexp = ptr(regexp.MustCompile("eth[0-2]"))
+	discoverAllPorts = ptr(false)
+	discoverBondSlaves = ptr(true)
+	decisions, err := interfaces.ExplainInterfaces(getNetClassPath(), createDiscoveryConfig(), parseAllowedInterfaceTypes(*discoverAllowedPortTypes))
+	assert.NoError(t, err)
+	return explainPorts(getNetClassPath(), "", decisions)
+}
+
+func TestPrintPortDecisions(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	discoverPortsRegexp = ptr(regexp.MustCompile("eth[0-4]"))
+	discoverPortsExclude = ptr(regexp.MustCompile("eth1"))
+	discoverAllPorts = ptr(false)
+	discoverBondSlaves = ptr(true)
+	decisions, err := interfaces.ExplainInterfaces(getNetClassPath(), createDiscoveryConfig(), parseAllowedInterfaceTypes(*discoverAllowedPortTypes))
+	assert.NoError(t, err)
+
+	var output strings.Builder
+	printPortDecisions(&output, decisions)
+	assert.Contains(t, output.String(), "Discovered following ports:\n  - eth0 (accepted by bond-slaves)\n")
+	assert.Contains(t, output.String(), `  - eth1 (rejected by exclude-regexp: name matches "eth1")`)
+	assert.Contains(t, output.String(), "  - eth2 (rejected by type: type is not one of [1])")
+	assert.Contains(t, output.String(), "  - eth4 (rejected by discovery: none of discovery filters passed: bond-slaves)")
+
+	output.Reset()
+	printPortDecisions(&output, []interfaces.PortDecision{})
+	assert.Equal(t, "No ports discovered, re-run with `--explain` to check the discovery logic\n", output.String())
+}
+
+func TestPrintPortsTable(t *testing.T) {
+	explainedPorts := explainTestPorts(t)
+
+	var output strings.Builder
+	assert.NoError(t, printPortsTable(&output, explainedPorts))
+	lines := strings.Split(output.String(), "\n")
+	assert.Regexp(t, `^PORT +TYPE +MASTER +DRIVER +OPERSTATE +DECISION +CHAIN$`, lines[0])
+	assert.Contains(t, lines, "eth0              1     bond0(bond)      -       up          accepted  +reserved +not-a-device +regexp +type +bond-slaves")
+	assert.Contains(t, output.String(), "-type(type is not one of [1])")
+
+	explainedPorts[0].Netns = "dpdk"
+	output.Reset()
+	assert.NoError(t, printPortsTable(&output, explainedPorts))
+	assert.True(t, strings.HasPrefix(output.String(), "NETNS"))
+}
+
+func TestPrintPortsJson(t *testing.T) {
+	explainedPorts := explainTestPorts(t)
+
+	var output strings.Builder
+	assert.NoError(t, printPortsJson(&output, explainedPorts))
+	var parsedPorts []map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(output.String()), &parsedPorts))
+	assert.Len(t, parsedPorts, len(explainedPorts))
+	for _, port := range parsedPorts {
+		if port["name"] != "eth0" {
+			continue
+		}
+		assert.Equal(t, true, port["accepted"])
+		assert.Equal(t, "bond0", port["master"])
+		assert.Equal(t, "bond", port["master_kind"])
+		assert.Equal(t, "up", port["operstate"])
+		assert.Equal(t, float64(1), port["type"])
+		assert.NotContains(t, port, "netns")
+	}
+}
+
+func TestPrintPortsByNetns(t *testing.T) {
+	explainedPorts := explainTestPorts(t)
+	explainedPorts = append(explainedPorts, explainedPort{
+		Netns:        "dpdk",
+		PortDecision: interfaces.PortDecision{Name: "eth0", Accepted: true, Chain: []interfaces.FilterResult{{Filter: "all-ports", Passed: true}}},
+	})
+
+	var output strings.Builder
+	printPortsByNetns(&output, explainedPorts)
+	assert.Contains(t, output.String(), "Discovered following ports:\n  - eth0 (accepted by bond-slaves)\n")
+	assert.Contains(t, output.String(), "\nIn network namespace dpdk:\nDiscovered following ports:\n  - eth0 (accepted by all-ports)\n")
+}
+
+func TestPrintPortDecisionsExpanded(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	discoverPortsRegexp = ptr(regexp.MustCompile(`^bond0\.100$`))
+	discoverExpandLower = ptr(true)
+	decisions, err := interfaces.ExplainInterfaces(getNetClassPath(), createDiscoveryConfig(), parseAllowedInterfaceTypes(*discoverAllowedPortTypes))
+	assert.NoError(t, err)
+
+	var output strings.Builder
+	printPortDecisions(&output, decisions)
+	assert.Contains(t, output.String(), "  - bond0.100 (accepted by all-ports, expanded to eth0)\n")
+	assert.NoError(t, printPortsTable(&output, explainPorts(getNetClassPath(), "", decisions)))
+	assert.Contains(t, output.String(), "+all-ports +lower-devices => eth0\n")
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/newrushbolt/go-ethtool-exporter/collector"
+	"github.com/newrushbolt/go-ethtool-exporter/interfaces"
+	"github.com/newrushbolt/go-ethtool-exporter/netns"
+)
+
+// Same as collectInterfacesMetrics, but keeps parsed structures instead of metrics
+func collectInterfacesData(discoveredInterfaces []string, netClassPath string, collectorConfig collector.CollectorConfig) map[string]map[string]any {
+	allData := map[string]map[string]any{}
+	collector.RunInEthtoolNetns(collectorConfig, func(collectorConfig collector.CollectorConfig) {
+		for _, interfaceName := range discoveredInterfaces {
+			interfaceConfig := selectCollectorConfig(interfaceName, netClassPath, collectorConfig, interfaceOverrides)
+			interfaceData := collector.CollectInterfaceData(interfaceName, interfaceConfig)
+			if *collectDeviceInfo {
+				interfaceData["device_info"] = interfaces.GetDeviceInfo(netClassPath, interfaceName)
+			}
+			allData[interfaceName] = interfaceData
+		}
+	})
+	return allData
+}
+
+// Keyed like metric registries: by device, and by `<netns>/<device>` for other namespaces
+func collectData() map[string]map[string]any {
+	configLock.RLock()
+	defer configLock.RUnlock()
+	// Error is logged, ports of other network namespaces are still dumped
+	discoveredInterfaces, _ := discoverInterfaces()
+	collectorConfig := createCollectorConfig()
+
+	allData := collectInterfacesData(discoveredInterfaces, getNetClassPath(), collectorConfig)
+	if (*collectNamedNetns || *collectProcessNetns) && *replayDirectory == "" {
+		forEachNetns(func(namespace netns.Namespace, paths netns.Paths, decisions []interfaces.PortDecision) {
+			namespaceConfig := createNamespaceCollectorConfig(collectorConfig, paths)
+			for interfaceName, interfaceData := range collectInterfacesData(interfaces.AcceptedPorts(decisions), paths.NetClassPath, namespaceConfig) {
+				allData[namespace.Name+"/"+interfaceName] = interfaceData
+			}
+		})
+	}
+	return allData
+}
+
+func writeDump(writer io.Writer, format string) error {
+	switch format {
+	case "prometheus":
+		metricRegistries := collectMetrics().all()
+		_, err := fmt.Fprintln(writer, metricRegistries.GetAllMetricsText())
+		return err
+	case "openmetrics":
+		metricRegistries := collectMetrics().all()
+		_, err := io.WriteString(writer, metricRegistries.GetAllMetricsOpenMetrics())
+		return err
+	case "influx", "graphite":
+		metricRegistries := collectMetrics().all()
+		lines, err := renderLineMetrics(metricRegistries, format, nil, time.Now())
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(writer, lines)
+		return err
+	case "json":
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(collectData())
+	default:
+		return fmt.Errorf("unknown dump format %s", format)
+	}
+}
+
+func runDumpCommand() {
+	err := writeDump(os.Stdout, *dumpFormat)
+	if err != nil {
+		slog.Error("Cannot write metrics", "format", *dumpFormat, "error", err)
+		os.Exit(1)
+	}
+}
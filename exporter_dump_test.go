@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporterDumpPrometheus(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	var output bytes.Buffer
+	require.NoError(t, writeDump(&output, "prometheus"))
+	assert.Contains(t, output.String(), `generic_info_settings_speed_bits{device="eth4"} 1e+10`)
+	assert.True(t, strings.HasSuffix(output.String(), "\n"))
+}
+
+func TestExporterDumpOpenMetrics(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	var output bytes.Buffer
+	require.NoError(t, writeDump(&output, "openmetrics"))
+	assert.Contains(t, output.String(), "# TYPE generic_info_settings_speed_bits gauge\ngeneric_info_settings_speed_bits{device=\"eth4\"} 1e+10\n")
+	assert.Contains(t, output.String(), "# TYPE generic_info_settings info\n")
+	assert.True(t, strings.HasSuffix(output.String(), "# EOF\n"))
+}
+
+func TestExporterDumpJson(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	collectDeviceInfo = ptr(true)
+	var output bytes.Buffer
+	require.NoError(t, writeDump(&output, "json"))
+
+	var data map[string]map[string]map[string]any
+	require.NoError(t, json.Unmarshal(output.Bytes(), &data))
+	require.Contains(t, data, "eth4")
+	assert.ElementsMatch(t, []string{"generic_info", "device_info"}, slices.Collect(maps.Keys(data["eth4"])))
+	settings := data["eth4"]["generic_info"]["Settings"].(map[string]any)
+	assert.Equal(t, "10000Mb/s", settings["Speed"])
+	assert.Equal(t, 1e10, settings["SpeedBits"])
+}
+
+func TestExporterDumpUnknownFormat(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	assert.Error(t, writeDump(&bytes.Buffer{}, "xml"))
+}
+
+func TestExporterDumpInflux(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	var output bytes.Buffer
+	require.NoError(t, writeDump(&output, "influx"))
+	assert.Regexp(t, `(?m)^ethtool,collector=generic_info,device=eth4 settings_speed_bits=1e\+10 \d+$`, output.String())
+}
+
+func TestExporterDumpGraphite(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	graphitePathTemplate = ptr("ethtool.{device}.{name}")
+	var output bytes.Buffer
+	require.NoError(t, writeDump(&output, "graphite"))
+	assert.Regexp(t, `(?m)^ethtool\.eth4\.generic_info_settings_speed_bits 1e\+10 \d+$`, output.String())
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/newrushbolt/go-ethtool-exporter/collector"
+	"github.com/newrushbolt/go-ethtool-exporter/registry"
+)
+
+const (
+	influxMeasurement           = "ethtool"
+	graphiteHostnamePlaceholder = "{hostname}"
+	// Metrics without collector prefix, eg `device_info` and link events
+	textfileOtherCollectorName = "exporter"
+)
+
+// Collectors, whose names prefix their metric names.
+// They become `collector` tag in Influx line protocol, and separate textfiles with `textfile-per-collector`
+func metricCollectorNames() []string {
+	collectorNames := []string{"bond_info"}
+	for _, mode := range collector.EthtoolModes {
+		collectorNames = append(collectorNames, mode.Collector)
+	}
+	return collectorNames
+}
+
+// Renders metrics in 'influx' or 'graphite' format, extra labels are added to every sample
+func renderLineMetrics(metricRegistries registry.RegistryCollection, format string, extraLabels map[string]string, timestamp time.Time) (string, error) {
+	switch format {
+	case "influx":
+		return metricRegistries.GetAllMetricsInflux(influxMeasurement, metricCollectorNames(), extraLabels, timestamp), nil
+	case "graphite":
+		labels := maps.Clone(extraLabels)
+		if strings.Contains(*graphitePathTemplate, graphiteHostnamePlaceholder) {
+			hostname, err := os.Hostname()
+			if err != nil {
+				return "", err
+			}
+			if labels == nil {
+				labels = map[string]string{}
+			}
+			labels["hostname"] = hostname
+		}
+		return metricRegistries.GetAllMetricsGraphite(*graphitePathTemplate, labels, timestamp), nil
+	default:
+		return "", fmt.Errorf("unknown line format %s", format)
+	}
+}
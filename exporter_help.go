@@ -5,85 +5,242 @@ package main
 const helpText = `USAGE:
 Commands:
 
+check:
+  Checks transceivers and link speed ONCE, like Nagios/Icinga plugin. Exit code is 0 for OK, 1 for WARNING, 2 for CRITICAL and 3 for UNKNOWN
+    --check-min-speed=0 ($GO_ETHTOOL_EXPORTER_CHECK_MIN_SPEED)
+        Link speed in Mb/s, lower speed of a port is WARNING. Ports without known speed are not checked
+
 discover-ports:
   Show discovered ports and exit
+    --explain ($GO_ETHTOOL_EXPORTER_DISCOVER_PORTS_EXPLAIN)
+        Show a table of all the netclass entries with their type, master, driver, operstate and discovery filters chain
+
+    --output=text ($GO_ETHTOOL_EXPORTER_DISCOVER_PORTS_OUTPUT)
+        Output format, 'json' always includes all the details shown by --explain. Possible values are: text, json
+
+dump:
+  Writes all metrics to stdout ONCE. Usefull for troubleshooting, Ansible facts and CI smoke tests
+    --dump-format=prometheus ($GO_ETHTOOL_EXPORTER_DUMP_FORMAT)
+        Output format, 'json' keeps parsed structures of every collector, keyed by device and collector name. 'influx' and 'graphite' are the same as in 'textfile-format'. Possible values are: prometheus, openmetrics, json, influx, graphite
+
 http-server:
   Starts HTTP server of scraping metrics over HTTP(S), like node-exporter does
-    --web.listen-address=:9417
+    --web.listen-address=:9417 ($GO_ETHTOOL_EXPORTER_HTTP_SERVER_WEB_LISTEN_ADDRESS)
         Address on which to expose metrics
 
-    --web.max-requests=3
+    --web.max-requests=3 ($GO_ETHTOOL_EXPORTER_HTTP_SERVER_WEB_MAX_REQUESTS)
         Maximum number of concurrent HTTP requests
 
 loop-textfile:
   Writes all metrics to textfile every loop-interval
-    --loop-textfile-update-interval=30s
+    --loop-textfile-update-interval=30s ($GO_ETHTOOL_EXPORTER_LOOP_TEXTFILE_UPDATE_INTERVAL)
         Interval between textfile updates
 
+otlp:
+  Exports all metrics to OpenTelemetry collector over OTLP, every otlp-interval or ONCE
+    --otlp-endpoint=http://localhost:4318 ($GO_ETHTOOL_EXPORTER_OTLP_ENDPOINT)
+        Collector URL, 'http://' scheme disables TLS. Path '/v1/metrics' is used by default with 'http/protobuf', eg 'http://collector:4318'
+
+    --otlp-protocol=http/protobuf ($GO_ETHTOOL_EXPORTER_OTLP_PROTOCOL)
+        OTLP transport. Possible values are: http/protobuf, grpc
+
+    --otlp-headers= ($GO_ETHTOOL_EXPORTER_OTLP_HEADERS)
+        Comma-separated list of 'key=value' headers (or gRPC metadata) sent with every export, eg for authentication
+
+    --otlp-interval=30s ($GO_ETHTOOL_EXPORTER_OTLP_INTERVAL)
+        Interval between exports, '0s' exports once and exits with non-zero code if export failed
+
+    --otlp-timeout=10s ($GO_ETHTOOL_EXPORTER_OTLP_TIMEOUT)
+        Timeout of a single export, including retries of the OTLP exporter
+
+push:
+  Pushes all metrics to Pushgateway or Prometheus remote-write endpoint, ONCE or every push-interval. Usefull for short-lived hosts, that cannot be scraped
+    --push-url= ($GO_ETHTOOL_EXPORTER_PUSH_URL)
+        Pushgateway base URL, eg 'http://pushgateway:9091', remote-write URL, eg 'http://prometheus:9090/api/v1/write', or 'tcp://' and 'udp://' address for 'influx' and 'graphite', eg 'tcp://graphite:2003'
+
+    --push-protocol=pushgateway ($GO_ETHTOOL_EXPORTER_PUSH_PROTOCOL)
+        Push protocol, 'pushgateway' replaces metrics of the group with PUT, 'remote-write' sends snappy-compressed protobuf, 'influx' and 'graphite' send lines in 'textfile-format' formats. Possible values are: pushgateway, remote-write, influx, graphite
+
+    --push-job=go-ethtool-exporter ($GO_ETHTOOL_EXPORTER_PUSH_JOB)
+        Value of 'job' label, Pushgateway grouping key always starts with it
+
+    --push-grouping-key=instance=<hostname> ($GO_ETHTOOL_EXPORTER_PUSH_GROUPING_KEY)
+        Comma-separated list of extra 'label=value' grouping key pairs. They are added as labels to every sample with other protocols
+
+    --push-interval=0s ($GO_ETHTOOL_EXPORTER_PUSH_INTERVAL)
+        Interval between pushes, '0s' pushes once and exits with non-zero code if push failed
+
+    --push-timeout=10s ($GO_ETHTOOL_EXPORTER_PUSH_TIMEOUT)
+        Timeout of a single push request
+
+    --push-retries=3 ($GO_ETHTOOL_EXPORTER_PUSH_RETRIES)
+        Number of retries after connection errors, 5xx and 429 responses. Other responses are not retried
+
+    --push-retry-backoff=1s ($GO_ETHTOOL_EXPORTER_PUSH_RETRY_BACKOFF)
+        Delay before the first retry, doubled for every next one
+
+report:
+  Writes diagnostic tar.gz archive with raw ethtool output for all discovered ports, sysfs attributes, exporter version, flags and metrics, and exit
+    --report-file= ($GO_ETHTOOL_EXPORTER_REPORT_FILE)
+        Path of the report archive, defaults to 'go-ethtool-exporter-report-<hostname>-<timestamp>.tar.gz' in current directory
+
 single-textfile:
   Writes all metrics to textfile ONCE. Usefull for testing or crons
 
 
 Flags:
 
+Every flag can also be set via environment variable shown in brackets, command line flags take precedence.
+Log level is only set via $GO_ETHTOOL_EXPORTER_LOG_LEVEL (debug, info, warn or error).
+
+Configuration file settings:
+  --config.file= ($GO_ETHTOOL_EXPORTER_CONFIG_FILE)
+    YAML configuration file with discovery, collectors and output settings, see README. Flags given in command line or environment override values from the file. Reloaded on SIGHUP or POST to '/-/reload'
+
 Ethtool settings:
-  --path.ethtool=/usr/sbin/ethtool
-  --ethtool-timeout=5s
+  --path.ethtool=/usr/sbin/ethtool ($GO_ETHTOOL_EXPORTER_PATH_ETHTOOL)
+  --ethtool-timeout=5s ($GO_ETHTOOL_EXPORTER_ETHTOOL_TIMEOUT)
     Timeout for ethtool command execution.
+  --ethtool-host-netns ($GO_ETHTOOL_EXPORTER_ETHTOOL_HOST_NETNS)
+    Run ethtool in the network namespace of PID 1 from 'path.procfs', eg when exporter runs in a container without host network. Requires CAP_SYS_ADMIN
+  --ethtool-netlink ($GO_ETHTOOL_EXPORTER_ETHTOOL_NETLINK)
+    Read link settings ('generic_info_*' metrics) over ethtool netlink instead of running ethtool. Other collectors still run ethtool
+  --replay-dir= ($GO_ETHTOOL_EXPORTER_REPLAY_DIR)
+    Read recorded ethtool output instead of running ethtool, and use '<replay-dir>/sysfs' and '<replay-dir>/procfs' as 'path.sysfs' and 'path.procfs'. Expects extracted 'report' archive, go-ethtool-metrics testdata is not supported as its instances are not named by interfaces and have no sysfs
 
 Various paths settings:
-  --path.sysfs.net.class=/sys/class/net
-  --path.textfile-directory=/var/lib/node-exporter/textfiles
+  --path.sysfs=/sys ($GO_ETHTOOL_EXPORTER_PATH_SYSFS)
+    Sysfs mountpoint, eg '/host/sys' in a container. Root for all the sysfs paths below
+  --path.procfs=/proc ($GO_ETHTOOL_EXPORTER_PATH_PROCFS)
+    Procfs mountpoint, eg '/host/proc' in a container. Root for all the procfs paths below
+  --path.sysfs.net.class= ($GO_ETHTOOL_EXPORTER_PATH_SYSFS_NET_CLASS)
+    Overrides '<path.sysfs>/class/net'
+  --path.procfs.net.bonding= ($GO_ETHTOOL_EXPORTER_PATH_PROCFS_NET_BONDING)
+    Directory with bonding driver status files, used by bond-info collector. Overrides '<path.procfs>/net/bonding'
+  --path.run.netns=/run/netns ($GO_ETHTOOL_EXPORTER_PATH_RUN_NETNS)
+    Directory with named network namespaces, the same as used by 'ip netns'
+  --path.textfile-directory=/var/lib/node-exporter/textfiles ($GO_ETHTOOL_EXPORTER_PATH_TEXTFILE_DIRECTORY)
     Path to the node_exporter textfile directory. Only used in 'single-textfile' and 'loop-textfile' modes
 
+Textfile settings:
+  --textfile-name=ethtool_exporter.prom ($GO_ETHTOOL_EXPORTER_TEXTFILE_NAME)
+    Textfile name inside 'path.textfile-directory'. node_exporter only reads '*.prom' files
+  --textfile-mode=0644 ($GO_ETHTOOL_EXPORTER_TEXTFILE_MODE)
+    Octal permissions of textfiles, eg '0640'. Umask is not applied
+  --textfile-fsync ($GO_ETHTOOL_EXPORTER_TEXTFILE_FSYNC)
+    Sync textfiles and textfile directory to disk after every update
+  --textfile-per-collector ($GO_ETHTOOL_EXPORTER_TEXTFILE_PER_COLLECTOR)
+    Write metrics of every collector to a separate '<name>_<collector>.prom' file, so failed update of one file doesn't affect others. Metrics not related to collectors go to '<name>_exporter.prom'
+
 Collectors, enabled by default:
-  --no-collect-generic-info-settings
-  --no-collect-driver-info-common
-  --no-collect-module-info-diagnostics-alarms
-  --no-collect-module-info-diagnostics-warnings
-  --collect-statistics-general
+  --no-collect-generic-info-settings ($GO_ETHTOOL_EXPORTER_COLLECT_GENERIC_INFO_SETTINGS)
+  --no-collect-driver-info-common ($GO_ETHTOOL_EXPORTER_COLLECT_DRIVER_INFO_COMMON)
+  --no-collect-module-info-diagnostics-alarms ($GO_ETHTOOL_EXPORTER_COLLECT_MODULE_INFO_DIAGNOSTICS_ALARMS)
+  --no-collect-module-info-diagnostics-warnings ($GO_ETHTOOL_EXPORTER_COLLECT_MODULE_INFO_DIAGNOSTICS_WARNINGS)
+  --collect-statistics-general ($GO_ETHTOOL_EXPORTER_COLLECT_STATISTICS_GENERAL)
 
 Collectors, disabled by default:
-  --collect-all-metrics
+  --collect-all-metrics ($GO_ETHTOOL_EXPORTER_COLLECT_ALL_METRICS)
     Ignores all the flags below. Usefull for testing
-  --collect-driver-info-features
-  --collect-generic-info-modes
-  --collect-module-info-diagnostics-values
-  --collect-module-info-vendor
-  --collect-statistics-per-queue-general
-  --collect-statistics-per-queue-per-type
-  --collect-statistics-per-queue-xdp
+  --collect-driver-info-features ($GO_ETHTOOL_EXPORTER_COLLECT_DRIVER_INFO_FEATURES)
+  --collect-generic-info-modes ($GO_ETHTOOL_EXPORTER_COLLECT_GENERIC_INFO_MODES)
+  --collect-module-info-diagnostics-values ($GO_ETHTOOL_EXPORTER_COLLECT_MODULE_INFO_DIAGNOSTICS_VALUES)
+  --collect-module-info-vendor ($GO_ETHTOOL_EXPORTER_COLLECT_MODULE_INFO_VENDOR)
+  --collect-statistics-per-queue-general ($GO_ETHTOOL_EXPORTER_COLLECT_STATISTICS_PER_QUEUE_GENERAL)
+  --collect-statistics-per-queue-per-type ($GO_ETHTOOL_EXPORTER_COLLECT_STATISTICS_PER_QUEUE_PER_TYPE)
+  --collect-statistics-per-queue-xdp ($GO_ETHTOOL_EXPORTER_COLLECT_STATISTICS_PER_QUEUE_XDP)
+  --collect-bond-info ($GO_ETHTOOL_EXPORTER_COLLECT_BOND_INFO)
+    Collect bonding mode, MII status, link failures and LACP state of bond masters and their slaves. Only makes sense with bond masters discovered
+  --collect-device-info ($GO_ETHTOOL_EXPORTER_COLLECT_DEVICE_INFO)
+    Expose 'device_info' metric with PCI address, vendor and device IDs, NUMA node, permanent MAC, phys_port_id, driver and ifalias from sysfs
 
 Port detection settings:
-  --discover-allowed-port-types=1,
+  --discover-allowed-port-types=1, ($GO_ETHTOOL_EXPORTER_DISCOVER_ALLOWED_PORT_TYPES)
     Comma-separated list of allowed interface types (see if_arp.h). Set to empty ('') to allow all port types
-  --discover-all-ports
+  --discover-all-ports ($GO_ETHTOOL_EXPORTER_DISCOVER_ALL_PORTS)
     Force discover all the ports, ignoring all the other discover flags, EXCEPT for 'discover-allowed-port-types' and 'discover-ports-regexp'
-  --no-discover-bond-slaves
+  --no-discover-bond-slaves ($GO_ETHTOOL_EXPORTER_DISCOVER_BOND_SLAVES)
     Whether to discover ports that are enslaved by bonds
-  --discover-bridge-slaves
+  --discover-bond-masters ($GO_ETHTOOL_EXPORTER_DISCOVER_BOND_MASTERS)
+    Whether to discover bond master ports
+  --discover-bridge-slaves ($GO_ETHTOOL_EXPORTER_DISCOVER_BRIDGE_SLAVES)
     Whether to discover ports that are enslaved by bridges
-  --discover-ports-regexp=.+
+  --discover-team-slaves ($GO_ETHTOOL_EXPORTER_DISCOVER_TEAM_SLAVES)
+    Whether to discover ports that are enslaved by libteam devices
+  --discover-expand-lower-devices ($GO_ETHTOOL_EXPORTER_DISCOVER_EXPAND_LOWER_DEVICES)
+    Replace discovered VLAN, team, macvlan and other stacked ports with their lowest devices (via 'lower_*' links), eg VLAN over bond expands to bond slaves. Bond masters discovered via 'discover-bond-masters' are kept
+  --discover-ovs-slaves ($GO_ETHTOOL_EXPORTER_DISCOVER_OVS_SLAVES)
+    Whether to discover ports that are attached to Open vSwitch datapath (their master is 'ovs-system')
+  --no-discover-ovs-skip-virtual-ports ($GO_ETHTOOL_EXPORTER_DISCOVER_OVS_SKIP_VIRTUAL_PORTS)
+    Skip OVS ports that are not backed by a hardware device, such as internal, tap and tunnel ports
+  --discover-physical-ports ($GO_ETHTOOL_EXPORTER_DISCOVER_PHYSICAL_PORTS)
+    Whether to discover ports backed by a PCI or platform device, regardless of bond or bridge membership. Virtual devices (veth, tun, dummy, etc) are never physical
+  --discover-virtual-functions ($GO_ETHTOOL_EXPORTER_DISCOVER_VIRTUAL_FUNCTIONS)
+    Whether to discover SR-IOV virtual functions (ports with 'device/physfn' link)
+  --discover-representors ($GO_ETHTOOL_EXPORTER_DISCOVER_REPRESENTORS)
+    Whether to discover switchdev representors of VFs, SFs and PFs (ports with 'phys_port_name' like 'pf0vf1')
+  --discover-skip-virtual-functions ($GO_ETHTOOL_EXPORTER_DISCOVER_SKIP_VIRTUAL_FUNCTIONS)
+    Never discover SR-IOV virtual functions, even if they pass other discover flags
+  --discover-skip-representors ($GO_ETHTOOL_EXPORTER_DISCOVER_SKIP_REPRESENTORS)
+    Never discover switchdev representors, even if they pass other discover flags
+  --discover-ports-regexp=.+ ($GO_ETHTOOL_EXPORTER_DISCOVER_PORTS_REGEXP)
     Only discover ports with names matching this regexp
+  --discover-ports-exclude-regexp= ($GO_ETHTOOL_EXPORTER_DISCOVER_PORTS_EXCLUDE_REGEXP)
+    Never discover ports with names matching this regexp
+  --discover-allowed-operstates= ($GO_ETHTOOL_EXPORTER_DISCOVER_ALLOWED_OPERSTATES)
+    Comma-separated list of allowed operstates (up, down, dormant, notpresent, lowerlayerdown, testing, unknown). Empty allows all operstates
+  --discover-skip-operstates= ($GO_ETHTOOL_EXPORTER_DISCOVER_SKIP_OPERSTATES)
+    Comma-separated list of operstates to never discover, eg 'notpresent,lowerlayerdown'
+  --discover-require-carrier ($GO_ETHTOOL_EXPORTER_DISCOVER_REQUIRE_CARRIER)
+    Only discover ports with carrier, eg with cable connected and link up
+  --discover-drivers-regexp= ($GO_ETHTOOL_EXPORTER_DISCOVER_DRIVERS_REGEXP)
+    Only discover ports with driver names (from 'device/driver' link) matching this regexp. Virtual ports have empty driver name
+  --discovery-cache ($GO_ETHTOOL_EXPORTER_DISCOVERY_CACHE)
+    Cache discovered ports until netlink (or inotify) reports link changes. Only used in 'http-server', 'loop-textfile', and looped 'push' and 'otlp' modes
 
 Absent metrics exposure. This controls how to expose missing metrics: via Nan values of the same metrics, via counter metrics, counting how many metrics are missing per collector, or via special per-metric metrics, exposing full missing label name via label:
-  --absent-metrics-driver-info-expose-nan
-  --absent-metrics-driver-info-expose-total-counter
-  --absent-metrics-driver-info-expose-detailed-info
-  --absent-metrics-generic-info-expose-nan
-  --absent-metrics-generic-info-expose-total-counter
-  --absent-metrics-generic-info-expose-detailed-info
-  --no-absent-metrics-module-info-expose-nan
-  --absent-metrics-module-info-expose-total-counter
-  --absent-metrics-module-info-expose-detailed-info
-  --absent-metrics-statistics-expose-nan
-  --absent-metrics-statistics-expose-total-counter
-  --absent-metrics-statistics-expose-detailed-info
+  --absent-metrics-driver-info-expose-nan ($GO_ETHTOOL_EXPORTER_ABSENT_METRICS_DRIVER_INFO_EXPOSE_NAN)
+  --absent-metrics-driver-info-expose-total-counter ($GO_ETHTOOL_EXPORTER_ABSENT_METRICS_DRIVER_INFO_EXPOSE_TOTAL_COUNTER)
+  --absent-metrics-driver-info-expose-detailed-info ($GO_ETHTOOL_EXPORTER_ABSENT_METRICS_DRIVER_INFO_EXPOSE_DETAILED_INFO)
+  --absent-metrics-generic-info-expose-nan ($GO_ETHTOOL_EXPORTER_ABSENT_METRICS_GENERIC_INFO_EXPOSE_NAN)
+  --absent-metrics-generic-info-expose-total-counter ($GO_ETHTOOL_EXPORTER_ABSENT_METRICS_GENERIC_INFO_EXPOSE_TOTAL_COUNTER)
+  --absent-metrics-generic-info-expose-detailed-info ($GO_ETHTOOL_EXPORTER_ABSENT_METRICS_GENERIC_INFO_EXPOSE_DETAILED_INFO)
+  --no-absent-metrics-module-info-expose-nan ($GO_ETHTOOL_EXPORTER_ABSENT_METRICS_MODULE_INFO_EXPOSE_NAN)
+  --absent-metrics-module-info-expose-total-counter ($GO_ETHTOOL_EXPORTER_ABSENT_METRICS_MODULE_INFO_EXPOSE_TOTAL_COUNTER)
+  --absent-metrics-module-info-expose-detailed-info ($GO_ETHTOOL_EXPORTER_ABSENT_METRICS_MODULE_INFO_EXPOSE_DETAILED_INFO)
+  --absent-metrics-statistics-expose-nan ($GO_ETHTOOL_EXPORTER_ABSENT_METRICS_STATISTICS_EXPOSE_NAN)
+  --absent-metrics-statistics-expose-total-counter ($GO_ETHTOOL_EXPORTER_ABSENT_METRICS_STATISTICS_EXPOSE_TOTAL_COUNTER)
+  --absent-metrics-statistics-expose-detailed-info ($GO_ETHTOOL_EXPORTER_ABSENT_METRICS_STATISTICS_EXPOSE_DETAILED_INFO)
+  --absent-metrics-bond-info-expose-nan ($GO_ETHTOOL_EXPORTER_ABSENT_METRICS_BOND_INFO_EXPOSE_NAN)
+  --absent-metrics-bond-info-expose-total-counter ($GO_ETHTOOL_EXPORTER_ABSENT_METRICS_BOND_INFO_EXPOSE_TOTAL_COUNTER)
+  --absent-metrics-bond-info-expose-detailed-info ($GO_ETHTOOL_EXPORTER_ABSENT_METRICS_BOND_INFO_EXPOSE_DETAILED_INFO)
+
+Link events settings:
+  --watch-link-events ($GO_ETHTOOL_EXPORTER_WATCH_LINK_EVENTS)
+    Subscribe to netlink link notifications and expose link up/down, speed and module change counters. Only used in 'http-server', 'loop-textfile', and looped 'push' and 'otlp' modes
+  --no-watch-link-events-ethtool-monitor ($GO_ETHTOOL_EXPORTER_WATCH_LINK_EVENTS_ETHTOOL_MONITOR)
+    Also subscribe to ethtool netlink monitor notifications for speed and module changes
+
+Network namespaces settings:
+  --collect-named-netns ($GO_ETHTOOL_EXPORTER_COLLECT_NAMED_NETNS)
+    Also discover ports and collect metrics inside named network namespaces (see 'path.run.netns'). Metrics are labeled with 'netns'. Requires CAP_SYS_ADMIN
+  --collect-process-netns ($GO_ETHTOOL_EXPORTER_COLLECT_PROCESS_NETNS)
+    Also discover ports and collect metrics inside network namespaces of all the processes, eg containers. Such namespaces are labeled as 'pid/<lowest pid>'
+
+Output format settings:
+  --textfile-format=prometheus ($GO_ETHTOOL_EXPORTER_TEXTFILE_FORMAT)
+    Textfile format, 'influx' is Influx line protocol with 'device' and 'collector' tags, 'graphite' is Graphite plaintext protocol with 'graphite-path-template' paths. Only 'prometheus' is supported by node_exporter. Possible values are: prometheus, influx, graphite
+  --graphite-path-template=ethtool.{hostname}.{device}.{name} ($GO_ETHTOOL_EXPORTER_GRAPHITE_PATH_TEMPLATE)
+    Graphite path, '{name}' is metric name, '{hostname}' is hostname, other placeholders are label values. Labels not used in the template become Graphite tags
 
 Metrics processing settings:
-  --no-statistics-generate-missing-per-queue-metrics
+  --no-statistics-generate-missing-per-queue-metrics ($GO_ETHTOOL_EXPORTER_STATISTICS_GENERATE_MISSING_PER_QUEUE_METRICS)
     Generate missing metrics per queue if missing (eg in Broadcom bnxt_en driver)
-  --list-label-format=multi-label
+  --list-label-format=multi-label ($GO_ETHTOOL_EXPORTER_LIST_LABEL_FORMAT)
     How to transform lists of strings to prometheus labels. Possible values are: single-label, multi-label, both
+  --label-sriov-topology ($GO_ETHTOOL_EXPORTER_LABEL_SRIOV_TOPOLOGY)
+    Add 'sriov_role' (pf, vf or representor) and 'pf_device' labels to every metric of SR-IOV ports
+  --label-device-info= ($GO_ETHTOOL_EXPORTER_LABEL_DEVICE_INFO)
+    Comma-separated list of 'device_info' labels to add to every metric, eg 'pci_address,permanent_mac'. Beware of 16 labels limit per metric
 
 `
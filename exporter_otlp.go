@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+
+	"github.com/newrushbolt/go-ethtool-exporter/registry"
+)
+
+const otlpScopeName = "github.com/newrushbolt/go-ethtool-exporter"
+
+// Semantic convention attributes, see https://opentelemetry.io/docs/specs/semconv/
+const (
+	otlpServiceNameAttribute    = "service.name"
+	otlpServiceVersionAttribute = "service.version"
+	otlpHostNameAttribute       = "host.name"
+	otlpDeviceAttribute         = "network.interface.name"
+)
+
+// Start time of cumulative sums, NIC counters are not reset by the exporter
+var otlpStartTime = time.Now()
+
+func parseOtlpHeaders(headers string) (map[string]string, error) {
+	parsedHeaders := map[string]string{}
+	for _, pair := range strings.Split(headers, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		headerName, headerValue, found := strings.Cut(pair, "=")
+		if !found || headerName == "" {
+			return nil, fmt.Errorf("header <%s> is not in 'key=value' format", pair)
+		}
+		parsedHeaders[strings.TrimSpace(headerName)] = strings.TrimSpace(headerValue)
+	}
+	return parsedHeaders, nil
+}
+
+func newOtlpExporter(ctx context.Context) (sdkmetric.Exporter, error) {
+	headers, err := parseOtlpHeaders(*otlpHeaders)
+	if err != nil {
+		return nil, err
+	}
+	if *otlpProtocol == "grpc" {
+		return otlpmetricgrpc.New(ctx,
+			otlpmetricgrpc.WithEndpointURL(*otlpEndpoint),
+			otlpmetricgrpc.WithHeaders(headers),
+			otlpmetricgrpc.WithTimeout(*otlpTimeout),
+		)
+	}
+	return otlpmetrichttp.New(ctx,
+		otlpmetrichttp.WithEndpointURL(*otlpEndpoint),
+		otlpmetrichttp.WithHeaders(headers),
+		otlpmetrichttp.WithTimeout(*otlpTimeout),
+	)
+}
+
+// Registries are keyed by device, or `<netns>/<device>` for other network namespaces
+func otlpRegistryDevice(registryName string) string {
+	return registryName[strings.LastIndex(registryName, "/")+1:]
+}
+
+func newOtlpResource(hostname string, device string) *resource.Resource {
+	attributes := []attribute.KeyValue{
+		attribute.String(otlpServiceNameAttribute, "go-ethtool-exporter"),
+		attribute.String(otlpServiceVersionAttribute, getExporterVersion(debug.ReadBuildInfo)),
+		attribute.String(otlpHostNameAttribute, hostname),
+	}
+	if device != "" {
+		attributes = append(attributes, attribute.String(otlpDeviceAttribute, device))
+	}
+	return resource.NewSchemaless(attributes...)
+}
+
+// Device label is already a resource attribute, so it is dropped from data points
+func newOtlpAttributes(metricRecord registry.MetricRecord, device string) attribute.Set {
+	attributes := []attribute.KeyValue{}
+	for _, labelName := range slices.Sorted(maps.Keys(metricRecord.Labels)) {
+		if labelName == "device" && metricRecord.Labels[labelName] == device {
+			continue
+		}
+		attributes = append(attributes, attribute.String(labelName, metricRecord.Labels[labelName]))
+	}
+	return attribute.NewSet(attributes...)
+}
+
+// Counters become monotonic cumulative sums, everything else becomes gauges
+func newOtlpMetric(metricName string, dataPoints []metricdata.DataPoint[float64]) metricdata.Metrics {
+	if registry.GetMetricType(metricName) == registry.MetricTypeCounter {
+		return metricdata.Metrics{
+			Name: metricName,
+			Data: metricdata.Sum[float64]{
+				DataPoints:  dataPoints,
+				Temporality: metricdata.CumulativeTemporality,
+				IsMonotonic: true,
+			},
+		}
+	}
+	return metricdata.Metrics{Name: metricName, Data: metricdata.Gauge[float64]{DataPoints: dataPoints}}
+}
+
+// Every registry becomes a separate resource, with samples grouped into metrics by name.
+// Device is empty for exporter-wide metrics.
+func registryToResourceMetrics(device string, metricRegistry registry.Registry, hostname string, now time.Time) *metricdata.ResourceMetrics {
+	metricDataPoints := map[string][]metricdata.DataPoint[float64]{}
+	for _, metricRecord := range metricRegistry {
+		metricDataPoints[metricRecord.Name] = append(metricDataPoints[metricRecord.Name], metricdata.DataPoint[float64]{
+			Attributes: newOtlpAttributes(metricRecord, device),
+			StartTime:  otlpStartTime,
+			Time:       now,
+			Value:      metricRecord.Value,
+		})
+	}
+	scopeMetrics := metricdata.ScopeMetrics{Scope: instrumentation.Scope{Name: otlpScopeName}}
+	for _, metricName := range slices.Sorted(maps.Keys(metricDataPoints)) {
+		scopeMetrics.Metrics = append(scopeMetrics.Metrics, newOtlpMetric(metricName, metricDataPoints[metricName]))
+	}
+	return &metricdata.ResourceMetrics{
+		Resource:     newOtlpResource(hostname, device),
+		ScopeMetrics: []metricdata.ScopeMetrics{scopeMetrics},
+	}
+}
+
+// Every interface registry becomes a separate resource, exporter-wide metrics go last
+func newOtlpResourceMetrics(metrics collectedMetrics) ([]*metricdata.ResourceMetrics, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	resources := []*metricdata.ResourceMetrics{}
+	for _, registryName := range slices.Sorted(maps.Keys(metrics.Interfaces)) {
+		resources = append(resources, registryToResourceMetrics(otlpRegistryDevice(registryName), metrics.Interfaces[registryName], hostname, now))
+	}
+	if len(metrics.Exporter) > 0 {
+		resources = append(resources, registryToResourceMetrics("", metrics.Exporter, hostname, now))
+	}
+	return resources, nil
+}
+
+// All resources are exported even if some of them failed, first error is returned
+func exportOtlpResourceMetrics(ctx context.Context, exporter sdkmetric.Exporter, resources []*metricdata.ResourceMetrics) error {
+	var firstErr error
+	for _, resourceMetrics := range resources {
+		err := exporter.Export(ctx, resourceMetrics)
+		if err != nil {
+			slog.Debug("Cannot export metrics over OTLP", "resource", resourceMetrics.Resource, "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func exportOtlpMetrics(ctx context.Context, exporter sdkmetric.Exporter, metrics collectedMetrics) error {
+	resources, err := newOtlpResourceMetrics(metrics)
+	if err != nil {
+		return err
+	}
+	return exportOtlpResourceMetrics(ctx, exporter, resources)
+}
+
+func runOtlpCommand() {
+	ctx := context.Background()
+	exporter, err := newOtlpExporter(ctx)
+	if err != nil {
+		slog.Error("Cannot create OTLP exporter", "endpoint", *otlpEndpoint, "protocol", *otlpProtocol, "error", err)
+		os.Exit(1)
+	}
+	defer exporter.Shutdown(ctx)
+
+	if *otlpInterval == 0 {
+		err = exportOtlpMetrics(ctx, exporter, collectMetrics())
+		if err != nil {
+			slog.Error("Cannot export metrics over OTLP", "endpoint", *otlpEndpoint, "protocol", *otlpProtocol, "error", err)
+			exporter.Shutdown(ctx)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Loop export mode, failed exports are skipped until the next interval
+	startLinkWatcher()
+	startDiscoveryCache()
+	startReloadOnSighup()
+	for {
+		time.Sleep(exportOtlpMetricsOnce(ctx, exporter))
+	}
+}
+
+// Config read lock is only held until metrics are collected, so reload cannot change flags in between.
+// Export and its retries run without it, so reload does not wait for slow OTLP endpoints.
+// Returns interval until the next export.
+func exportOtlpMetricsOnce(ctx context.Context, exporter sdkmetric.Exporter) time.Duration {
+	configLock.RLock()
+	resources, err := newOtlpResourceMetrics(collectMetricsLocked())
+	endpoint, protocol, interval := *otlpEndpoint, *otlpProtocol, *otlpInterval
+	configLock.RUnlock()
+
+	if err == nil {
+		err = exportOtlpResourceMetrics(ctx, exporter, resources)
+	}
+	if err != nil {
+		slog.Error("Cannot export metrics over OTLP", "endpoint", endpoint, "protocol", protocol, "error", err)
+	}
+	return interval
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/newrushbolt/go-ethtool-exporter/registry"
+)
+
+// Test stand-in of OpenTelemetry collector, for both OTLP transports
+type otlpTestCollector struct {
+	colmetricpb.UnimplementedMetricsServiceServer
+	lock     sync.Mutex
+	requests []*colmetricpb.ExportMetricsServiceRequest
+	headers  []string
+}
+
+func (collector *otlpTestCollector) Export(ctx context.Context, request *colmetricpb.ExportMetricsServiceRequest) (*colmetricpb.ExportMetricsServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+	collector.requests = append(collector.requests, request)
+	collector.headers = append(collector.headers, md.Get("authorization")...)
+	return &colmetricpb.ExportMetricsServiceResponse{}, nil
+}
+
+func (collector *otlpTestCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	request := &colmetricpb.ExportMetricsServiceRequest{}
+	if r.URL.Path != "/v1/metrics" || proto.Unmarshal(body, request) != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+	collector.requests = append(collector.requests, request)
+	collector.headers = append(collector.headers, r.Header.Get("Authorization"))
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	response, _ := proto.Marshal(&colmetricpb.ExportMetricsServiceResponse{})
+	_, _ = w.Write(response)
+}
+
+// Resource attributes and metrics of every exported resource, keyed by device
+func (collector *otlpTestCollector) resources() map[string][]*metricpb.Metric {
+	resources := map[string][]*metricpb.Metric{}
+	for _, request := range collector.requests {
+		for _, resourceMetrics := range request.ResourceMetrics {
+			device := otlpAttributeValue(resourceMetrics.Resource.Attributes, otlpDeviceAttribute)
+			resources[device] = append(resources[device], resourceMetrics.ScopeMetrics[0].Metrics...)
+		}
+	}
+	return resources
+}
+
+func otlpAttributeValue(attributes []*commonpb.KeyValue, key string) string {
+	for _, keyValue := range attributes {
+		if keyValue.Key == key {
+			return keyValue.Value.GetStringValue()
+		}
+	}
+	return ""
+}
+
+func setupOtlpFlags(t *testing.T, endpoint string, protocol string) {
+	setupHttpHandlerFlags(t)
+	otlpEndpoint = ptr(endpoint)
+	otlpProtocol = ptr(protocol)
+	otlpHeaders = ptr("authorization=Bearer secret")
+	otlpTimeout = ptr(5 * time.Second)
+}
+
+func assertOtlpExport(t *testing.T, collector *otlpTestCollector) {
+	ctx := context.Background()
+	exporter, err := newOtlpExporter(ctx)
+	require.NoError(t, err)
+	defer exporter.Shutdown(ctx)
+	exportedMetrics := collectMetrics()
+	exportedMetrics.Exporter = registry.Registry{{Name: "discovery_refreshes_total", Labels: map[string]string{}, Value: 3}}
+	require.NoError(t, exportOtlpMetrics(ctx, exporter, exportedMetrics))
+
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+	require.NotEmpty(t, collector.requests)
+	assert.Equal(t, "Bearer secret", collector.headers[0])
+	resource := collector.requests[0].ResourceMetrics[0].Resource
+	assert.Equal(t, "eth4", otlpAttributeValue(resource.Attributes, otlpDeviceAttribute))
+	assert.NotEmpty(t, otlpAttributeValue(resource.Attributes, otlpHostNameAttribute))
+
+	metrics := map[string]*metricpb.Metric{}
+	for _, metric := range collector.resources()["eth4"] {
+		metrics[metric.Name] = metric
+	}
+	require.Contains(t, metrics, "generic_info_settings_speed_bits")
+	dataPoint := metrics["generic_info_settings_speed_bits"].GetGauge().DataPoints[0]
+	assert.Equal(t, 1e10, dataPoint.GetAsDouble())
+	assert.Empty(t, dataPoint.Attributes)
+
+	// Exporter-wide metrics are a separate resource without device
+	exporterMetrics := collector.resources()[""]
+	require.Len(t, exporterMetrics, 1)
+	assert.Equal(t, "discovery_refreshes_total", exporterMetrics[0].Name)
+	assert.Equal(t, 3.0, exporterMetrics[0].GetSum().DataPoints[0].GetAsDouble())
+}
+
+func TestOtlpExportHttp(t *testing.T) {
+	collector := &otlpTestCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+	setupOtlpFlags(t, server.URL, "http/protobuf")
+	assertOtlpExport(t, collector)
+}
+
+func TestOtlpExportGrpc(t *testing.T) {
+	collector := &otlpTestCollector{}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	colmetricpb.RegisterMetricsServiceServer(server, collector)
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+	setupOtlpFlags(t, "http://"+listener.Addr().String(), "grpc")
+	assertOtlpExport(t, collector)
+}
+
+func TestExportOtlpMetricsOnceReleasesConfigLock(t *testing.T) {
+	collector := &otlpTestCollector{}
+	var lockedExports atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Reload takes the write lock, it must not wait for exports
+		if configLock.TryLock() {
+			configLock.Unlock()
+		} else {
+			lockedExports.Add(1)
+		}
+		collector.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	setupOtlpFlags(t, server.URL, "http/protobuf")
+	otlpInterval = ptr(time.Minute)
+
+	ctx := context.Background()
+	exporter, err := newOtlpExporter(ctx)
+	require.NoError(t, err)
+	defer exporter.Shutdown(ctx)
+	assert.Equal(t, time.Minute, exportOtlpMetricsOnce(ctx, exporter))
+
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+	assert.NotEmpty(t, collector.requests)
+	assert.Zero(t, lockedExports.Load())
+}
+
+func TestRegistryToResourceMetrics(t *testing.T) {
+	metricRegistry := registry.Registry{
+		{Name: "statistics_general_rx_packets", Labels: map[string]string{"device": "eth0", "netns": "blue"}, Value: 42},
+		{Name: "bond_info_slave_state", Labels: map[string]string{"device": "bond0", "slave": "eth0"}, Value: 1},
+		{Name: "bond_info_slave_state", Labels: map[string]string{"device": "eth0", "slave": "eth1"}, Value: 0},
+	}
+	now := time.Now()
+	resourceMetrics := registryToResourceMetrics(otlpRegistryDevice("blue/eth0"), metricRegistry, "host1", now)
+
+	deviceAttribute, found := resourceMetrics.Resource.Set().Value(otlpDeviceAttribute)
+	require.True(t, found)
+	assert.Equal(t, "eth0", deviceAttribute.AsString())
+	hostAttribute, _ := resourceMetrics.Resource.Set().Value(otlpHostNameAttribute)
+	assert.Equal(t, "host1", hostAttribute.AsString())
+
+	metrics := resourceMetrics.ScopeMetrics[0].Metrics
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "bond_info_slave_state", metrics[0].Name)
+	gaugePoints := metrics[0].Data.(metricdata.Gauge[float64]).DataPoints
+	require.Len(t, gaugePoints, 2)
+	assert.Equal(t, 2, gaugePoints[0].Attributes.Len(), "device of other port is kept")
+	assert.Equal(t, 1, gaugePoints[1].Attributes.Len())
+
+	sum := metrics[1].Data.(metricdata.Sum[float64])
+	assert.True(t, sum.IsMonotonic)
+	assert.Equal(t, 42.0, sum.DataPoints[0].Value)
+	assert.Equal(t, now, sum.DataPoints[0].Time)
+	netnsAttribute, _ := sum.DataPoints[0].Attributes.Value("netns")
+	assert.Equal(t, "blue", netnsAttribute.AsString())
+
+	_, found = registryToResourceMetrics("", registry.Registry{}, "host1", now).Resource.Set().Value(otlpDeviceAttribute)
+	assert.False(t, found)
+}
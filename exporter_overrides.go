@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+
+	"github.com/newrushbolt/go-ethtool-exporter/collector"
+	"github.com/newrushbolt/go-ethtool-exporter/interfaces"
+)
+
+// Rule of `interface_overrides` config file section.
+// Regexps are not anchored, like `--discover-ports-regexp`. If both are set, both have to match.
+type interfaceOverrideFileConfig struct {
+	NameRegexp   string               `yaml:"name_regexp"`
+	DriverRegexp string               `yaml:"driver_regexp"`
+	Collectors   collectorsFileConfig `yaml:"collectors"`
+}
+
+type interfaceOverride struct {
+	nameRegexp   *regexp.Regexp
+	driverRegexp *regexp.Regexp
+	collectors   collectorsFileConfig
+}
+
+// Only set from config file, replaced on reload
+var interfaceOverrides []interfaceOverride
+
+func compileInterfaceOverrides(rules []interfaceOverrideFileConfig) ([]interfaceOverride, error) {
+	overrides := []interfaceOverride{}
+	for ruleIndex, rule := range rules {
+		if rule.NameRegexp == "" && rule.DriverRegexp == "" {
+			return nil, fmt.Errorf("interface override #%d: name_regexp or driver_regexp is required", ruleIndex)
+		}
+		// Device info is not an ethtool collector, it's exporter-wide
+		if rule.Collectors.DeviceInfo.Enabled != nil {
+			return nil, fmt.Errorf("interface override #%d: device_info cannot be overridden per interface", ruleIndex)
+		}
+		override := interfaceOverride{collectors: rule.Collectors}
+		var err error
+		if rule.NameRegexp != "" {
+			override.nameRegexp, err = regexp.Compile(rule.NameRegexp)
+			if err != nil {
+				return nil, fmt.Errorf("interface override #%d: invalid name_regexp: %w", ruleIndex, err)
+			}
+		}
+		if rule.DriverRegexp != "" {
+			override.driverRegexp, err = regexp.Compile(rule.DriverRegexp)
+			if err != nil {
+				return nil, fmt.Errorf("interface override #%d: invalid driver_regexp: %w", ruleIndex, err)
+			}
+		}
+		overrides = append(overrides, override)
+	}
+	return overrides, nil
+}
+
+func (override interfaceOverride) matches(interfaceName string, driverName func() string) bool {
+	if override.nameRegexp != nil && !override.nameRegexp.MatchString(interfaceName) {
+		return false
+	}
+	if override.driverRegexp != nil && !override.driverRegexp.MatchString(driverName()) {
+		return false
+	}
+	return true
+}
+
+func overrideBool(target *bool, value *bool) {
+	if value != nil {
+		*target = *value
+	}
+}
+
+// Sets every collector switch, absent metrics settings are kept
+func setAllCollectors(config *collector.CollectorConfig, enabled bool) {
+	config.DriverInfo.CollectCommon = enabled
+	config.DriverInfo.CollectFeatures = enabled
+	config.GenericInfo.CollectAdvertisedSettings = enabled
+	config.GenericInfo.CollectSupportedSettings = enabled
+	config.GenericInfo.CollectSettings = enabled
+	config.ModuleInfo.CollectDiagnosticsAlarms = enabled
+	config.ModuleInfo.CollectDiagnosticsValues = enabled
+	config.ModuleInfo.CollectDiagnosticsWarnings = enabled
+	config.ModuleInfo.CollectVendor = enabled
+	config.Statistics.General = enabled
+	config.Statistics.PerQueueGeneral = enabled
+	config.Statistics.PerQueuePerType = enabled
+	config.Statistics.PerQueueXdp = enabled
+	config.BondInfo.Collect = enabled
+}
+
+// Only values set in the rule are changed, others are kept from flags.
+// `all` enables or disables every collector first, so explicitly set values still apply on top of it.
+func applyCollectorsOverride(collectors collectorsFileConfig, config *collector.CollectorConfig) {
+	if collectors.All != nil {
+		setAllCollectors(config, *collectors.All)
+	}
+
+	overrideBool(&config.GenericInfo.CollectSettings, collectors.GenericInfo.Settings)
+	overrideBool(&config.GenericInfo.CollectAdvertisedSettings, collectors.GenericInfo.Modes)
+	overrideBool(&config.GenericInfo.CollectSupportedSettings, collectors.GenericInfo.Modes)
+	applyAbsentMetricsOverride(collectors.GenericInfo.AbsentMetrics, &config.GenericInfoAbsentMetrics.ExposeNan, &config.GenericInfoAbsentMetrics.ExposeTotalCounter, &config.GenericInfoAbsentMetrics.ExposeDetailedInfo)
+
+	overrideBool(&config.DriverInfo.CollectCommon, collectors.DriverInfo.Common)
+	overrideBool(&config.DriverInfo.CollectFeatures, collectors.DriverInfo.Features)
+	applyAbsentMetricsOverride(collectors.DriverInfo.AbsentMetrics, &config.DriverInfoAbsentMetrics.ExposeNan, &config.DriverInfoAbsentMetrics.ExposeTotalCounter, &config.DriverInfoAbsentMetrics.ExposeDetailedInfo)
+
+	overrideBool(&config.ModuleInfo.CollectDiagnosticsAlarms, collectors.ModuleInfo.DiagnosticsAlarms)
+	overrideBool(&config.ModuleInfo.CollectDiagnosticsWarnings, collectors.ModuleInfo.DiagnosticsWarnings)
+	overrideBool(&config.ModuleInfo.CollectDiagnosticsValues, collectors.ModuleInfo.DiagnosticsValues)
+	overrideBool(&config.ModuleInfo.CollectVendor, collectors.ModuleInfo.Vendor)
+	applyAbsentMetricsOverride(collectors.ModuleInfo.AbsentMetrics, &config.ModuleInfoAbsentMetrics.ExposeNan, &config.ModuleInfoAbsentMetrics.ExposeTotalCounter, &config.ModuleInfoAbsentMetrics.ExposeDetailedInfo)
+
+	overrideBool(&config.Statistics.General, collectors.Statistics.General)
+	overrideBool(&config.Statistics.PerQueueGeneral, collectors.Statistics.PerQueueGeneral)
+	overrideBool(&config.Statistics.PerQueuePerType, collectors.Statistics.PerQueuePerType)
+	overrideBool(&config.Statistics.PerQueueXdp, collectors.Statistics.PerQueueXdp)
+	overrideBool(&config.Statistics.PerQueueGenerateMissingBytesMetrics, collectors.Statistics.GenerateMissingPerQueueMetrics)
+	applyAbsentMetricsOverride(collectors.Statistics.AbsentMetrics, &config.StatisticsAbsentMetrics.ExposeNan, &config.StatisticsAbsentMetrics.ExposeTotalCounter, &config.StatisticsAbsentMetrics.ExposeDetailedInfo)
+
+	overrideBool(&config.BondInfo.Collect, collectors.BondInfo.Enabled)
+	applyAbsentMetricsOverride(collectors.BondInfo.AbsentMetrics, &config.BondInfoAbsentMetrics.ExposeNan, &config.BondInfoAbsentMetrics.ExposeTotalCounter, &config.BondInfoAbsentMetrics.ExposeDetailedInfo)
+}
+
+func applyAbsentMetricsOverride(absentMetrics absentMetricsFileConfig, exposeNan *bool, exposeTotalCounter *bool, exposeDetailedInfo *bool) {
+	overrideBool(exposeNan, absentMetrics.ExposeNan)
+	overrideBool(exposeTotalCounter, absentMetrics.ExposeTotalCounter)
+	overrideBool(exposeDetailedInfo, absentMetrics.ExposeDetailedInfo)
+}
+
+// The first matching override wins, global flags are used if none matches.
+// Driver name is only read from sysfs if some rule needs it.
+func selectCollectorConfig(interfaceName string, netClassPath string, defaultConfig collector.CollectorConfig, overrides []interfaceOverride) collector.CollectorConfig {
+	driverName := ""
+	driverNameRead := false
+	readDriverName := func() string {
+		if !driverNameRead {
+			driverName = interfaces.GetDriverName(netClassPath, interfaceName)
+			driverNameRead = true
+		}
+		return driverName
+	}
+	for overrideIndex, override := range overrides {
+		if !override.matches(interfaceName, readDriverName) {
+			continue
+		}
+		slog.Debug("Interface collector config is overridden", "interfaceName", interfaceName, "override", overrideIndex)
+		interfaceConfig := defaultConfig
+		applyCollectorsOverride(override.collectors, &interfaceConfig)
+		return interfaceConfig
+	}
+	return defaultConfig
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/newrushbolt/go-ethtool-exporter/collector"
+)
+
+func TestCompileInterfaceOverrides(t *testing.T) {
+	overrides, err := compileInterfaceOverrides(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, overrides)
+
+	_, err = compileInterfaceOverrides([]interfaceOverrideFileConfig{{}})
+	assert.ErrorContains(t, err, "name_regexp or driver_regexp is required")
+
+	_, err = compileInterfaceOverrides([]interfaceOverrideFileConfig{{NameRegexp: "eth["}})
+	assert.ErrorContains(t, err, "invalid name_regexp")
+
+	_, err = compileInterfaceOverrides([]interfaceOverrideFileConfig{{DriverRegexp: "ixgbe("}})
+	assert.ErrorContains(t, err, "invalid driver_regexp")
+
+	deviceInfoRule := interfaceOverrideFileConfig{NameRegexp: "eth0"}
+	deviceInfoRule.Collectors.DeviceInfo.Enabled = ptr(true)
+	_, err = compileInterfaceOverrides([]interfaceOverrideFileConfig{deviceInfoRule})
+	assert.ErrorContains(t, err, "device_info cannot be overridden")
+}
+
+func TestSelectCollectorConfig(t *testing.T) {
+	perQueueRule := interfaceOverrideFileConfig{NameRegexp: "^eth[0-4]$"}
+	perQueueRule.Collectors.Statistics.PerQueueGeneral = ptr(true)
+	perQueueRule.Collectors.ModuleInfo.AbsentMetrics.ExposeNan = ptr(false)
+	driverRule := interfaceOverrideFileConfig{NameRegexp: "eth", DriverRegexp: "^ixgbe$"}
+	driverRule.Collectors.GenericInfo.Settings = ptr(false)
+	driverRule.Collectors.DriverInfo.Common = ptr(true)
+	allRule := interfaceOverrideFileConfig{NameRegexp: "."}
+	allRule.Collectors.All = ptr(true)
+	allRule.Collectors.BondInfo.Enabled = ptr(false)
+	overrides, err := compileInterfaceOverrides([]interfaceOverrideFileConfig{perQueueRule, driverRule, allRule})
+	assert.NoError(t, err)
+
+	netClassPath := "testdata/interfaces/sys/class/net"
+	defaultConfig := collector.CollectorConfig{}
+	defaultConfig.GenericInfo.CollectSettings = true
+	defaultConfig.ModuleInfoAbsentMetrics.ExposeNan = true
+
+	assert.Equal(t, defaultConfig, selectCollectorConfig("eth0", netClassPath, defaultConfig, nil))
+
+	// Values missing in the rule are kept
+	expectedConfig := defaultConfig
+	expectedConfig.Statistics.PerQueueGeneral = true
+	expectedConfig.ModuleInfoAbsentMetrics.ExposeNan = false
+	assert.Equal(t, expectedConfig, selectCollectorConfig("eth4", netClassPath, defaultConfig, overrides))
+
+	// The first matching rule wins
+	expectedConfig = defaultConfig
+	expectedConfig.GenericInfo.CollectSettings = false
+	expectedConfig.DriverInfo.CollectCommon = true
+	assert.Equal(t, expectedConfig, selectCollectorConfig("eth6", netClassPath, defaultConfig, overrides))
+
+	allConfig := selectCollectorConfig("bond0", netClassPath, defaultConfig, overrides)
+	assert.True(t, allConfig.Statistics.PerQueueXdp)
+	assert.True(t, allConfig.ModuleInfo.CollectVendor)
+	assert.False(t, allConfig.BondInfo.Collect)
+
+	// Collectors not set in the rule are disabled
+	onlyDriverRule := interfaceOverrideFileConfig{NameRegexp: "^eno"}
+	onlyDriverRule.Collectors.All = ptr(false)
+	onlyDriverRule.Collectors.DriverInfo.Common = ptr(true)
+	overrides, err = compileInterfaceOverrides([]interfaceOverrideFileConfig{onlyDriverRule})
+	assert.NoError(t, err)
+	expectedConfig = defaultConfig
+	expectedConfig.GenericInfo.CollectSettings = false
+	expectedConfig.DriverInfo.CollectCommon = true
+	assert.Equal(t, expectedConfig, selectCollectorConfig("eno1", netClassPath, defaultConfig, overrides))
+}
+
+func TestExporterCollectMetricsWithInterfaceOverrides(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	t.Cleanup(func() { interfaceOverrides = nil })
+
+	metricRegistries := collectMetrics().Interfaces
+	assert.NotEmpty(t, metricRegistries["eth4"])
+
+	disableRule := interfaceOverrideFileConfig{DriverRegexp: "^$"}
+	disableRule.Collectors.GenericInfo.Settings = ptr(false)
+	disableRule.Collectors.GenericInfo.Modes = ptr(false)
+	overrides, err := compileInterfaceOverrides([]interfaceOverrideFileConfig{disableRule})
+	assert.NoError(t, err)
+	interfaceOverrides = overrides
+	metricRegistries = collectMetrics().Interfaces
+	assert.Empty(t, metricRegistries["eth4"])
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/newrushbolt/go-ethtool-exporter/registry"
+)
+
+const (
+	pushHostnamePlaceholder = "<hostname>"
+	// Fits into 1500 bytes MTU with IPv6 and UDP headers
+	pushUdpDatagramSize = 1432
+)
+
+var errUnsupportedPushScheme = errors.New("unsupported URL scheme")
+
+// Response of push endpoint, that is not 2xx
+type pushStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (err *pushStatusError) Error() string {
+	return fmt.Sprintf("push endpoint responded with %d: %s", err.StatusCode, err.Body)
+}
+
+// Only server-side failures and throttling are retried, other 4xx won't succeed on the next try
+func isPushRetriable(err error) bool {
+	if errors.Is(err, errUnsupportedPushScheme) {
+		return false
+	}
+	var statusErr *pushStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// Parses `label=value` pairs, `<hostname>` value is replaced with the hostname
+func parsePushGroupingKey(groupingKey string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, pair := range strings.Split(groupingKey, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		labelName, labelValue, found := strings.Cut(pair, "=")
+		if !found || labelName == "" {
+			return nil, fmt.Errorf("grouping key pair <%s> is not in 'label=value' format", pair)
+		}
+		if labelName == "job" {
+			return nil, errors.New("grouping key cannot contain 'job' label, use --push-job instead")
+		}
+		if labelValue == pushHostnamePlaceholder {
+			hostname, err := os.Hostname()
+			if err != nil {
+				return nil, err
+			}
+			labelValue = hostname
+		}
+		labels[labelName] = labelValue
+	}
+	return labels, nil
+}
+
+// Values with slashes or empty values are base64-encoded, as described in
+// https://github.com/prometheus/pushgateway#url
+func pushgatewayPathSegment(labelName string, labelValue string) string {
+	if labelValue == "" {
+		return labelName + "@base64/="
+	}
+	if strings.Contains(labelValue, "/") {
+		return labelName + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(labelValue))
+	}
+	return labelName + "/" + url.PathEscape(labelValue)
+}
+
+func getPushgatewayUrl(baseUrl string, job string, groupingKey map[string]string) string {
+	segments := []string{strings.TrimSuffix(baseUrl, "/"), "metrics", pushgatewayPathSegment("job", job)}
+	for _, labelName := range slices.Sorted(maps.Keys(groupingKey)) {
+		segments = append(segments, pushgatewayPathSegment(labelName, groupingKey[labelName]))
+	}
+	return strings.Join(segments, "/")
+}
+
+// Rendered metrics with everything needed to send them. It's built while config lock is held,
+// so sending and retries don't read flags and config reload doesn't wait for them.
+type pushPayload struct {
+	Protocol     string
+	Url          string
+	Timeout      time.Duration
+	Retries      int
+	RetryBackoff time.Duration
+	// HTTP protocols only
+	Method  string
+	Headers map[string]string
+	// Lines for 'influx' and 'graphite', request body for others
+	Body []byte
+}
+
+// All retries push the same samples with the same timestamp, so remote-write receivers can deduplicate them.
+// Protocol and URL are set even if error is returned, eg for logging.
+func newPushPayload(metricRegistries registry.RegistryCollection) (pushPayload, error) {
+	payload := pushPayload{
+		Protocol:     *pushProtocol,
+		Url:          *pushUrl,
+		Timeout:      *pushTimeout,
+		Retries:      *pushRetries,
+		RetryBackoff: *pushRetryBackoff,
+	}
+	groupingKey, err := parsePushGroupingKey(*pushGroupingKey)
+	if err != nil {
+		return payload, err
+	}
+	timestamp := time.Now()
+	extraLabels := maps.Clone(groupingKey)
+	extraLabels["job"] = *pushJob
+
+	switch payload.Protocol {
+	case "influx", "graphite":
+		pushAddress, err := url.Parse(payload.Url)
+		if err != nil {
+			return payload, err
+		}
+		if pushAddress.Scheme != "tcp" && pushAddress.Scheme != "udp" {
+			return payload, fmt.Errorf("%w <%s> for %s protocol, expected 'tcp' or 'udp'", errUnsupportedPushScheme, pushAddress.Scheme, payload.Protocol)
+		}
+		lines, err := renderLineMetrics(metricRegistries, payload.Protocol, extraLabels, timestamp)
+		if err != nil {
+			return payload, err
+		}
+		payload.Body = []byte(lines)
+	case "remote-write":
+		payload.Method = http.MethodPost
+		payload.Headers = map[string]string{
+			"Content-Encoding":                  "snappy",
+			"Content-Type":                      "application/x-protobuf",
+			"X-Prometheus-Remote-Write-Version": "0.1.0",
+		}
+		payload.Body = metricRegistries.GetRemoteWriteRequest(extraLabels, timestamp)
+	default:
+		payload.Method = http.MethodPut
+		payload.Url = getPushgatewayUrl(payload.Url, *pushJob, groupingKey)
+		payload.Headers = map[string]string{"Content-Type": "text/plain; version=0.0.4; charset=utf-8"}
+		payload.Body = []byte(metricRegistries.GetAllMetricsText() + "\n")
+	}
+	return payload, nil
+}
+
+// Request is rebuilt for every retry, as body is consumed
+func sendPushRequest(payload pushPayload) error {
+	ctx, cancel := context.WithTimeout(context.Background(), payload.Timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, payload.Method, payload.Url, bytes.NewReader(payload.Body))
+	if err != nil {
+		return err
+	}
+	for headerName, headerValue := range payload.Headers {
+		request.Header.Set(headerName, headerValue)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		responseBody, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return &pushStatusError{StatusCode: response.StatusCode, Body: strings.TrimSpace(string(responseBody))}
+	}
+	return nil
+}
+
+// Sends lines over TCP, or over UDP in datagrams below usual MTU, so they are not fragmented.
+// Lines longer than that are sent in separate datagrams.
+func sendPushLines(payload pushPayload) error {
+	pushAddress, err := url.Parse(payload.Url)
+	if err != nil {
+		return err
+	}
+	connection, err := net.DialTimeout(pushAddress.Scheme, pushAddress.Host, payload.Timeout)
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+	err = connection.SetDeadline(time.Now().Add(payload.Timeout))
+	if err != nil {
+		return err
+	}
+	lines := string(payload.Body)
+	if pushAddress.Scheme == "tcp" {
+		_, err = io.WriteString(connection, lines)
+		return err
+	}
+	var datagram strings.Builder
+	for _, line := range strings.SplitAfter(lines, "\n") {
+		if datagram.Len() > 0 && datagram.Len()+len(line) > pushUdpDatagramSize {
+			_, err = io.WriteString(connection, datagram.String())
+			if err != nil {
+				return err
+			}
+			datagram.Reset()
+		}
+		datagram.WriteString(line)
+	}
+	if datagram.Len() > 0 {
+		_, err = io.WriteString(connection, datagram.String())
+	}
+	return err
+}
+
+func sendPushPayload(payload pushPayload) error {
+	backoff := payload.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		var err error
+		if payload.Protocol == "influx" || payload.Protocol == "graphite" {
+			err = sendPushLines(payload)
+		} else {
+			err = sendPushRequest(payload)
+		}
+		if err == nil {
+			slog.Debug("Metrics pushed", "protocol", payload.Protocol, "url", payload.Url, "attempt", attempt)
+			return nil
+		}
+		if attempt >= payload.Retries || !isPushRetriable(err) {
+			return err
+		}
+		slog.Warn("Cannot push metrics, retrying", "protocol", payload.Protocol, "url", payload.Url, "attempt", attempt, "backoff", backoff, "error", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func pushMetrics(metricRegistries registry.RegistryCollection) error {
+	payload, err := newPushPayload(metricRegistries)
+	if err != nil {
+		return err
+	}
+	return sendPushPayload(payload)
+}
+
+func runPushCommand() {
+	if *pushUrl == "" {
+		slog.Error("Flag --push-url is required in 'push' mode")
+		os.Exit(1)
+	}
+	_, err := parsePushGroupingKey(*pushGroupingKey)
+	if err != nil {
+		slog.Error("Cannot parse grouping key", "pushGroupingKey", *pushGroupingKey, "error", err)
+		os.Exit(1)
+	}
+
+	if *pushInterval == 0 {
+		err = pushMetrics(collectMetrics().all())
+		if err != nil {
+			slog.Error("Cannot push metrics", "protocol", *pushProtocol, "url", *pushUrl, "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Loop push mode, failed pushes are skipped until the next interval
+	startLinkWatcher()
+	startDiscoveryCache()
+	startReloadOnSighup()
+	for {
+		time.Sleep(pushMetricsOnce())
+	}
+}
+
+// Config read lock is only held until metrics are rendered, so reload doesn't wait for slow pushes and retries.
+// Returns interval until the next push.
+func pushMetricsOnce() time.Duration {
+	configLock.RLock()
+	payload, err := newPushPayload(collectMetricsLocked().all())
+	interval := *pushInterval
+	configLock.RUnlock()
+
+	if err == nil {
+		err = sendPushPayload(payload)
+	}
+	if err != nil {
+		slog.Error("Cannot push metrics", "protocol", payload.Protocol, "url", payload.Url, "error", err)
+	}
+	return interval
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupPushFlags(t *testing.T, serverUrl string) {
+	setupHttpHandlerFlags(t)
+	pushUrl = ptr(serverUrl)
+	pushProtocol = ptr("pushgateway")
+	pushJob = ptr("ethtool")
+	pushGroupingKey = ptr("instance=host1,rack=a/1")
+	pushTimeout = ptr(time.Second)
+	pushRetries = ptr(2)
+	pushRetryBackoff = ptr(time.Millisecond)
+}
+
+func TestParsePushGroupingKey(t *testing.T) {
+	groupingKey, err := parsePushGroupingKey(" instance=host1, site=dc1 ,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"instance": "host1", "site": "dc1"}, groupingKey)
+
+	groupingKey, err = parsePushGroupingKey("")
+	require.NoError(t, err)
+	assert.Empty(t, groupingKey)
+
+	_, err = parsePushGroupingKey("instance")
+	assert.Error(t, err)
+	_, err = parsePushGroupingKey("job=other")
+	assert.Error(t, err)
+}
+
+func TestGetPushgatewayUrl(t *testing.T) {
+	pushgatewayUrl := getPushgatewayUrl("http://pushgateway:9091/", "ethtool", map[string]string{"rack": "a/1", "instance": "host1", "empty": ""})
+	assert.Equal(t, "http://pushgateway:9091/metrics/job/ethtool/empty@base64/=/instance/host1/rack@base64/YS8x", pushgatewayUrl)
+}
+
+func TestPushMetricsPushgateway(t *testing.T) {
+	var requestMethod, requestPath, requestBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requestMethod, requestPath, requestBody = r.Method, r.URL.EscapedPath(), string(body)
+	}))
+	defer server.Close()
+	setupPushFlags(t, server.URL)
+
+	require.NoError(t, pushMetrics(collectMetrics().all()))
+	assert.Equal(t, http.MethodPut, requestMethod)
+	assert.Equal(t, "/metrics/job/ethtool/instance/host1/rack@base64/YS8x", requestPath)
+	assert.Contains(t, requestBody, `generic_info_settings_speed_bits{device="eth4"} 1e+10`)
+}
+
+func TestPushMetricsRemoteWrite(t *testing.T) {
+	var requestHeaders http.Header
+	var requestBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestHeaders = r.Header
+		compressed, _ := io.ReadAll(r.Body)
+		requestBody, _ = snappy.Decode(nil, compressed)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	setupPushFlags(t, server.URL+"/api/v1/write")
+	pushProtocol = ptr("remote-write")
+
+	require.NoError(t, pushMetrics(collectMetrics().all()))
+	assert.Equal(t, "snappy", requestHeaders.Get("Content-Encoding"))
+	assert.Equal(t, "application/x-protobuf", requestHeaders.Get("Content-Type"))
+	assert.Equal(t, "0.1.0", requestHeaders.Get("X-Prometheus-Remote-Write-Version"))
+	assert.Contains(t, string(requestBody), "generic_info_settings_speed_bits")
+	assert.Contains(t, string(requestBody), "host1")
+}
+
+func TestPushMetricsRetries(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	setupPushFlags(t, server.URL)
+
+	require.NoError(t, pushMetrics(collectMetrics().all()))
+	assert.Equal(t, int32(3), attempts.Load())
+
+	attempts.Store(-10)
+	err := pushMetrics(collectMetrics().all())
+	assert.ErrorContains(t, err, "503")
+	assert.Equal(t, int32(-7), attempts.Load())
+}
+
+func TestPushMetricsClientErrorNotRetried(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		http.Error(w, "pushed metrics are invalid", http.StatusBadRequest)
+	}))
+	defer server.Close()
+	setupPushFlags(t, server.URL)
+
+	err := pushMetrics(collectMetrics().all())
+	assert.ErrorContains(t, err, "pushed metrics are invalid")
+	assert.Equal(t, int32(1), attempts.Load())
+}
+
+func TestPushMetricsGraphiteTcp(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	received := make(chan string, 1)
+	go func() {
+		connection, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer connection.Close()
+		lines, _ := io.ReadAll(connection)
+		received <- string(lines)
+	}()
+	setupPushFlags(t, "tcp://"+listener.Addr().String())
+	pushProtocol = ptr("graphite")
+	graphitePathTemplate = ptr("ethtool.{device}.{name}")
+
+	require.NoError(t, pushMetrics(collectMetrics().all()))
+	lines := <-received
+	assert.Regexp(t, `(?m)^ethtool\.eth4\.generic_info_settings_speed_bits;instance=host1;job=ethtool;rack=a/1 1e\+10 \d+$`, lines)
+}
+
+func TestPushMetricsInfluxUdp(t *testing.T) {
+	connection, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer connection.Close()
+	setupPushFlags(t, "udp://"+connection.LocalAddr().String())
+	pushProtocol = ptr("influx")
+	// Driver features are enough to exceed a single datagram
+	collectDriverInfoFeatures = ptr(true)
+	metricRegistries := collectMetrics().all()
+	require.NoError(t, pushMetrics(metricRegistries))
+
+	expectedLines := metricRegistries.GetAllMetricsInflux(influxMeasurement, metricCollectorNames(), map[string]string{"instance": "host1", "job": "ethtool", "rack": "a/1"}, time.Now())
+	var received strings.Builder
+	datagram := make([]byte, 65536)
+	require.NoError(t, connection.SetReadDeadline(time.Now().Add(time.Second)))
+	datagrams := 0
+	for received.Len() < len(expectedLines) {
+		length, _, err := connection.ReadFrom(datagram)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, length, pushUdpDatagramSize)
+		received.Write(datagram[:length])
+		datagrams++
+	}
+	assert.Greater(t, datagrams, 1)
+	assert.Equal(t, strings.Count(expectedLines, "\n"), strings.Count(received.String(), "\n"))
+	assert.Contains(t, received.String(), "ethtool,collector=generic_info,device=eth4,instance=host1,job=ethtool,rack=a/1 settings_speed_bits=1e+10 ")
+}
+
+func TestPushMetricsUnsupportedScheme(t *testing.T) {
+	setupPushFlags(t, "http://localhost:2003")
+	pushProtocol = ptr("graphite")
+	err := pushMetrics(collectMetrics().all())
+	assert.ErrorIs(t, err, errUnsupportedPushScheme)
+}
+
+func TestPushMetricsOnceReleasesConfigLock(t *testing.T) {
+	var attempts, lockedAttempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Reload takes the write lock, it must not wait for pushes and retries
+		if configLock.TryLock() {
+			configLock.Unlock()
+		} else {
+			lockedAttempts.Add(1)
+		}
+		if attempts.Add(1) < 2 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	setupPushFlags(t, server.URL)
+	pushInterval = ptr(time.Minute)
+
+	assert.Equal(t, time.Minute, pushMetricsOnce())
+	assert.Equal(t, int32(2), attempts.Load())
+	assert.Zero(t, lockedAttempts.Load())
+}
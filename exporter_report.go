@@ -0,0 +1,429 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/newrushbolt/go-ethtool-exporter/collector"
+	"github.com/newrushbolt/go-ethtool-exporter/interfaces"
+	"github.com/newrushbolt/go-ethtool-metrics/pkg/metrics/driver_info"
+	"github.com/newrushbolt/go-ethtool-metrics/pkg/metrics/generic_info"
+	"github.com/newrushbolt/go-ethtool-metrics/pkg/metrics/module_info"
+	"github.com/newrushbolt/go-ethtool-metrics/pkg/metrics/statistics"
+)
+
+// Vendor directories used in go-ethtool-metrics testdata, by PCI vendor ID
+var reportVendorNames = map[string]string{
+	"0x8086": "intel",
+	"0x14e4": "broadcom",
+	"0x15b3": "mellanox",
+	"0x1ae0": "google",
+	"0x1d0f": "amazon",
+	"0x1af4": "virtual",
+	"0x1077": "qlogic",
+	"0x1924": "solarflare",
+	"0x1425": "chelsio",
+}
+
+// Sysfs attributes used by port discovery and device info
+var (
+	reportSysfsAttributes      = []string{"type", "operstate", "carrier", "addr_assign_type", "address", "ifalias", "phys_port_name", "phys_port_id", "phys_switch_id", "uevent"}
+	reportSysfsAttributeGroups = []string{"bonding_slave", "brport", "bonding"}
+	// Attributes of the bus device behind `device` link, eg PCI function
+	reportSysfsBusAttributes = []string{"vendor", "device", "numa_node", "uevent"}
+)
+
+// Links to other ports are stored as symlinks to their netclass entries, and `lower_*` ones are found by listing.
+// Only the last part of bus name links is kept, it is enough to read driver and subsystem names.
+// SR-IOV links between PCI functions are kept as is, they point to sibling bus devices.
+var (
+	reportSysfsPortLinks    = []string{"master", "brport/bridge"}
+	reportSysfsBusNameLinks = []string{"driver", "subsystem"}
+	reportSysfsBusLinkGlobs = []string{"physfn", "virtfn*"}
+)
+
+const reportNetClassDirectory = "sysfs/class/net"
+
+// Flags that carry credentials, eg authentication headers, and URL flags that may have `user:pass@` in them
+var (
+	reportRedactedFlags = []string{"otlp-headers"}
+	reportUrlFlags      = []string{"push-url", "otlp-endpoint"}
+)
+
+const reportRedactedValue = "<redacted>"
+
+type reportFile struct {
+	Name    string
+	Content []byte
+	// Symlink is written instead of regular file if set
+	LinkTarget string
+}
+
+// Results are rendered the same way go-ethtool-metrics tests expect them, with 'default' and 'full' collect configs
+func renderReportResults(collectorName string, rawData string) (map[string]any, error) {
+	results := map[string]any{}
+	switch collectorName {
+	case "driver_info":
+		results["default"] = driver_info.ParseInfo(rawData, driver_info.CollectConfig{}.Default())
+		results["full"] = driver_info.ParseInfo(rawData, &driver_info.CollectConfig{CollectCommon: true, CollectFeatures: true})
+	case "generic_info":
+		results["default"] = generic_info.ParseInfo(rawData, generic_info.CollectConfig{}.Default())
+		results["full"] = generic_info.ParseInfo(rawData, &generic_info.CollectConfig{CollectAdvertisedSettings: true, CollectSupportedSettings: true, CollectSettings: true})
+	case "module_info":
+		results["default"] = module_info.ParseInfo(rawData, module_info.CollectConfig{}.Default())
+		results["full"] = module_info.ParseInfo(rawData, &module_info.CollectConfig{CollectDiagnosticsAlarms: true, CollectDiagnosticsValues: true, CollectDiagnosticsWarnings: true, CollectVendor: true})
+	case "statistics":
+		results["default"] = statistics.ParseInfo(rawData, statistics.CollectConfig{}.Default())
+		results["full"] = statistics.ParseInfo(rawData, &statistics.CollectConfig{General: true, PerQueueGeneral: true, PerQueueXdp: true, PerQueuePerType: true, PerQueueGenerateMissingBytesMetrics: true})
+	default:
+		return nil, fmt.Errorf("unknown collector %s", collectorName)
+	}
+	return results, nil
+}
+
+// Driver from `ethtool -i` is preferred, as testdata README suggests, sysfs one is empty for virtual devices
+func reportTestdataDirectory(interfaceName string, driverInfoRaw string, netClassPath string, index map[string]int) string {
+	vendorName, found := reportVendorNames[interfaces.GetDeviceInfo(netClassPath, interfaceName).PciVendorId]
+	if !found {
+		vendorName = "unknown"
+	}
+	driverName := ""
+	driverInfo := driver_info.ParseInfo(driverInfoRaw, driver_info.CollectConfig{}.Default())
+	if driverInfo.Common != nil {
+		driverName = driverInfo.Common.DriverName
+	}
+	if driverName == "" {
+		driverName = interfaces.GetDriverName(netClassPath, interfaceName)
+	}
+	if driverName == "" {
+		driverName = "unknown"
+	}
+	driverDirectory := path.Join(vendorName, driverName)
+	instanceIndex := index[driverDirectory]
+	index[driverDirectory]++
+	return path.Join("testdata", driverDirectory, fmt.Sprintf("%02d_%s", instanceIndex, interfaceName))
+}
+
+func reportEthtoolFiles(discoveredInterfaces []string, netClassPath string, collectorConfig collector.CollectorConfig) []reportFile {
+	files := []reportFile{}
+	index := map[string]int{}
+	collector.RunInEthtoolNetns(collectorConfig, func(collectorConfig collector.CollectorConfig) {
+		for _, interfaceName := range discoveredInterfaces {
+			rawData, err := collector.ReadRawEthtoolData(interfaceName, collectorConfig)
+			if err != nil {
+				slog.Error("Cannot read ethtool data for report", "interfaceName", interfaceName, "error", err)
+				continue
+			}
+			instanceDirectory := reportTestdataDirectory(interfaceName, rawData["driver_info"], netClassPath, index)
+			for _, mode := range collector.EthtoolModes {
+				files = append(files, reportFile{
+					Name:    path.Join(instanceDirectory, "src", mode.Collector),
+					Content: []byte(rawData[mode.Collector]),
+				})
+				results, err := renderReportResults(mode.Collector, rawData[mode.Collector])
+				if err != nil {
+					slog.Error("Cannot render results for report", "interfaceName", interfaceName, "error", err)
+					continue
+				}
+				for _, settingsMode := range []string{"default", "full"} {
+					resultJson, err := json.MarshalIndent(results[settingsMode], "", "    ")
+					if err != nil {
+						slog.Error("Cannot render results for report", "interfaceName", interfaceName, "error", err)
+						continue
+					}
+					files = append(files, reportFile{
+						Name:    path.Join(instanceDirectory, "results", fmt.Sprintf("%s.%s.json", mode.Collector, settingsMode)),
+						Content: resultJson,
+					})
+				}
+			}
+		}
+	})
+	return files
+}
+
+// All the netclass entries are included, so it is clear why some ports were not discovered.
+// Entries are symlinks to device directories, eg `../../devices/pci0000:00/0000:00:06.0/net/eth6`,
+// they are recorded the same way along with bus devices, so physical ports and SR-IOV topology
+// of extracted archive are discovered the same as live ones. Other entries are recorded as directories.
+func reportSysfsFiles(netClassPath string) []reportFile {
+	files := []reportFile{}
+	deviceDirs, err := os.ReadDir(netClassPath)
+	if err != nil {
+		slog.Error("Cannot list netclass directory for report", "netClassPath", netClassPath, "error", err)
+		return files
+	}
+	recordedBusDirectories := []string{}
+	for _, deviceDir := range deviceDirs {
+		devicePath := path.Join(netClassPath, deviceDir.Name())
+		reportDirectory := path.Join(reportNetClassDirectory, deviceDir.Name())
+		entryTarget, err := os.Readlink(devicePath)
+		if devicesIndex := strings.Index(entryTarget, "devices/"); err == nil && devicesIndex >= 0 {
+			reportDirectory = path.Join("sysfs", entryTarget[devicesIndex:])
+			files = append(files, reportFile{Name: path.Join(reportNetClassDirectory, deviceDir.Name()), LinkTarget: "../../" + entryTarget[devicesIndex:]})
+		}
+		files = append(files, reportPortSysfsFiles(devicePath, reportDirectory)...)
+
+		busTarget, err := os.Readlink(path.Join(devicePath, "device"))
+		if err != nil {
+			continue
+		}
+		busDirectory := path.Join(reportDirectory, busTarget)
+		if !strings.HasPrefix(busDirectory, "sysfs/") {
+			slog.Debug("Bus device is outside of sysfs, skipping it in report", "devicePath", devicePath, "busTarget", busTarget)
+			continue
+		}
+		files = append(files, reportFile{Name: path.Join(reportDirectory, "device"), LinkTarget: busTarget})
+		// PF netdev and its representors share the bus device
+		if slices.Contains(recordedBusDirectories, busDirectory) {
+			continue
+		}
+		recordedBusDirectories = append(recordedBusDirectories, busDirectory)
+		files = append(files, reportBusSysfsFiles(path.Join(devicePath, "device"), busDirectory)...)
+	}
+	return files
+}
+
+func reportSysfsAttributeFiles(sysfsPath string, reportDirectory string, attributePaths []string) []reportFile {
+	files := []reportFile{}
+	for _, attributePath := range attributePaths {
+		// Some attributes are write-only or return errors, eg `brport/flush` or `phys_port_name`
+		content, err := os.ReadFile(path.Join(sysfsPath, attributePath))
+		if err != nil {
+			continue
+		}
+		files = append(files, reportFile{Name: path.Join(reportDirectory, attributePath), Content: content})
+	}
+	return files
+}
+
+func reportPortSysfsFiles(devicePath string, reportDirectory string) []reportFile {
+	attributePaths := slices.Clone(reportSysfsAttributes)
+	linkPaths := slices.Clone(reportSysfsPortLinks)
+	deviceEntries, _ := os.ReadDir(devicePath)
+	for _, deviceEntry := range deviceEntries {
+		if strings.HasPrefix(deviceEntry.Name(), "lower_") {
+			linkPaths = append(linkPaths, deviceEntry.Name())
+		}
+	}
+	for _, attributeGroup := range reportSysfsAttributeGroups {
+		groupEntries, err := os.ReadDir(path.Join(devicePath, attributeGroup))
+		if err != nil {
+			continue
+		}
+		for _, groupEntry := range groupEntries {
+			attributePaths = append(attributePaths, path.Join(attributeGroup, groupEntry.Name()))
+		}
+	}
+	files := reportSysfsAttributeFiles(devicePath, reportDirectory, attributePaths)
+
+	for _, linkPath := range linkPaths {
+		linkTarget, err := os.Readlink(path.Join(devicePath, linkPath))
+		if err != nil {
+			continue
+		}
+		linkDirectory := path.Join(reportDirectory, path.Dir(linkPath))
+		relativeTarget, err := filepath.Rel(linkDirectory, path.Join(reportNetClassDirectory, path.Base(linkTarget)))
+		if err != nil {
+			continue
+		}
+		files = append(files, reportFile{Name: path.Join(reportDirectory, linkPath), LinkTarget: relativeTarget})
+	}
+	return files
+}
+
+func reportBusSysfsFiles(busPath string, reportDirectory string) []reportFile {
+	files := reportSysfsAttributeFiles(busPath, reportDirectory, reportSysfsBusAttributes)
+	for _, linkPath := range reportSysfsBusNameLinks {
+		linkTarget, err := os.Readlink(path.Join(busPath, linkPath))
+		if err != nil {
+			continue
+		}
+		files = append(files, reportFile{Name: path.Join(reportDirectory, linkPath), LinkTarget: path.Base(linkTarget)})
+	}
+	for _, linkGlob := range reportSysfsBusLinkGlobs {
+		linkPaths, _ := filepath.Glob(path.Join(busPath, linkGlob))
+		for _, linkPath := range linkPaths {
+			linkTarget, err := os.Readlink(linkPath)
+			if err != nil {
+				continue
+			}
+			files = append(files, reportFile{Name: path.Join(reportDirectory, path.Base(linkPath)), LinkTarget: linkTarget})
+		}
+	}
+	return files
+}
+
+func reportBondingFiles(procNetBondingPath string) []reportFile {
+	files := []reportFile{}
+	bondEntries, err := os.ReadDir(procNetBondingPath)
+	if err != nil {
+		slog.Debug("Cannot list bonding directory for report", "procNetBondingPath", procNetBondingPath, "error", err)
+		return files
+	}
+	for _, bondEntry := range bondEntries {
+		content, err := os.ReadFile(path.Join(procNetBondingPath, bondEntry.Name()))
+		if err != nil {
+			continue
+		}
+		files = append(files, reportFile{Name: path.Join("procfs/net/bonding", bondEntry.Name()), Content: content})
+	}
+	return files
+}
+
+// Effective values, including ones from config file and environment
+// Report is meant to be attached to public bug reports, so credentials are never written to it.
+// Raw command line is not written either, as it may contain them in any form.
+func reportFlagsFile(app *kingpin.Application) reportFile {
+	var content strings.Builder
+	for _, flagModel := range app.Model().Flags {
+		if flagModel.Hidden || flagModel.Value == nil {
+			continue
+		}
+		fmt.Fprintf(&content, "--%s=%s\n", flagModel.Name, redactFlagValue(flagModel.Name, flagModel.Value.String()))
+	}
+	return reportFile{Name: "flags.txt", Content: []byte(content.String())}
+}
+
+func redactFlagValue(flagName string, value string) string {
+	if value == "" {
+		return value
+	}
+	if slices.Contains(reportRedactedFlags, flagName) {
+		return reportRedactedValue
+	}
+	if !slices.Contains(reportUrlFlags, flagName) {
+		return value
+	}
+	parsedUrl, err := url.Parse(value)
+	if err != nil {
+		return reportRedactedValue
+	}
+	parsedUrl.User = nil
+	return parsedUrl.String()
+}
+
+func buildReportFiles() ([]reportFile, error) {
+	allowedTypes := parseAllowedInterfaceTypes(*discoverAllowedPortTypes)
+	decisions, err := interfaces.ExplainInterfaces(getNetClassPath(), createDiscoveryConfig(), allowedTypes)
+	if err != nil {
+		return nil, err
+	}
+	var discoveryJson bytes.Buffer
+	err = printPortsJson(&discoveryJson, explainPorts(getNetClassPath(), "", decisions))
+	if err != nil {
+		return nil, err
+	}
+
+	metricRegistries := collectMetrics().all()
+	files := []reportFile{
+		{Name: "version.txt", Content: []byte(getExporterVersion(debug.ReadBuildInfo) + "\n")},
+		reportFlagsFile(kingpin.CommandLine),
+		{Name: "discovery.json", Content: discoveryJson.Bytes()},
+		{Name: "metrics.prom", Content: []byte(metricRegistries.GetAllMetricsText())},
+	}
+	files = append(files, reportEthtoolFiles(interfaces.AcceptedPorts(decisions), getNetClassPath(), createCollectorConfig())...)
+	files = append(files, reportSysfsFiles(getNetClassPath())...)
+	files = append(files, reportBondingFiles(getProcNetBondingPath())...)
+	return files, nil
+}
+
+// Archive is written to a temporary file and renamed on success, the same way as textfiles,
+// so a failed report never leaves truncated archive behind
+func writeReportArchive(reportPath string, files []reportFile) error {
+	tmpFile, err := os.CreateTemp(path.Dir(reportPath), path.Base(reportPath)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	err = writeReportTar(tmpFile, strings.TrimSuffix(path.Base(reportPath), ".tar.gz"), files)
+	if err != nil {
+		tmpFile.Close()
+		return err
+	}
+	err = tmpFile.Chmod(0644)
+	if err != nil {
+		tmpFile.Close()
+		return err
+	}
+	err = tmpFile.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), reportPath)
+}
+
+// All files are put into a single top directory, named after the archive
+func writeReportTar(archiveFile io.Writer, topDirectory string, files []reportFile) error {
+	gzipWriter := gzip.NewWriter(archiveFile)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	modTime := time.Now().Truncate(time.Second)
+	for _, file := range files {
+		header := &tar.Header{
+			Name:    path.Join(topDirectory, file.Name),
+			Mode:    0644,
+			Size:    int64(len(file.Content)),
+			ModTime: modTime,
+		}
+		if file.LinkTarget != "" {
+			header.Typeflag = tar.TypeSymlink
+			header.Linkname = file.LinkTarget
+			header.Mode = 0777
+		}
+		err := tarWriter.WriteHeader(header)
+		if err != nil {
+			return err
+		}
+		_, err = tarWriter.Write(file.Content)
+		if err != nil {
+			return err
+		}
+	}
+	err := tarWriter.Close()
+	if err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+func defaultReportPath(now time.Time) string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("go-ethtool-exporter-report-%s-%s.tar.gz", hostname, now.Format("20060102-150405"))
+}
+
+func runReportCommand() {
+	reportPath := *reportFilePath
+	if reportPath == "" {
+		reportPath = defaultReportPath(time.Now())
+	}
+	files, err := buildReportFiles()
+	if err != nil {
+		panic(err.Error())
+	}
+	err = writeReportArchive(reportPath, files)
+	if err != nil {
+		slog.Error("Cannot write report archive", "reportPath", reportPath, "error", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Report with %d files written to %s\n", len(files), reportPath)
+}
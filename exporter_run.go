@@ -1,56 +1,56 @@
 package main
 
 import (
-	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
 	"os"
 	"slices"
-	"strings"
 	"time"
 
-	"github.com/newrushbolt/go-ethtool-exporter/interfaces"
 	"golang.org/x/net/netutil"
 )
 
-func runDiscoverPortsCommand() {
-	// Discover ports mode
-	allowedTypes := parseAllowedInterfaceTypes(*discoverAllowedPortTypes)
-	discoverConfig := createDiscoveryConfig()
-	interfaces := interfaces.GetInterfacesList(*linuxNetClassPath, discoverConfig, allowedTypes)
-	if len(interfaces) == 0 {
-		fmt.Println("No ports discovered, re-run with `GO_ETHTOOL_EXPORTER_LOG_LEVEL=DEBUG` to check the discovery logic")
-	} else {
-		fmt.Println("Discovered following ports:")
-		interfacesString := fmt.Sprintf("  - %s", strings.Join(interfaces, "\n  - "))
-		fmt.Println(interfacesString)
-	}
-}
+const reloadPath = "/-/reload"
 
 func runSingleTextfileCommand() {
 	// Single textfile mode
 	MustDirectoryExist(textfileDirectory)
-	metricRegistries := collectMetrics()
-	writeAllMetricsToTextfiles(metricRegistries)
+	configLock.RLock()
+	defer configLock.RUnlock()
+	updateTextfilesLocked()
 }
 
 func runLoopTextfileCommand() {
 	// Loop textfile mode
 	MustDirectoryExist(textfileDirectory)
+	startLinkWatcher()
+	startDiscoveryCache()
+	startReloadOnSighup()
 	for {
-		metricRegistries := collectMetrics()
-		writeAllMetricsToTextfiles(metricRegistries)
-		time.Sleep(*loopTextfileUpdateInterval)
+		time.Sleep(updateTextfiles())
 	}
 }
 
+// Config read lock is held until textfiles are written, so reload cannot change flags in between.
+// Returns interval until the next update.
+func updateTextfiles() time.Duration {
+	configLock.RLock()
+	defer configLock.RUnlock()
+	updateTextfilesLocked()
+	return *loopTextfileUpdateInterval
+}
+
 // Middleware for logging requests and filtering
 func loggingAndFilterMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		slog.Info("HTTP request", "method", r.Method, "url", r.URL.String(), "remote", r.RemoteAddr, "content-type", r.Header.Get("Content-Type"))
 
-		if r.Method != http.MethodGet {
+		allowedMethod := http.MethodGet
+		if r.URL.Path == reloadPath {
+			allowedMethod = http.MethodPost
+		}
+		if r.Method != allowedMethod {
 			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 			return
 		}
@@ -76,7 +76,7 @@ func metricsHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		}
 	}()
-	metricRegistries := collectMetrics()
+	metricRegistries := collectMetrics().all()
 	// The same as in node_exporter :shrug:
 	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8; escaping=underscores")
 	allMetrics := metricRegistries.GetAllMetricsText()
@@ -88,10 +88,32 @@ func metricsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Like in Prometheus, reload is only triggered by POST
+func reloadHandler(w http.ResponseWriter, r *http.Request) {
+	if *configFile == "" {
+		http.Error(w, "Config file is not set", http.StatusBadRequest)
+		return
+	}
+	err := reloadConfig()
+	if err != nil {
+		slog.Error("Cannot reload config file, keeping previous config", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, err = w.Write([]byte("Config file reloaded\n"))
+	if err != nil {
+		slog.Error("Failed to write response", "error", err)
+	}
+}
+
 func runHttpServerCommand() {
 	slog.Info("Starting HTTP server", "address", *httpListenAddress)
+	startLinkWatcher()
+	startDiscoveryCache()
+	startReloadOnSighup()
 	mux := http.NewServeMux()
 	mux.HandleFunc("/metrics", metricsHandler)
+	mux.HandleFunc(reloadPath, reloadHandler)
 	wrappedMux := loggingAndFilterMiddleware(mux)
 
 	rawListener, err := net.Listen("tcp", *httpListenAddress)
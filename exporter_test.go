@@ -1,19 +1,26 @@
 package main
 
 import (
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path"
 	"regexp"
 	"runtime/debug"
 	"testing"
 	"time"
 
+	"github.com/newrushbolt/go-ethtool-exporter/collector"
+	"github.com/newrushbolt/go-ethtool-exporter/events"
+	"github.com/newrushbolt/go-ethtool-exporter/interfaces"
 	"github.com/newrushbolt/go-ethtool-exporter/registry"
 
+	"github.com/alecthomas/kingpin/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func setBuildInfo(mainVersion, vcsRevision, vcsTime string) func() (*debug.BuildInfo, bool) {
@@ -49,6 +56,39 @@ func TestParseAllowedInterfaceTypes(t *testing.T) {
 	assert.Equal(t, []int{1, 2}, typesWithWord)
 }
 
+func TestParseCommaSeparatedList(t *testing.T) {
+	assert.Equal(t, []string{}, parseCommaSeparatedList(""))
+	assert.Equal(t, []string{}, parseCommaSeparatedList(",,"))
+	assert.Equal(t, []string{"up", "down"}, parseCommaSeparatedList(" up , down ,"))
+}
+
+func TestParseDeviceInfoLabels(t *testing.T) {
+	assert.Equal(t, []string{}, parseDeviceInfoLabels(""))
+	assert.Equal(t, []string{"pci_address", "driver"}, parseDeviceInfoLabels(" pci_address , driver ,"))
+	assert.Equal(t, []string{"driver"}, parseDeviceInfoLabels("fuu,driver"))
+}
+
+func TestPathsFromRoots(t *testing.T) {
+	sysfsPath = ptr("/host/sys")
+	procfsPath = ptr("/host/proc")
+	linuxNetClassPath = ptr("")
+	procNetBondingPath = ptr("")
+	ethtoolHostNetns = ptr(false)
+	ethtoolNetlink = ptr(false)
+	assert.Equal(t, "/host/sys/class/net", getNetClassPath())
+	assert.Equal(t, "/host/proc/net/bonding", getProcNetBondingPath())
+	assert.Equal(t, "", getEthtoolNetnsPath())
+
+	ethtoolHostNetns = ptr(true)
+	assert.Equal(t, "/host/proc/1/ns/net", getEthtoolNetnsPath())
+
+	// Specific paths win over roots
+	linuxNetClassPath = ptr("/custom/class/net")
+	procNetBondingPath = ptr("/custom/bonding")
+	assert.Equal(t, "/custom/class/net", getNetClassPath())
+	assert.Equal(t, "/custom/bonding", getProcNetBondingPath())
+}
+
 func TestExporterVersionAllFields(t *testing.T) {
 	expectedVersion := `go-ethtool-exporter version: v1.2.3
 vcs.revision: abc123
@@ -71,6 +111,7 @@ func TestExporterVersionBuildInfoError(t *testing.T) {
 
 func TestExporterWriteAllMetricsToTextfiles(t *testing.T) {
 	expectedMetrics := `dummy_metric{foo="bar"} 42`
+	setupHttpHandlerFlags(t)
 	dir := t.TempDir()
 	textfileDirectory = &dir // override global pointer for test
 
@@ -85,7 +126,7 @@ func TestExporterWriteAllMetricsToTextfiles(t *testing.T) {
 		"eth0": eth0Registry,
 	}
 
-	writeAllMetricsToTextfiles(registries)
+	writeAllMetricsToTextfiles(collectedMetrics{Interfaces: registries})
 
 	filePath := dir + "/ethtool_exporter.prom"
 	metrics, err := os.ReadFile(filePath)
@@ -109,12 +150,44 @@ func TestExporterDirectoryMustExist(t *testing.T) {
 
 func ptr[T any](v T) *T { return &v }
 
+// The same output testdata/ethtool.sh prints, for every netclass entry of test sysfs
+func newTestDataSource(t *testing.T) collector.StaticDataSource {
+	netClassEntries, err := os.ReadDir("testdata/interfaces/sys/class/net")
+	require.NoError(t, err)
+	eth4GenericInfo, err := os.ReadFile("testdata/eth4.generic_info.src")
+	require.NoError(t, err)
+	eth5GenericInfo, err := os.ReadFile("testdata/eth5.generic_info.src")
+	require.NoError(t, err)
+
+	dataSource := collector.StaticDataSource{}
+	for _, netClassEntry := range netClassEntries {
+		interfaceName := netClassEntry.Name()
+		dataSource[interfaceName] = testInterfaceData(interfaceName)
+	}
+	dataSource["eth4"]["generic_info"] = string(eth4GenericInfo)
+	dataSource["ens7f0v0"]["generic_info"] = string(eth4GenericInfo)
+	dataSource["eth5"]["generic_info"] = string(eth5GenericInfo)
+	return dataSource
+}
+
+func testInterfaceData(interfaceName string) map[string]string {
+	return map[string]string{
+		"driver_info":  "driver info for " + interfaceName + "\n",
+		"generic_info": "generic info for " + interfaceName + "\n",
+		"module_info":  "module info for " + interfaceName + "\n",
+		"statistics":   "statistics for " + interfaceName + "\n",
+	}
+}
+
 func setupHttpHandlerFlags(t *testing.T) {
 	// Set test-specific overrides
 	portsRegexp := regexp.MustCompile("eth4")
 	discoverPortsRegexp = &portsRegexp
+	// Only validated, ethtool output comes from memory
 	ethtoolPath = ptr("testdata/ethtool.sh")
-	linuxNetClassPath = ptr("testdata/interfaces/sys/class/net")
+	ethtoolDataSource = newTestDataSource(t)
+	t.Cleanup(func() { ethtoolDataSource = nil })
+	sysfsPath = ptr("testdata/interfaces/sys")
 	// Set default global params
 	absentMetricsDriverInfoExposeDetailedInfo = ptr(false)
 	absentMetricsDriverInfoExposeNan = ptr(false)
@@ -140,10 +213,41 @@ func setupHttpHandlerFlags(t *testing.T) {
 	discoverAllPorts = ptr(true)
 	discoverBondSlaves = ptr(false)
 	discoverBridgeSlaves = ptr(false)
+	discoverOvsSlaves = ptr(false)
+	discoverOvsSkipVirtual = ptr(true)
+	discoverPhysicalPorts = ptr(false)
+	discoverVirtualFunctions = ptr(false)
+	discoverRepresentors = ptr(false)
+	discoverSkipVfs = ptr(false)
+	discoverSkipRepresentors = ptr(false)
+	labelSriovTopology = ptr(false)
+	collectDeviceInfo = ptr(false)
+	labelDeviceInfo = ptr("")
+	collectNamedNetns = ptr(false)
+	collectProcessNetns = ptr(false)
+	procfsPath = ptr("/proc")
+	linuxNetClassPath = ptr("")
+	procNetBondingPath = ptr("")
+	ethtoolHostNetns = ptr(false)
+	replayDirectory = ptr("")
+	runNetnsPath = ptr("/run/netns")
+	discoverPortsExclude = ptr[*regexp.Regexp](nil)
+	discoverOperStates = ptr("")
+	discoverSkipOperStates = ptr("")
+	discoverRequireCarrier = ptr(false)
+	discoverDriversRegexp = ptr[*regexp.Regexp](nil)
+	discoverTeamSlaves = ptr(false)
+	discoverExpandLower = ptr(false)
 	ethtoolTimeout = ptr(time.Second * 5)
 	listLabelFormat = ptr("single-label")
 	loopTextfileUpdateInterval = ptr(time.Second)
 	textfileDirectory = ptr(t.TempDir())
+	textfileFormat = ptr("prometheus")
+	textfileName = ptr("ethtool_exporter.prom")
+	textfileMode = ptr("0644")
+	textfileFsync = ptr(false)
+	textfilePerCollector = ptr(false)
+	graphitePathTemplate = ptr("ethtool.{hostname}.{device}.{name}")
 }
 
 func TestExporterHttpMetricsHandler(t *testing.T) {
@@ -167,7 +271,8 @@ func TestExporterHttpMetricsHandler(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to read expected metrics: %v", err)
 	}
-	expectedMetricResult := string(expectedBytes)
+	// Exporter-wide metrics go first, as their registry key is empty
+	expectedMetricResult := "discovery_error{} 0\n" + string(expectedBytes)
 	assert.Equal(t, expectedMetricResult, string(body))
 }
 
@@ -199,7 +304,8 @@ func TestExporterHttpMetricsHandlerWithMissingMetrics(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to read expected metrics: %v", err)
 	}
-	expectedMetricResult := string(expectedBytes)
+	// Exporter-wide metrics go first, as their registry key is empty
+	expectedMetricResult := "discovery_error{} 0\n" + string(expectedBytes)
 	assert.Equal(t, expectedMetricResult, string(body))
 }
 
@@ -214,15 +320,15 @@ func TestExporterHttpMetricsHandlerFail(t *testing.T) {
 	resp := recorder.Result()
 	defer resp.Body.Close()
 
-	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
-	assert.Equal(t, "text/plain; charset=utf-8", resp.Header.Get("Content-Type"))
+	// Failed discovery doesn't fail the scrape, it's exposed as a metric
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		t.Fatalf("Failed to read response body: %v", err)
 	}
 
-	expectedMetricResult := "Internal Server Error\n"
+	expectedMetricResult := "discovery_error{} 1"
 	assert.Equal(t, expectedMetricResult, string(body))
 }
 
@@ -270,7 +376,7 @@ func TestExporterSingleTextfile(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to read expected metrics: %v", err)
 	}
-	expectedMetric := string(expectedMetricBytes)
+	expectedMetric := "discovery_error{} 0\n" + string(expectedMetricBytes)
 
 	resultedMetricsBytes, err := os.ReadFile(path.Join(*textfileDirectory, "ethtool_exporter.prom"))
 	if err != nil {
@@ -280,3 +386,320 @@ func TestExporterSingleTextfile(t *testing.T) {
 
 	assert.Equal(t, expectedMetric, resultedMetrics)
 }
+
+func TestExporterHttpMetricsHandlerWithLinkEvents(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	linkWatcher = events.NewWatcher(nil)
+	defer func() { linkWatcher = nil }()
+	linkWatcher.Record(events.LinkEvent{Device: "eth4", Type: events.LinkDown, Time: time.Unix(1700000000, 0)})
+	// Events of not discovered devices should not be exposed
+	linkWatcher.Record(events.LinkEvent{Device: "veth0", Type: events.LinkUp, Time: time.Unix(1700000000, 0)})
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	metricsHandler(recorder, req)
+	resp := recorder.Result()
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response body: %v", err)
+	}
+	assert.Contains(t, string(body), `link_events_total{device="eth4",event="link_down"} 1`)
+	assert.Contains(t, string(body), `link_events_last_timestamp_seconds{device="eth4",event="link_down"} 1.7e+09`)
+	assert.NotContains(t, string(body), "veth0")
+}
+
+func TestExporterCollectMetricsWithDiscoveryCache(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	discoveryCache = &interfaces.DiscoveryCache{
+		NetClassDirectory: getNetClassPath(),
+		Options:           createDiscoveryConfig(),
+		AllowedTypes:      parseAllowedInterfaceTypes(*discoverAllowedPortTypes),
+	}
+	defer func() { discoveryCache = nil }()
+
+	collectMetrics()
+	metrics := collectMetrics()
+
+	assert.Len(t, metrics.Interfaces, 1)
+	assert.Len(t, metrics.Interfaces["eth4"], 7)
+	assert.Equal(t, "discovery_error{} 0\ndiscovery_refreshes_total{} 2", metrics.Exporter.FormatTextfileString())
+	assert.Len(t, metrics.all(), 2)
+
+	discoveryCache.NetClassDirectory = "non_existent_testdata/interfaces/sys/class/net"
+	metrics = collectMetrics()
+	assert.Empty(t, metrics.Interfaces)
+	assert.Equal(t, "discovery_error{} 1\ndiscovery_refreshes_total{} 2", metrics.Exporter.FormatTextfileString())
+}
+
+func TestExporterCollectMetricsWithSriovLabels(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	portsRegexp := regexp.MustCompile("ens7f0v0|eth4")
+	discoverPortsRegexp = &portsRegexp
+	labelSriovTopology = ptr(true)
+
+	metricRegistries := collectMetrics().Interfaces
+
+	assert.Len(t, metricRegistries, 2)
+	assert.NotEmpty(t, metricRegistries["ens7f0v0"])
+	for _, metric := range metricRegistries["ens7f0v0"] {
+		assert.Equal(t, "vf", metric.Labels["sriov_role"], metric.Name)
+		assert.Equal(t, "ens7f0", metric.Labels["pf_device"], metric.Name)
+	}
+	for _, metric := range metricRegistries["eth4"] {
+		assert.NotContains(t, metric.Labels, "sriov_role", metric.Name)
+	}
+}
+
+func TestExporterCollectMetricsWithDeviceInfo(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	collectDeviceInfo = ptr(true)
+	labelDeviceInfo = ptr("pci_address")
+
+	metricRegistries := collectMetrics().Interfaces
+
+	deviceRegistry := metricRegistries["eth4"]
+	deviceInfoIndex, err := deviceRegistry.GetMetricIndex("device_info")
+	assert.NoError(t, err)
+	assert.Equal(t, "eth4", deviceRegistry[deviceInfoIndex].Labels["device"])
+	assert.Len(t, deviceRegistry[deviceInfoIndex].Labels, 9)
+	for _, metric := range deviceRegistry {
+		assert.Contains(t, metric.Labels, "pci_address", metric.Name)
+	}
+}
+
+func TestExporterCollectMetricsBondSlavesWithDeviceInfo(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	portsRegexp := regexp.MustCompile("^bond0$")
+	discoverPortsRegexp = &portsRegexp
+	collectBondInfo = ptr(true)
+	procNetBondingPath = ptr("testdata/proc/net/bonding")
+	collectDeviceInfo = ptr(true)
+	labelDeviceInfo = ptr("driver")
+
+	bondRegistry := collectMetrics().Interfaces["bond0"]
+	slaveMetrics := 0
+	for _, metric := range bondRegistry {
+		if metric.Labels["device"] == "bond0" {
+			assert.Contains(t, metric.Labels, "driver", metric.Name)
+			continue
+		}
+		slaveMetrics++
+		assert.Equal(t, "bond0", metric.Labels["master"], metric.Name)
+		assert.NotContains(t, metric.Labels, "driver", metric.Name)
+	}
+	assert.NotZero(t, slaveMetrics)
+}
+
+func TestExporterCollectMetricsInNetns(t *testing.T) {
+	err := exec.Command("unshare", "--net", "true").Run()
+	if err != nil {
+		t.Skipf("Cannot create network namespace: %v", err)
+	}
+	unshareCommand := exec.Command("unshare", "--net", "sleep", "30")
+	assert.NoError(t, unshareCommand.Start())
+	t.Cleanup(func() {
+		unshareCommand.Process.Kill()
+		unshareCommand.Wait()
+	})
+	// Wait for unshare to exec sleep in the new namespace
+	namespacePath := fmt.Sprintf("/proc/%d/ns/net", unshareCommand.Process.Pid)
+	assert.Eventually(t, func() bool {
+		hostNamespace, _ := os.Readlink("/proc/self/ns/net")
+		processNamespace, _ := os.Readlink(namespacePath)
+		return processNamespace != "" && processNamespace != hostNamespace
+	}, time.Second, 10*time.Millisecond)
+
+	setupHttpHandlerFlags(t)
+	runNetnsPath = ptr(t.TempDir())
+	assert.NoError(t, os.Symlink(namespacePath, path.Join(*runNetnsPath, "test")))
+	collectNamedNetns = ptr(true)
+	discoverAllowedPortTypes = ptr("")
+	portsRegexp := regexp.MustCompile("lo|eth4")
+	discoverPortsRegexp = &portsRegexp
+	ethtoolDataSource.(collector.StaticDataSource)["lo"] = testInterfaceData("lo")
+
+	metricRegistries := collectMetrics().Interfaces
+	if _, found := metricRegistries["test/lo"]; !found {
+		t.Skip("Cannot enter network namespace, check logs for details")
+	}
+
+	assert.Len(t, metricRegistries, 2)
+	assert.NotEmpty(t, metricRegistries["test/lo"])
+	for _, metric := range metricRegistries["test/lo"] {
+		assert.Equal(t, "test", metric.Labels["netns"], metric.Name)
+		assert.Equal(t, "lo", metric.Labels["device"], metric.Name)
+	}
+	for _, metric := range metricRegistries["eth4"] {
+		assert.NotContains(t, metric.Labels, "netns", metric.Name)
+	}
+}
+
+func TestExporterCreateDataSource(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	ethtoolDataSource = nil
+	assert.IsType(t, collector.ExecDataSource{}, createDataSource())
+
+	ethtoolNetlink = ptr(true)
+	dataSource := createDataSource()
+	assert.IsType(t, collector.NetlinkDataSource{}, dataSource)
+	assert.Equal(t, collector.ExecDataSource{Path: *ethtoolPath, Timeout: *ethtoolTimeout}, dataSource.(collector.NetlinkDataSource).Fallback)
+
+	replayDirectory = ptr("testdata/replay")
+	assert.IsType(t, collector.ReplayDataSource{}, createDataSource())
+}
+
+func TestExporterReplay(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	liveRegistries := collectMetrics().Interfaces
+	liveMetrics := liveRegistries.GetAllMetricsText()
+
+	replayDirectory = ptr("testdata/replay")
+	ethtoolPath = ptr("non_existent_ethtool")
+	assert.NoError(t, validateEthtoolPath(kingpin.CommandLine))
+	assert.Equal(t, "testdata/replay/sysfs/class/net", getNetClassPath())
+	assert.Equal(t, "testdata/replay/procfs/net/bonding", getProcNetBondingPath())
+	replayedRegistries := collectMetrics().Interfaces
+	replayedMetrics := replayedRegistries.GetAllMetricsText()
+	assert.Contains(t, replayedMetrics, `generic_info_settings_speed_bits{device="eth4"} 1e+10`)
+	assert.Equal(t, liveMetrics, replayedMetrics)
+
+	replayDirectory = ptr("non_existent_replay")
+	assert.Error(t, validateEthtoolPath(kingpin.CommandLine))
+	replayDirectory = ptr("")
+	assert.Error(t, validateEthtoolPath(kingpin.CommandLine))
+	ethtoolPath = ptr("testdata/ethtool.sh")
+	assert.NoError(t, validateEthtoolPath(kingpin.CommandLine))
+}
+
+func TestExporterWriteAllMetricsToTextfilesInflux(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	textfileFormat = ptr("influx")
+	registries := collectedMetrics{Interfaces: registry.RegistryCollection{
+		"eth0": {{Name: "statistics_rx_packets", Value: 42, Labels: map[string]string{"device": "eth0"}}},
+	}}
+
+	writeAllMetricsToTextfiles(registries)
+	metrics, err := os.ReadFile(path.Join(*textfileDirectory, "ethtool_exporter.prom"))
+	require.NoError(t, err)
+	assert.Regexp(t, `^ethtool,collector=statistics,device=eth0 rx_packets=42 \d+\n$`, string(metrics))
+}
+
+func TestExporterWriteAllMetricsToTextfilesOptions(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	textfileName = ptr("ethtool.prom")
+	textfileMode = ptr("0640")
+	textfileFsync = ptr(true)
+	registries := collectedMetrics{Interfaces: registry.RegistryCollection{
+		"eth0": {{Name: "dummy_metric", Value: 42, Labels: map[string]string{"foo": "bar"}}},
+	}}
+
+	writeAllMetricsToTextfiles(registries)
+	fileInfo, err := os.Stat(path.Join(*textfileDirectory, "ethtool.prom"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), fileInfo.Mode().Perm())
+}
+
+func TestExporterUpdateTextfilesPerCollector(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	textfilePerCollector = ptr(true)
+	collectDriverInfoCommon = ptr(true)
+	collectModuleInfoVendor = ptr(true)
+	dataSource := ethtoolDataSource.(collector.StaticDataSource)
+	dataSource["eth4"]["driver_info"] = "driver: ixgbe\n"
+	// Module of the only port cannot be read
+	delete(dataSource["eth4"], "module_info")
+	// Left from single textfile mode
+	require.NoError(t, os.WriteFile(path.Join(*textfileDirectory, "ethtool_exporter.prom"), []byte("stale"), 0o644))
+	require.NoError(t, os.WriteFile(path.Join(*textfileDirectory, "ethtool_exporter_module_info.prom"), []byte("previous"), 0o644))
+	// Directory in place of the textfile makes its rename fail
+	require.NoError(t, os.Mkdir(path.Join(*textfileDirectory, "ethtool_exporter_bond_info.prom"), 0o755))
+
+	updateTextfilesLocked()
+	textfiles := map[string]string{}
+	entries, err := os.ReadDir(*textfileDirectory)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		content, err := os.ReadFile(path.Join(*textfileDirectory, entry.Name()))
+		require.NoError(t, err)
+		textfiles[entry.Name()] = string(content)
+	}
+	assert.NotContains(t, textfiles, "ethtool_exporter.prom")
+	assert.Equal(t, "previous", textfiles["ethtool_exporter_module_info.prom"])
+	assert.Contains(t, textfiles["ethtool_exporter_driver_info.prom"], `driver_info_common_info{`)
+	assert.NotContains(t, textfiles["ethtool_exporter_driver_info.prom"], "generic_info_")
+	assert.Contains(t, textfiles["ethtool_exporter_generic_info.prom"], `generic_info_settings_speed_bits{device="eth4"} 1e+10`)
+	assert.NotContains(t, textfiles["ethtool_exporter_generic_info.prom"], "driver_info_")
+	// Failed textfiles don't block the others, disabled collectors are written empty
+	assert.NotContains(t, textfiles, "ethtool_exporter_bond_info.prom")
+	assert.Contains(t, textfiles, "ethtool_exporter_statistics.prom")
+	assert.Equal(t, "", textfiles["ethtool_exporter_statistics.prom"])
+
+	// Module is readable again
+	dataSource["eth4"]["module_info"] = "module info for eth4\n"
+	updateTextfilesLocked()
+	content, err := os.ReadFile(path.Join(*textfileDirectory, "ethtool_exporter_module_info.prom"))
+	require.NoError(t, err)
+	assert.NotEqual(t, "previous", string(content))
+}
+
+// Counts reads by `<interface>/<collector>`
+type countingDataSource struct {
+	collector.DataSource
+	reads map[string]int
+}
+
+func (source countingDataSource) ReadEthtoolData(interfaceName string, collectorName string) (string, error) {
+	source.reads[interfaceName+"/"+collectorName]++
+	return source.DataSource.ReadEthtoolData(interfaceName, collectorName)
+}
+
+func TestExporterWriteTextfilesPerCollectorExporterMetrics(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	textfilePerCollector = ptr(true)
+	dataSource := countingDataSource{DataSource: ethtoolDataSource, reads: map[string]int{}}
+	ethtoolDataSource = dataSource
+	discoveryCache = &interfaces.DiscoveryCache{
+		NetClassDirectory: getNetClassPath(),
+		Options:           createDiscoveryConfig(),
+		AllowedTypes:      parseAllowedInterfaceTypes(*discoverAllowedPortTypes),
+	}
+	t.Cleanup(func() { discoveryCache = nil })
+
+	updateTextfilesLocked()
+	content, err := os.ReadFile(path.Join(*textfileDirectory, "ethtool_exporter_exporter.prom"))
+	require.NoError(t, err)
+	// Discovery and ethtool are only run once for all collectors
+	assert.Equal(t, "discovery_error{} 0\ndiscovery_refreshes_total{} 1", string(content))
+	assert.Equal(t, map[string]int{"eth4/generic_info": 1}, dataSource.reads)
+}
+
+func TestValidateNetClassPath(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	assert.NoError(t, validateNetClassPath(kingpin.CommandLine))
+	sysfsPath = ptr("testdata/non_existent_sys")
+	assert.Error(t, validateNetClassPath(kingpin.CommandLine))
+	linuxNetClassPath = ptr("testdata/interfaces/sys/class/net")
+	assert.NoError(t, validateNetClassPath(kingpin.CommandLine))
+	linuxNetClassPath = ptr("testdata/interfaces/sys/class/net/eth0/type")
+	assert.Error(t, validateNetClassPath(kingpin.CommandLine))
+}
+
+func TestValidateTextfileFlags(t *testing.T) {
+	setupHttpHandlerFlags(t)
+	assert.NoError(t, validateTextfileFlags(kingpin.CommandLine))
+	textfileMode = ptr("0o644")
+	assert.Error(t, validateTextfileFlags(kingpin.CommandLine))
+	textfileMode = ptr("1777")
+	assert.Error(t, validateTextfileFlags(kingpin.CommandLine))
+	textfileMode = ptr("600")
+	assert.NoError(t, validateTextfileFlags(kingpin.CommandLine))
+	textfileName = ptr("../ethtool.prom")
+	assert.Error(t, validateTextfileFlags(kingpin.CommandLine))
+	textfileName = ptr("")
+	assert.Error(t, validateTextfileFlags(kingpin.CommandLine))
+}
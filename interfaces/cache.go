@@ -0,0 +1,121 @@
+package interfaces
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// DiscoveryCache keeps the list of discovered interfaces until link changes are reported.
+// Without a running watcher (see Watch) every call rescans the netclass directory.
+// Watcher is restarted when SetOptions changes the netclass directory.
+type DiscoveryCache struct {
+	NetClassDirectory string
+	Options           PortDiscoveryOptions
+	AllowedTypes      []int
+
+	mutex      sync.Mutex
+	interfaces []string
+	valid      bool
+	// Incremented on every invalidation, so changes during rescan are not lost
+	generation atomic.Uint64
+	refreshes  atomic.Uint64
+	// ID of the running watcher, zero if cache is not watched
+	activeWatch atomic.Uint64
+
+	watchMutex   sync.Mutex
+	watchContext context.Context
+	stopWatch    context.CancelFunc
+	lastWatchId  uint64
+}
+
+func (cache *DiscoveryCache) GetInterfacesList() ([]string, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cache.valid && cache.activeWatch.Load() != 0 {
+		return slices.Clone(cache.interfaces), nil
+	}
+
+	generation := cache.generation.Load()
+	discoveredInterfaces, err := ListInterfaces(cache.NetClassDirectory, cache.Options, cache.AllowedTypes)
+	if err != nil {
+		cache.valid = false
+		return nil, err
+	}
+	cache.refreshes.Add(1)
+	cache.interfaces = discoveredInterfaces
+	cache.valid = cache.generation.Load() == generation
+	slog.Debug("Refreshed interface discovery cache", "interfaces", discoveredInterfaces)
+	return slices.Clone(discoveredInterfaces), nil
+}
+
+func (cache *DiscoveryCache) Invalidate() {
+	cache.generation.Add(1)
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.valid = false
+}
+
+// SetOptions replaces discovery settings, eg after config reload. Ports are rediscovered on the next call.
+func (cache *DiscoveryCache) SetOptions(netClassDirectory string, options PortDiscoveryOptions, allowedTypes []int) {
+	cache.generation.Add(1)
+	cache.mutex.Lock()
+	directoryChanged := cache.NetClassDirectory != netClassDirectory
+	cache.NetClassDirectory = netClassDirectory
+	cache.Options = options
+	cache.AllowedTypes = allowedTypes
+	cache.valid = false
+	cache.mutex.Unlock()
+
+	if !directoryChanged {
+		return
+	}
+	cache.watchMutex.Lock()
+	defer cache.watchMutex.Unlock()
+	if cache.watchContext == nil {
+		return
+	}
+	err := cache.restartWatch(netClassDirectory)
+	if err != nil {
+		slog.Warn("Cannot watch link changes in new netclass directory, ports will be rediscovered on every collection", "netClassDirectory", netClassDirectory, "error", err)
+	}
+}
+
+// Watch invalidates the cache on link changes, see watchLinkChanges.
+// Cache is only used while the watcher is running.
+func (cache *DiscoveryCache) Watch(ctx context.Context) error {
+	cache.mutex.Lock()
+	netClassDirectory := cache.NetClassDirectory
+	cache.mutex.Unlock()
+
+	cache.watchMutex.Lock()
+	defer cache.watchMutex.Unlock()
+	cache.watchContext = ctx
+	return cache.restartWatch(netClassDirectory)
+}
+
+// Stops the previous watcher, if any. Must be called with watchMutex held.
+func (cache *DiscoveryCache) restartWatch(netClassDirectory string) error {
+	if cache.stopWatch != nil {
+		cache.stopWatch()
+		cache.stopWatch = nil
+		cache.activeWatch.Store(0)
+	}
+	watchContext, stopWatch := context.WithCancel(cache.watchContext)
+	cache.lastWatchId++
+	err := cache.watchLinkChanges(watchContext, netClassDirectory, cache.lastWatchId)
+	if err != nil {
+		stopWatch()
+		return err
+	}
+	cache.stopWatch = stopWatch
+	return nil
+}
+
+// Refreshes returns how many times the netclass directory was actually rescanned
+func (cache *DiscoveryCache) Refreshes() uint64 {
+	return cache.refreshes.Load()
+}
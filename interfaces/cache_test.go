@@ -0,0 +1,153 @@
+package interfaces
+
+import (
+	"context"
+	"os"
+	"path"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscoveryCacheNotWatching(t *testing.T) {
+	cache := &DiscoveryCache{
+		NetClassDirectory: defaultNetClassPath,
+		Options: PortDiscoveryOptions{
+			PortsRegexp:        regexp.MustCompile(".+"),
+			DiscoverBondSlaves: true,
+		},
+		AllowedTypes: []int{1},
+	}
+
+	for range 2 {
+		interfaces, err := cache.GetInterfacesList()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"eth0"}, interfaces)
+	}
+	assert.Equal(t, uint64(2), cache.Refreshes())
+}
+
+func TestDiscoveryCacheInvalidate(t *testing.T) {
+	cache := &DiscoveryCache{
+		NetClassDirectory: defaultNetClassPath,
+		Options: PortDiscoveryOptions{
+			PortsRegexp:      regexp.MustCompile("eth[0-1]"),
+			DiscoverAllPorts: true,
+		},
+	}
+	cache.activeWatch.Store(1)
+
+	for range 2 {
+		interfaces, err := cache.GetInterfacesList()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"eth0", "eth1"}, interfaces)
+	}
+	assert.Equal(t, uint64(1), cache.Refreshes())
+
+	cache.Invalidate()
+	_, err := cache.GetInterfacesList()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), cache.Refreshes())
+}
+
+func TestDiscoveryCacheSetOptions(t *testing.T) {
+	cache := &DiscoveryCache{
+		NetClassDirectory: defaultNetClassPath,
+		Options: PortDiscoveryOptions{
+			PortsRegexp:      regexp.MustCompile("eth[0-1]"),
+			DiscoverAllPorts: true,
+		},
+	}
+	cache.activeWatch.Store(1)
+	interfaces, err := cache.GetInterfacesList()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"eth0", "eth1"}, interfaces)
+
+	cache.SetOptions(defaultNetClassPath, PortDiscoveryOptions{
+		PortsRegexp:      regexp.MustCompile("eth4"),
+		DiscoverAllPorts: true,
+	}, nil)
+	interfaces, err = cache.GetInterfacesList()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"eth4"}, interfaces)
+	assert.Equal(t, uint64(2), cache.Refreshes())
+}
+
+func TestDiscoveryCacheBrokenPath(t *testing.T) {
+	cache := &DiscoveryCache{
+		NetClassDirectory: "../testdata/interfaces/sys/class/net2",
+		Options: PortDiscoveryOptions{
+			PortsRegexp:      regexp.MustCompile(".+"),
+			DiscoverAllPorts: true,
+		},
+	}
+	_, err := cache.GetInterfacesList()
+	assert.Error(t, err)
+	assert.Equal(t, uint64(0), cache.Refreshes())
+}
+
+func TestDiscoveryCacheWatch(t *testing.T) {
+	netClassDirectory := t.TempDir()
+	cache := &DiscoveryCache{
+		NetClassDirectory: netClassDirectory,
+		Options: PortDiscoveryOptions{
+			PortsRegexp:      regexp.MustCompile(".+"),
+			DiscoverAllPorts: true,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := cache.Watch(ctx)
+	if err != nil {
+		t.Skipf("Cannot watch link changes: %v", err)
+	}
+
+	interfaces, err := cache.GetInterfacesList()
+	assert.NoError(t, err)
+	assert.Empty(t, interfaces)
+
+	// Netlink watcher would not notice it, so invalidate manually in that case
+	assert.NoError(t, os.Mkdir(path.Join(netClassDirectory, "eth0"), 0755))
+	cache.Invalidate()
+	assert.Eventually(t, func() bool {
+		interfaces, err := cache.GetInterfacesList()
+		return err == nil && len(interfaces) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, uint64(2), cache.Refreshes())
+}
+
+func TestDiscoveryCacheWatchNewDirectory(t *testing.T) {
+	cache := &DiscoveryCache{
+		NetClassDirectory: t.TempDir(),
+		Options: PortDiscoveryOptions{
+			PortsRegexp:      regexp.MustCompile(".+"),
+			DiscoverAllPorts: true,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := cache.Watch(ctx)
+	if err != nil {
+		t.Skipf("Cannot watch link changes: %v", err)
+	}
+	firstWatch := cache.activeWatch.Load()
+	assert.NotZero(t, firstWatch)
+
+	cache.SetOptions(cache.NetClassDirectory, cache.Options, nil)
+	assert.Equal(t, firstWatch, cache.activeWatch.Load(), "watcher is kept for the same directory")
+
+	cache.SetOptions(defaultNetClassPath, cache.Options, []int{1})
+	secondWatch := cache.activeWatch.Load()
+	assert.NotZero(t, secondWatch)
+	assert.NotEqual(t, firstWatch, secondWatch)
+	// Stopped watcher exits after poll timeout, it must not disable the new one
+	time.Sleep(1100 * time.Millisecond)
+	assert.Equal(t, secondWatch, cache.activeWatch.Load())
+	for range 2 {
+		_, err = cache.GetInterfacesList()
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, uint64(1), cache.Refreshes())
+}
@@ -0,0 +1,98 @@
+package interfaces
+
+import "slices"
+
+// Filter names, as shown by `discover-ports` command
+const (
+	filterReserved             = "reserved"
+	filterNotDevice            = "not-a-device"
+	filterRegexp               = "regexp"
+	filterExcludeRegexp        = "exclude-regexp"
+	filterType                 = "type"
+	filterOperState            = "operstate"
+	filterCarrier              = "carrier"
+	filterDriver               = "driver"
+	filterSkipVirtualFunctions = "skip-virtual-functions"
+	filterSkipRepresentors     = "skip-representors"
+	filterAllPorts             = "all-ports"
+	filterBondSlaves           = "bond-slaves"
+	filterBondMasters          = "bond-masters"
+	filterTeamSlaves           = "team-slaves"
+	filterBridgeSlaves         = "bridge-slaves"
+	filterPhysicalPorts        = "physical-ports"
+	filterVirtualFunctions     = "virtual-functions"
+	filterRepresentors         = "representors"
+	filterOvsSlaves            = "ovs-slaves"
+	filterNoDiscovery          = "discovery"
+	filterLowerDevices         = "lower-devices"
+)
+
+// FilterResult is a single step of the discovery decision, Reason is only set for failed filters
+type FilterResult struct {
+	Filter string `json:"filter"`
+	Passed bool   `json:"passed"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type PortDecision struct {
+	Name     string         `json:"name"`
+	Accepted bool           `json:"accepted"`
+	Chain    []FilterResult `json:"chain"`
+	// Lowest devices, that replace accepted port, eg physical members of VLAN over bond
+	ExpandedTo []string `json:"expanded_to,omitempty"`
+}
+
+func (decision *PortDecision) check(filter string, passed bool, reason string) bool {
+	result := FilterResult{Filter: filter, Passed: passed}
+	if !passed {
+		result.Reason = reason
+	}
+	decision.Chain = append(decision.Chain, result)
+	return passed
+}
+
+// PassedFilters returns discovery filters that accepted the port
+func (decision PortDecision) PassedFilters() []string {
+	passedFilters := []string{}
+	for _, result := range decision.Chain {
+		if result.Passed && isDiscoveryFilter(result.Filter) {
+			passedFilters = append(passedFilters, result.Filter)
+		}
+	}
+	return passedFilters
+}
+
+// RejectedBy returns the last failed filter, it is the one that rejected the port
+func (decision PortDecision) RejectedBy() FilterResult {
+	for index := len(decision.Chain) - 1; index >= 0; index-- {
+		if !decision.Chain[index].Passed {
+			return decision.Chain[index]
+		}
+	}
+	return FilterResult{}
+}
+
+func isDiscoveryFilter(filter string) bool {
+	switch filter {
+	case filterAllPorts, filterBondSlaves, filterBondMasters, filterTeamSlaves, filterBridgeSlaves, filterPhysicalPorts, filterVirtualFunctions, filterRepresentors, filterOvsSlaves:
+		return true
+	}
+	return false
+}
+
+// AcceptedPorts returns sorted names of accepted ports, expanded ones are replaced by their lower devices
+func AcceptedPorts(decisions []PortDecision) []string {
+	acceptedPorts := []string{}
+	for _, decision := range decisions {
+		switch {
+		case !decision.Accepted:
+			continue
+		case len(decision.ExpandedTo) > 0:
+			acceptedPorts = append(acceptedPorts, decision.ExpandedTo...)
+		default:
+			acceptedPorts = append(acceptedPorts, decision.Name)
+		}
+	}
+	slices.Sort(acceptedPorts)
+	return slices.Compact(acceptedPorts)
+}
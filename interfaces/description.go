@@ -0,0 +1,57 @@
+package interfaces
+
+import (
+	"os"
+	"path"
+	"strconv"
+)
+
+// Kinds of port masters
+const (
+	MasterKindBond   = "bond"
+	MasterKindTeam   = "team"
+	MasterKindBridge = "bridge"
+	MasterKindOvs    = "ovs"
+)
+
+// PortDescription is sysfs info, that is useful to understand discovery decision.
+// Missing attributes are left empty, Type is -1 if it cannot be read.
+type PortDescription struct {
+	Type       int    `json:"type"`
+	Master     string `json:"master,omitempty"`
+	MasterKind string `json:"master_kind,omitempty"`
+	Driver     string `json:"driver,omitempty"`
+	OperState  string `json:"operstate,omitempty"`
+}
+
+func DescribePort(netClassDirectory string, deviceName string) PortDescription {
+	devicePath := path.Join(netClassDirectory, deviceName)
+	if deviceStat, err := os.Stat(devicePath); err != nil || !deviceStat.IsDir() {
+		return PortDescription{Type: -1}
+	}
+	description := PortDescription{
+		Type:      -1,
+		Master:    readSysfsLinkName(path.Join(devicePath, "master")),
+		Driver:    readDriverName(devicePath),
+		OperState: readOperState(devicePath),
+	}
+	interfaceType, err := strconv.Atoi(readSysfsAttribute(path.Join(devicePath, "type")))
+	if err == nil {
+		description.Type = interfaceType
+	}
+	switch {
+	case isInterfaceBondSlave(devicePath):
+		description.MasterKind = MasterKindBond
+	case isInterfaceTeamSlave(devicePath):
+		description.MasterKind = MasterKindTeam
+	case isInterfaceBridgeSlave(devicePath):
+		description.MasterKind = MasterKindBridge
+		// Bridge ports always have `brport/bridge` link, `master` may be missing in old kernels
+		if description.Master == "" {
+			description.Master = readSysfsLinkName(path.Join(devicePath, "brport/bridge"))
+		}
+	case isInterfaceOvsSlave(devicePath):
+		description.MasterKind = MasterKindOvs
+	}
+	return description
+}
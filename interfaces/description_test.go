@@ -0,0 +1,24 @@
+package interfaces
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribePort(t *testing.T) {
+	testCases := map[string]PortDescription{
+		"eth0":            {Type: 1, Master: "bond0", MasterKind: MasterKindBond, OperState: "up"},
+		"slave0":          {Type: 1, Master: "br0", MasterKind: MasterKindBridge, OperState: "notpresent"},
+		"eth6":            {Type: 1, Master: "ovs-system", MasterKind: MasterKindOvs, Driver: "ixgbe", OperState: "up"},
+		"eth7":            {Type: 1, Master: "team0", MasterKind: MasterKindTeam},
+		"eth5":            {Type: 666},
+		"unreadable_file": {Type: -1},
+		"absent0":         {Type: -1},
+	}
+	for deviceName, expectedDescription := range testCases {
+		t.Run(deviceName, func(t *testing.T) {
+			assert.Equal(t, expectedDescription, DescribePort(defaultNetClassPath, deviceName))
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package interfaces
+
+import (
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	DeviceInfoMetricName = "device_info"
+	pciSubsystemName     = "pci"
+	// NET_ADDR_PERM from netdevice.h, address was not changed since device was created
+	addrAssignTypePermanent = "0"
+)
+
+// DeviceInfo identifies the hardware behind the netdev, so it survives interface renames.
+// Missing attributes are left empty.
+type DeviceInfo struct {
+	PciAddress   string
+	PciVendorId  string
+	PciDeviceId  string
+	NumaNode     string
+	PermanentMac string
+	PhysPortId   string
+	Driver       string
+	Alias        string
+}
+
+// Label names, also accepted by DeviceInfo.SomeLabels
+var DeviceInfoLabelNames = []string{
+	"pci_address",
+	"pci_vendor_id",
+	"pci_device_id",
+	"numa_node",
+	"permanent_mac",
+	"phys_port_id",
+	"driver",
+	"ifalias",
+}
+
+func readSysfsAttribute(attributePath string) string {
+	attributeRaw, err := os.ReadFile(attributePath)
+	if err != nil {
+		// Lots of attributes are optional or return EOPNOTSUPP, eg `phys_port_id`
+		slog.Debug("Cannot read sysfs attribute", "attributePath", attributePath, "error", err)
+		return ""
+	}
+	return strings.TrimSpace(string(attributeRaw))
+}
+
+func readSysfsLinkName(linkPath string) string {
+	linkTarget, err := os.Readlink(linkPath)
+	if err != nil {
+		slog.Debug("Cannot read sysfs link", "linkPath", linkPath, "error", err)
+		return ""
+	}
+	return path.Base(linkTarget)
+}
+
+// Bonding rewrites addresses of its slaves, but keeps the permanent one in `bonding_slave/perm_hwaddr`.
+// Otherwise sysfs only exposes current address, so it is treated as permanent unless it was changed,
+// eg by `ip link set address`
+func readPermanentMac(devicePath string) string {
+	bondSlaveMac := readSysfsAttribute(path.Join(devicePath, "bonding_slave", "perm_hwaddr"))
+	if bondSlaveMac != "" {
+		return bondSlaveMac
+	}
+	if readSysfsAttribute(path.Join(devicePath, "addr_assign_type")) != addrAssignTypePermanent {
+		return ""
+	}
+	return readSysfsAttribute(path.Join(devicePath, "address"))
+}
+
+func GetDeviceInfo(netClassDirectory string, deviceName string) DeviceInfo {
+	devicePath := path.Join(netClassDirectory, deviceName)
+	busDevicePath := path.Join(devicePath, "device")
+	deviceInfo := DeviceInfo{
+		NumaNode:     readSysfsAttribute(path.Join(busDevicePath, "numa_node")),
+		PermanentMac: readPermanentMac(devicePath),
+		PhysPortId:   readSysfsAttribute(path.Join(devicePath, "phys_port_id")),
+		Driver:       readSysfsLinkName(path.Join(busDevicePath, "driver")),
+		Alias:        readSysfsAttribute(path.Join(devicePath, "ifalias")),
+	}
+	if readSysfsLinkName(path.Join(busDevicePath, "subsystem")) == pciSubsystemName {
+		realBusDevicePath, err := filepath.EvalSymlinks(busDevicePath)
+		if err != nil {
+			slog.Debug("Device link cannot be resolved", "devicePath", devicePath, "error", err)
+		} else {
+			deviceInfo.PciAddress = path.Base(realBusDevicePath)
+		}
+		deviceInfo.PciVendorId = readSysfsAttribute(path.Join(busDevicePath, "vendor"))
+		deviceInfo.PciDeviceId = readSysfsAttribute(path.Join(busDevicePath, "device"))
+	}
+	return deviceInfo
+}
+
+// Labels returns all the attributes, including empty ones, so the set of labels is stable
+func (info DeviceInfo) Labels() map[string]string {
+	return map[string]string{
+		"pci_address":   info.PciAddress,
+		"pci_vendor_id": info.PciVendorId,
+		"pci_device_id": info.PciDeviceId,
+		"numa_node":     info.NumaNode,
+		"permanent_mac": info.PermanentMac,
+		"phys_port_id":  info.PhysPortId,
+		"driver":        info.Driver,
+		"ifalias":       info.Alias,
+	}
+}
+
+// SomeLabels returns only requested labels, unknown names are ignored
+func (info DeviceInfo) SomeLabels(labelNames []string) map[string]string {
+	allLabels := info.Labels()
+	someLabels := map[string]string{}
+	for _, labelName := range labelNames {
+		if labelValue, found := allLabels[labelName]; found {
+			someLabels[labelName] = labelValue
+		}
+	}
+	return someLabels
+}
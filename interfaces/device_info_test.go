@@ -0,0 +1,46 @@
+package interfaces
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDeviceInfoPci(t *testing.T) {
+	expectedInfo := DeviceInfo{
+		PciAddress:   "0000:00:06.0",
+		PciVendorId:  "0x8086",
+		PciDeviceId:  "0x10fb",
+		NumaNode:     "0",
+		PermanentMac: "90:e2:ba:11:22:33",
+		PhysPortId:   "90e2ba112233",
+		Driver:       "ixgbe",
+		Alias:        "uplink to tor1 xe-0/0/1",
+	}
+	assert.Equal(t, expectedInfo, GetDeviceInfo(defaultNetClassPath, "eth6"))
+}
+
+func TestGetDeviceInfoBondSlave(t *testing.T) {
+	// Address is set by bonding, permanent one is only known to bonding
+	assert.Equal(t, "90:e2:ba:00:00:01", GetDeviceInfo(defaultNetClassPath, "eth0").PermanentMac)
+	assert.Equal(t, "", readPermanentMac(defaultNetClassPath+"/eth1"))
+}
+
+func TestGetDeviceInfoVirtual(t *testing.T) {
+	assert.Equal(t, DeviceInfo{}, GetDeviceInfo(defaultNetClassPath, "dummy0"))
+	assert.Equal(t, DeviceInfo{}, GetDeviceInfo(defaultNetClassPath, "absent0"))
+}
+
+func TestDeviceInfoLabels(t *testing.T) {
+	deviceInfo := DeviceInfo{PciAddress: "0000:00:06.0", Driver: "ixgbe"}
+
+	allLabels := deviceInfo.Labels()
+	assert.Len(t, allLabels, len(DeviceInfoLabelNames))
+	for _, labelName := range DeviceInfoLabelNames {
+		assert.Contains(t, allLabels, labelName)
+	}
+
+	someLabels := deviceInfo.SomeLabels([]string{"pci_address", "driver", "unknown"})
+	assert.Equal(t, map[string]string{"pci_address": "0000:00:06.0", "driver": "ixgbe"}, someLabels)
+	assert.Empty(t, deviceInfo.SomeLabels(nil))
+}
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"os"
 	"path"
+	"path/filepath"
 	"regexp"
 	"slices"
 	"strconv"
@@ -15,8 +16,55 @@ type PortDiscoveryOptions struct {
 	PortsRegexp          *regexp.Regexp
 	DiscoverAllPorts     bool
 	DiscoverBondSlaves   bool
+	DiscoverBondMasters  bool
 	DiscoverBridgeSlaves bool
-	// TODO: filter out ovs ports by reading link, eg `/sys/class/net/tap2473528a-b1/master -> ../ovs-system`
+	DiscoverOvsSlaves    bool
+	// Skip OVS ports that are not backed by a hardware device: internal, tap, tunnel ports, etc
+	OvsSkipVirtualPorts bool
+	// Physical ports are discovered regardless of bond or bridge membership
+	DiscoverPhysicalPorts bool
+	// Mandatory filters, port is never discovered if any of them fails
+	PortsExcludeRegexp *regexp.Regexp
+	// Empty lists allow all states, see `operstate` in sysfs-class-net
+	AllowedOperStates []string
+	SkipOperStates    []string
+	RequireCarrier    bool
+	// Driver name is empty for devices without driver, eg virtual ones
+	DriversRegexp      *regexp.Regexp
+	DiscoverTeamSlaves bool
+	// Replace discovered VLAN, team, macvlan, etc ports with their lowest devices, see lower.go.
+	// Bond masters discovered as such are kept.
+	ExpandLowerDevices bool
+	// SR-IOV virtual functions and switchdev representors, see sriov.go
+	DiscoverVirtualFunctions bool
+	DiscoverRepresentors     bool
+	// Skipped ports are never discovered, even with DiscoverAllPorts
+	SkipVirtualFunctions bool
+	SkipRepresentors     bool
+}
+
+const (
+	ovsMasterName      = "ovs-system"
+	virtualDevicesPath = "/devices/virtual/"
+	teamDevtype        = "DEVTYPE=team"
+)
+
+func readOperState(devicePath string) string {
+	return readSysfsAttribute(path.Join(devicePath, "operstate"))
+}
+
+// Reading carrier of administratively down device returns EINVAL, so it is treated as no carrier
+func hasInterfaceCarrier(devicePath string) bool {
+	return readSysfsAttribute(path.Join(devicePath, "carrier")) == "1"
+}
+
+func readDriverName(devicePath string) string {
+	return readSysfsLinkName(path.Join(devicePath, "device/driver"))
+}
+
+// GetDriverName returns driver name of the device behind the netdev, it is empty for virtual devices
+func GetDriverName(netClassDirectory string, deviceName string) string {
+	return readDriverName(path.Join(netClassDirectory, deviceName))
 }
 
 func isInterfaceTypeValid(devicePath string, allowedInterfaceTypes []int) bool {
@@ -56,6 +104,40 @@ func isInterfaceBondSlave(devicePath string) bool {
 	return true
 }
 
+func isInterfaceBondMaster(devicePath string) bool {
+	bondModePath := path.Join(devicePath, "bonding/mode")
+	_, err := os.Stat(bondModePath)
+	if os.IsNotExist(err) {
+		slog.Debug("Device is not a bond master", "devicePath", devicePath)
+		return false
+	} else if err != nil {
+		slog.Warn("Device bonding mode file cannot be accessed", "devicePath", devicePath, "error", err)
+		return false
+	}
+	return true
+}
+
+// Team driver has no sysfs attributes for ports, so its master is checked instead,
+// eg `/sys/class/net/team0/uevent` contains `DEVTYPE=team`
+func isInterfaceTeamSlave(devicePath string) bool {
+	_, err := os.Stat(path.Join(devicePath, "team_slave"))
+	if err == nil {
+		return true
+	}
+	masterName := readSysfsLinkName(path.Join(devicePath, "master"))
+	if masterName == "" {
+		slog.Debug("Device has no master", "devicePath", devicePath)
+		return false
+	}
+	masterPath := path.Join(path.Dir(devicePath), masterName)
+	masterUevent := readSysfsAttribute(path.Join(masterPath, "uevent"))
+	if !slices.Contains(strings.Split(masterUevent, "\n"), teamDevtype) {
+		slog.Debug("Device master is not a team", "devicePath", devicePath, "master", masterName)
+		return false
+	}
+	return true
+}
+
 func isInterfaceBridgeSlave(devicePath string) bool {
 	bridgeFlagsPath := path.Join(devicePath, "brport/bridge/type")
 	// TODO: better check of file state (symlink, dir, etc) and maybe dumb read
@@ -70,69 +152,202 @@ func isInterfaceBridgeSlave(devicePath string) bool {
 	return true
 }
 
+// OVS ports are not bridge slaves, but their `master` link points to the datapath device,
+// eg `/sys/class/net/tap2473528a-b1/master -> ../ovs-system`
+func isInterfaceOvsSlave(devicePath string) bool {
+	masterPath := path.Join(devicePath, "master")
+	masterLink, err := os.Readlink(masterPath)
+	if os.IsNotExist(err) {
+		slog.Debug("Device has no master", "devicePath", devicePath)
+		return false
+	} else if err != nil {
+		slog.Warn("Device master link cannot be read", "devicePath", devicePath, "error", err)
+		return false
+	}
+	if path.Base(masterLink) != ovsMasterName {
+		slog.Debug("Device master is not OVS", "devicePath", devicePath, "master", masterLink)
+		return false
+	}
+	return true
+}
+
+// Physical ports have `device` link to the bus (PCI, platform, etc) device,
+// while virtual ones (veth, tun, dummy, OVS internal, etc) live in `/sys/devices/virtual`
+func isInterfacePhysical(devicePath string) bool {
+	realDevicePath, err := filepath.EvalSymlinks(devicePath)
+	if err != nil {
+		slog.Warn("Device path cannot be resolved", "devicePath", devicePath, "error", err)
+		return false
+	}
+	if strings.Contains(realDevicePath, virtualDevicesPath) {
+		slog.Debug("Device is virtual", "devicePath", devicePath, "realDevicePath", realDevicePath)
+		return false
+	}
+	busDevicePath, err := filepath.EvalSymlinks(path.Join(devicePath, "device"))
+	if os.IsNotExist(err) {
+		slog.Debug("Device is not backed by bus device", "devicePath", devicePath)
+		return false
+	} else if err != nil {
+		slog.Warn("Device link cannot be resolved", "devicePath", devicePath, "error", err)
+		return false
+	}
+	if strings.Contains(busDevicePath, virtualDevicesPath) {
+		slog.Debug("Bus device is virtual", "devicePath", devicePath, "busDevicePath", busDevicePath)
+		return false
+	}
+	return true
+}
+
+// Returns no ports if netclass directory cannot be read, use ListInterfaces to get the error
 func GetInterfacesList(netClassDirectory string, portDetectionOptions PortDiscoveryOptions, allowedInterfaceTypes []int) []string {
-	resultInterfaces := []string{}
+	resultInterfaces, err := ListInterfaces(netClassDirectory, portDetectionOptions, allowedInterfaceTypes)
+	if err != nil {
+		slog.Error("Cannot discover ports", "netClassDirectory", netClassDirectory, "error", err)
+		return []string{}
+	}
+	return resultInterfaces
+}
 
-	allInterfaces, err := os.ReadDir(netClassDirectory)
+func ListInterfaces(netClassDirectory string, portDetectionOptions PortDiscoveryOptions, allowedInterfaceTypes []int) ([]string, error) {
+	decisions, err := ExplainInterfaces(netClassDirectory, portDetectionOptions, allowedInterfaceTypes)
 	if err != nil {
-		errMsg := fmt.Sprintf("Cannot access netclass directory %s: %v", netClassDirectory, err)
-		panic(errMsg)
+		return nil, err
 	}
+	resultInterfaces := AcceptedPorts(decisions)
+	slog.Debug("Discovered following interfaces", "interfaces", resultInterfaces)
+	return resultInterfaces, nil
+}
 
-	reservedNetClassEntry := []string{"bonding_masters"}
+// ExplainInterfaces returns decision of every netclass entry.
+// Port is rejected by the first failed mandatory filter (regexps, type, state, etc),
+// otherwise it is accepted if any of enabled discovery filters (bonds, bridges, etc) passes.
+func ExplainInterfaces(netClassDirectory string, portDetectionOptions PortDiscoveryOptions, allowedInterfaceTypes []int) ([]PortDecision, error) {
+	decisions := []PortDecision{}
+
+	allInterfaces, err := os.ReadDir(netClassDirectory)
+	if err != nil {
+		return nil, fmt.Errorf("cannot access netclass directory %s: %w", netClassDirectory, err)
+	}
 
 	slog.Debug("Got unfiltered interface list", "allInterfaces", allInterfaces)
 	for _, deviceDir := range allInterfaces {
-		deviceName := deviceDir.Name()
-
-		if slices.Contains(reservedNetClassEntry, deviceName) {
-			slog.Debug("Skipping reserved netclass entry", "deviceName", deviceName)
-			continue
-		}
-
-		if deviceDir.Type().IsRegular() {
-			slog.Debug("Not a valid device, skipping", "deviceName", deviceName)
-			continue
+		decision := PortDecision{Name: deviceDir.Name()}
+		decision.Accepted = explainInterface(&decision, netClassDirectory, deviceDir, portDetectionOptions, allowedInterfaceTypes)
+		if decision.Accepted {
+			slog.Debug("Port passed one of filters, adding to final list", "deviceName", decision.Name, "filters", decision.PassedFilters())
+		} else {
+			slog.Debug("Port haven't passed filters, skipping", "deviceName", decision.Name, "filter", decision.RejectedBy())
 		}
+		decisions = append(decisions, decision)
+	}
+	return decisions, nil
+}
 
-		if !portDetectionOptions.PortsRegexp.MatchString(deviceName) {
-			slog.Debug("Port doesn't match regexp, skipping", "deviceName", deviceName, "regexp", portDetectionOptions.PortsRegexp)
-			continue
-		}
+func explainInterface(decision *PortDecision, netClassDirectory string, deviceDir os.DirEntry, portDetectionOptions PortDiscoveryOptions, allowedInterfaceTypes []int) bool {
+	deviceName := deviceDir.Name()
+	interfacePath := path.Join(netClassDirectory, deviceName)
+	reservedNetClassEntry := []string{"bonding_masters"}
 
-		interfacePath := path.Join(netClassDirectory, deviceName)
-		if !isInterfaceTypeValid(interfacePath, allowedInterfaceTypes) {
-			slog.Debug("Not a valid device type, skipping", "deviceName", deviceName)
-			continue
-		}
+	if !decision.check(filterReserved, !slices.Contains(reservedNetClassEntry, deviceName), "reserved netclass entry") {
+		return false
+	}
+	if !decision.check(filterNotDevice, !deviceDir.Type().IsRegular(), "regular file, not a device") {
+		return false
+	}
+	if !decision.check(filterRegexp, portDetectionOptions.PortsRegexp.MatchString(deviceName), fmt.Sprintf("name doesn't match %q", portDetectionOptions.PortsRegexp)) {
+		return false
+	}
+	if !checkMandatoryFilters(decision, netClassDirectory, portDetectionOptions, allowedInterfaceTypes) {
+		return false
+	}
 
-		portShouldBeAdded := false
-		if portDetectionOptions.DiscoverAllPorts {
+	if portDetectionOptions.DiscoverAllPorts {
+		decision.check(filterAllPorts, true, "")
+		return expandLowerDevices(decision, netClassDirectory, portDetectionOptions, allowedInterfaceTypes)
+	}
+	portShouldBeAdded := false
+	discoveryFilters := []struct {
+		name    string
+		enabled bool
+		check   func() bool
+		reason  string
+	}{
+		{filterBondSlaves, portDetectionOptions.DiscoverBondSlaves, func() bool { return isInterfaceBondSlave(interfacePath) }, "not a bond slave"},
+		{filterBondMasters, portDetectionOptions.DiscoverBondMasters, func() bool { return isInterfaceBondMaster(interfacePath) }, "not a bond master"},
+		{filterTeamSlaves, portDetectionOptions.DiscoverTeamSlaves, func() bool { return isInterfaceTeamSlave(interfacePath) }, "not a team slave"},
+		{filterBridgeSlaves, portDetectionOptions.DiscoverBridgeSlaves, func() bool { return isInterfaceBridgeSlave(interfacePath) }, "not a bridge slave"},
+		{filterPhysicalPorts, portDetectionOptions.DiscoverPhysicalPorts, func() bool { return isInterfacePhysical(interfacePath) }, "not a physical port"},
+		{filterVirtualFunctions, portDetectionOptions.DiscoverVirtualFunctions, func() bool { return isInterfaceVirtualFunction(interfacePath) }, "not a virtual function"},
+		{filterRepresentors, portDetectionOptions.DiscoverRepresentors, func() bool { return isInterfaceRepresentor(interfacePath) }, "not a representor"},
+	}
+	for _, discoveryFilter := range discoveryFilters {
+		if discoveryFilter.enabled && decision.check(discoveryFilter.name, discoveryFilter.check(), discoveryFilter.reason) {
 			portShouldBeAdded = true
+		}
+	}
+	if portDetectionOptions.DiscoverOvsSlaves {
+		if !isInterfaceOvsSlave(interfacePath) {
+			decision.check(filterOvsSlaves, false, "not an OVS port")
+		} else if portDetectionOptions.OvsSkipVirtualPorts && !isInterfacePhysical(interfacePath) {
+			decision.check(filterOvsSlaves, false, "virtual OVS port")
 		} else {
-			if portDetectionOptions.DiscoverBondSlaves {
-				if isInterfaceBondSlave(interfacePath) {
-					portShouldBeAdded = true
-				} else {
-					slog.Debug("Not a bonded port", "deviceName", deviceName)
-				}
-			}
-			if portDetectionOptions.DiscoverBridgeSlaves {
-				if isInterfaceBridgeSlave(interfacePath) {
-					portShouldBeAdded = true
-				} else {
-					slog.Debug("Not a bridged port", "deviceName", deviceName)
-				}
+			portShouldBeAdded = decision.check(filterOvsSlaves, true, "")
+		}
+	}
+	if !portShouldBeAdded {
+		triedFilters := []string{}
+		for _, result := range decision.Chain {
+			if isDiscoveryFilter(result.Filter) {
+				triedFilters = append(triedFilters, result.Filter)
 			}
 		}
+		decision.check(filterNoDiscovery, false, fmt.Sprintf("none of discovery filters passed: %s", strings.Join(triedFilters, ", ")))
+		return false
+	}
+	return expandLowerDevices(decision, netClassDirectory, portDetectionOptions, allowedInterfaceTypes)
+}
 
-		if portShouldBeAdded {
-			slog.Debug("Port passed one of filters, adding to final list", "deviceName", deviceName)
-			resultInterfaces = append(resultInterfaces, deviceName)
-		} else {
-			slog.Debug("Port haven't passed any of filters, skipping", "deviceName", deviceName)
+// Filters, that apply both to discovered ports and to lower devices they are expanded to.
+// Include regexp is not one of them, it selects stacked port itself, eg `bond0.100`.
+func checkMandatoryFilters(decision *PortDecision, netClassDirectory string, portDetectionOptions PortDiscoveryOptions, allowedInterfaceTypes []int) bool {
+	deviceName := decision.Name
+	interfacePath := path.Join(netClassDirectory, deviceName)
+	if portDetectionOptions.PortsExcludeRegexp != nil {
+		if !decision.check(filterExcludeRegexp, !portDetectionOptions.PortsExcludeRegexp.MatchString(deviceName), fmt.Sprintf("name matches %q", portDetectionOptions.PortsExcludeRegexp)) {
+			return false
 		}
 	}
-	slog.Debug("Discovered following interfaces", "interfaces", resultInterfaces)
-	return resultInterfaces
+	if !decision.check(filterType, isInterfaceTypeValid(interfacePath, allowedInterfaceTypes), fmt.Sprintf("type is not one of %v", allowedInterfaceTypes)) {
+		return false
+	}
+	if len(portDetectionOptions.AllowedOperStates) > 0 || len(portDetectionOptions.SkipOperStates) > 0 {
+		operState := readOperState(interfacePath)
+		operStateAllowed := len(portDetectionOptions.AllowedOperStates) == 0 || slices.Contains(portDetectionOptions.AllowedOperStates, operState)
+		operStateAllowed = operStateAllowed && !slices.Contains(portDetectionOptions.SkipOperStates, operState)
+		if !decision.check(filterOperState, operStateAllowed, fmt.Sprintf("operstate is %q", operState)) {
+			return false
+		}
+	}
+	if portDetectionOptions.RequireCarrier {
+		if !decision.check(filterCarrier, hasInterfaceCarrier(interfacePath), "no carrier") {
+			return false
+		}
+	}
+	if portDetectionOptions.DriversRegexp != nil {
+		driver := readDriverName(interfacePath)
+		if !decision.check(filterDriver, portDetectionOptions.DriversRegexp.MatchString(driver), fmt.Sprintf("driver %q doesn't match %q", driver, portDetectionOptions.DriversRegexp)) {
+			return false
+		}
+	}
+	if portDetectionOptions.SkipVirtualFunctions {
+		if !decision.check(filterSkipVirtualFunctions, !isInterfaceVirtualFunction(interfacePath), "SR-IOV virtual function") {
+			return false
+		}
+	}
+	if portDetectionOptions.SkipRepresentors {
+		if !decision.check(filterSkipRepresentors, !isInterfaceRepresentor(interfacePath), "switchdev representor") {
+			return false
+		}
+	}
+	return true
 }
@@ -2,6 +2,7 @@ package interfaces
 
 import (
 	"os"
+	"path"
 	"regexp"
 	"testing"
 
@@ -18,7 +19,7 @@ func TestInterfacesRegexp(t *testing.T) {
 	}
 	interfaces := GetInterfacesList(defaultNetClassPath, discoverConfig, allowedTypes)
 
-	assert.Equal(t, []string{"bond0", "br0"}, interfaces)
+	assert.Equal(t, []string{"bond0", "bond0.100", "br0"}, interfaces)
 }
 
 func TestInterfacesAllTypes(t *testing.T) {
@@ -29,7 +30,7 @@ func TestInterfacesAllTypes(t *testing.T) {
 	}
 	interfaces := GetInterfacesList(defaultNetClassPath, discoverConfig, allowedTypes)
 
-	assert.Equal(t, []string{"bond0", "br0", "eth0", "eth1", "eth2", "eth3", "eth4", "eth5", "slave0"}, interfaces)
+	assert.Equal(t, []string{"bond0", "bond0.100", "br0", "dummy0", "ens7f0", "ens7f0_0", "ens7f0v0", "eth0", "eth1", "eth2", "eth3", "eth4", "eth5", "eth6", "eth7", "ovs-system", "slave0", "tap0", "team0"}, interfaces)
 }
 
 func TestInterfacesAllEthernet(t *testing.T) {
@@ -40,7 +41,7 @@ func TestInterfacesAllEthernet(t *testing.T) {
 	}
 	interfaces := GetInterfacesList(defaultNetClassPath, discoverConfig, allowedTypes)
 
-	assert.Equal(t, []string{"bond0", "br0", "eth0", "eth4", "slave0"}, interfaces)
+	assert.Equal(t, []string{"bond0", "bond0.100", "br0", "dummy0", "ens7f0", "ens7f0_0", "ens7f0v0", "eth0", "eth4", "eth6", "eth7", "ovs-system", "slave0", "tap0", "team0"}, interfaces)
 }
 
 func TestInterfacesBonded(t *testing.T) {
@@ -55,6 +56,18 @@ func TestInterfacesBonded(t *testing.T) {
 	assert.Equal(t, []string{"eth0"}, interfaces)
 }
 
+func TestInterfacesBondMasters(t *testing.T) {
+	allowedTypes := []int{1}
+	discoverConfig := PortDiscoveryOptions{
+		PortsRegexp:         regexp.MustCompile(".+"),
+		DiscoverBondSlaves:  true,
+		DiscoverBondMasters: true,
+	}
+	interfaces := GetInterfacesList(defaultNetClassPath, discoverConfig, allowedTypes)
+
+	assert.Equal(t, []string{"bond0", "eth0"}, interfaces)
+}
+
 func TestInterfacesBridged(t *testing.T) {
 	allowedTypes := []int{1}
 	discoverConfig := PortDiscoveryOptions{
@@ -66,6 +79,55 @@ func TestInterfacesBridged(t *testing.T) {
 	assert.Equal(t, []string{"slave0"}, interfaces)
 }
 
+func TestInterfacesOvs(t *testing.T) {
+	allowedTypes := []int{1}
+	discoverConfig := PortDiscoveryOptions{
+		PortsRegexp:       regexp.MustCompile(".+"),
+		DiscoverOvsSlaves: true,
+	}
+	interfaces := GetInterfacesList(defaultNetClassPath, discoverConfig, allowedTypes)
+	assert.Equal(t, []string{"eth6", "tap0"}, interfaces)
+
+	discoverConfig.OvsSkipVirtualPorts = true
+	interfaces = GetInterfacesList(defaultNetClassPath, discoverConfig, allowedTypes)
+	assert.Equal(t, []string{"eth6"}, interfaces)
+}
+
+func TestInterfacesPhysical(t *testing.T) {
+	allowedTypes := []int{1}
+	discoverConfig := PortDiscoveryOptions{
+		PortsRegexp:           regexp.MustCompile(".+"),
+		DiscoverPhysicalPorts: true,
+	}
+	interfaces := GetInterfacesList(defaultNetClassPath, discoverConfig, allowedTypes)
+	assert.Equal(t, []string{"ens7f0", "ens7f0_0", "ens7f0v0", "eth6"}, interfaces)
+
+	discoverConfig.SkipVirtualFunctions = true
+	discoverConfig.SkipRepresentors = true
+	interfaces = GetInterfacesList(defaultNetClassPath, discoverConfig, allowedTypes)
+	assert.Equal(t, []string{"ens7f0", "eth6"}, interfaces)
+}
+
+func TestInterfacesSriov(t *testing.T) {
+	allowedTypes := []int{1}
+	discoverConfig := PortDiscoveryOptions{
+		PortsRegexp:              regexp.MustCompile(".+"),
+		DiscoverVirtualFunctions: true,
+	}
+	interfaces := GetInterfacesList(defaultNetClassPath, discoverConfig, allowedTypes)
+	assert.Equal(t, []string{"ens7f0v0"}, interfaces)
+
+	discoverConfig.DiscoverRepresentors = true
+	interfaces = GetInterfacesList(defaultNetClassPath, discoverConfig, allowedTypes)
+	assert.Equal(t, []string{"ens7f0_0", "ens7f0v0"}, interfaces)
+
+	discoverConfig.DiscoverAllPorts = true
+	discoverConfig.SkipRepresentors = true
+	interfaces = GetInterfacesList(defaultNetClassPath, discoverConfig, []int{})
+	assert.NotContains(t, interfaces, "ens7f0_0")
+	assert.Contains(t, interfaces, "ens7f0v0")
+}
+
 func TestInterfacesBrokenPath(t *testing.T) {
 	absentNetClassPath := "../testdata/interfaces/sys/class/net2"
 	allowedTypes := []int{1}
@@ -74,7 +136,9 @@ func TestInterfacesBrokenPath(t *testing.T) {
 		DiscoverAllPorts: true,
 	}
 
-	assert.Panics(t, func() { GetInterfacesList(absentNetClassPath, discoverConfig, allowedTypes) })
+	assert.Empty(t, GetInterfacesList(absentNetClassPath, discoverConfig, allowedTypes))
+	_, err := ListInterfaces(absentNetClassPath, discoverConfig, allowedTypes)
+	assert.Error(t, err)
 }
 
 func TestIsInterfaceBondedPermissionError(t *testing.T) {
@@ -89,3 +153,166 @@ func TestIsInterfaceBondedPermissionError(t *testing.T) {
 	isBonded := isInterfaceBondSlave(unreadableFile)
 	assert.False(t, isBonded)
 }
+
+func TestInterfacesExcludeRegexp(t *testing.T) {
+	discoverConfig := PortDiscoveryOptions{
+		PortsRegexp:        regexp.MustCompile("eth[0-9]"),
+		PortsExcludeRegexp: regexp.MustCompile("eth[1-57]"),
+		DiscoverAllPorts:   true,
+	}
+	interfaces := GetInterfacesList(defaultNetClassPath, discoverConfig, []int{})
+
+	assert.Equal(t, []string{"eth0", "eth6"}, interfaces)
+}
+
+func TestInterfacesOperState(t *testing.T) {
+	discoverConfig := PortDiscoveryOptions{
+		PortsRegexp:       regexp.MustCompile(".+"),
+		AllowedOperStates: []string{"up", "down"},
+		DiscoverAllPorts:  true,
+	}
+	interfaces := GetInterfacesList(defaultNetClassPath, discoverConfig, []int{1})
+	assert.Equal(t, []string{"eth0", "eth4", "eth6"}, interfaces)
+
+	discoverConfig.AllowedOperStates = []string{}
+	discoverConfig.SkipOperStates = []string{"notpresent", "down"}
+	interfaces = GetInterfacesList(defaultNetClassPath, discoverConfig, []int{1})
+	assert.Contains(t, interfaces, "eth0")
+	assert.Contains(t, interfaces, "bond0")
+	assert.NotContains(t, interfaces, "eth4")
+	assert.NotContains(t, interfaces, "slave0")
+}
+
+func TestInterfacesCarrierAndDriver(t *testing.T) {
+	discoverConfig := PortDiscoveryOptions{
+		PortsRegexp:      regexp.MustCompile(".+"),
+		RequireCarrier:   true,
+		DiscoverAllPorts: true,
+	}
+	interfaces := GetInterfacesList(defaultNetClassPath, discoverConfig, []int{1})
+	assert.Equal(t, []string{"eth0", "eth6"}, interfaces)
+
+	discoverConfig.DriversRegexp = regexp.MustCompile("^ixgbe$")
+	interfaces = GetInterfacesList(defaultNetClassPath, discoverConfig, []int{1})
+	assert.Equal(t, []string{"eth6"}, interfaces)
+}
+
+func TestGetDriverName(t *testing.T) {
+	assert.Equal(t, "ixgbe", GetDriverName(defaultNetClassPath, "eth6"))
+	assert.Equal(t, "", GetDriverName(defaultNetClassPath, "bond0"))
+	assert.Equal(t, "", GetDriverName(defaultNetClassPath, "non_existent"))
+}
+
+func TestExplainInterfaces(t *testing.T) {
+	discoverConfig := PortDiscoveryOptions{
+		PortsRegexp:        regexp.MustCompile(".+"),
+		PortsExcludeRegexp: regexp.MustCompile("eth1"),
+		DiscoverBondSlaves: true,
+		DiscoverOvsSlaves:  true,
+	}
+	decisions, err := ExplainInterfaces(defaultNetClassPath, discoverConfig, []int{1})
+	assert.NoError(t, err)
+	decisionsByName := map[string]PortDecision{}
+	for _, decision := range decisions {
+		decisionsByName[decision.Name] = decision
+	}
+
+	assert.True(t, decisionsByName["eth0"].Accepted)
+	assert.Equal(t, []string{"bond-slaves"}, decisionsByName["eth0"].PassedFilters())
+	assert.Equal(t, []string{"ovs-slaves"}, decisionsByName["tap0"].PassedFilters())
+
+	assert.False(t, decisionsByName["bonding_masters"].Accepted)
+	assert.Equal(t, FilterResult{Filter: "reserved", Reason: "reserved netclass entry"}, decisionsByName["bonding_masters"].RejectedBy())
+	assert.Equal(t, FilterResult{Filter: "exclude-regexp", Reason: `name matches "eth1"`}, decisionsByName["eth1"].RejectedBy())
+	assert.Equal(t, "type", decisionsByName["eth2"].RejectedBy().Filter)
+	assert.Equal(t, FilterResult{Filter: "discovery", Reason: "none of discovery filters passed: bond-slaves, ovs-slaves"}, decisionsByName["eth4"].RejectedBy())
+	assert.Equal(t, []FilterResult{
+		{Filter: "reserved", Passed: true},
+		{Filter: "not-a-device", Passed: true},
+		{Filter: "regexp", Passed: true},
+		{Filter: "exclude-regexp", Passed: true},
+		{Filter: "type", Passed: true},
+		{Filter: "bond-slaves", Reason: "not a bond slave"},
+		{Filter: "ovs-slaves", Reason: "not an OVS port"},
+		{Filter: "discovery", Reason: "none of discovery filters passed: bond-slaves, ovs-slaves"},
+	}, decisionsByName["eth4"].Chain)
+}
+
+func TestInterfacesTeamSlaves(t *testing.T) {
+	discoverConfig := PortDiscoveryOptions{
+		PortsRegexp:        regexp.MustCompile(".+"),
+		DiscoverTeamSlaves: true,
+	}
+	interfaces := GetInterfacesList(defaultNetClassPath, discoverConfig, []int{1})
+
+	assert.Equal(t, []string{"eth7"}, interfaces)
+}
+
+func TestInterfacesExpandLowerDevices(t *testing.T) {
+	discoverConfig := PortDiscoveryOptions{
+		PortsRegexp:        regexp.MustCompile(`^(bond0\.100|team0|eth4)$`),
+		DiscoverAllPorts:   true,
+		ExpandLowerDevices: true,
+	}
+	interfaces := GetInterfacesList(defaultNetClassPath, discoverConfig, []int{1})
+	assert.Equal(t, []string{"eth0", "eth4", "eth7"}, interfaces)
+
+	// Bond masters are kept, as bond-info metrics are collected for them
+	discoverConfig.PortsRegexp = regexp.MustCompile("^bond0$")
+	discoverConfig.DiscoverAllPorts = false
+	discoverConfig.DiscoverBondMasters = true
+	interfaces = GetInterfacesList(defaultNetClassPath, discoverConfig, []int{1})
+	assert.Equal(t, []string{"bond0"}, interfaces)
+
+	decisions, err := ExplainInterfaces(defaultNetClassPath, PortDiscoveryOptions{
+		PortsRegexp:        regexp.MustCompile(`^bond0\.100$`),
+		DiscoverAllPorts:   true,
+		ExpandLowerDevices: true,
+	}, []int{1})
+	assert.NoError(t, err)
+	for _, decision := range decisions {
+		if decision.Name == "bond0.100" {
+			assert.Equal(t, []string{"eth0"}, decision.ExpandedTo)
+			assert.Equal(t, FilterResult{Filter: "lower-devices", Passed: true}, decision.Chain[len(decision.Chain)-1])
+		}
+	}
+}
+
+func TestInterfacesExpandLowerDevicesFiltered(t *testing.T) {
+	discoverConfig := PortDiscoveryOptions{
+		PortsRegexp:        regexp.MustCompile(`^bond0\.100$`),
+		PortsExcludeRegexp: regexp.MustCompile("^eth0$"),
+		DiscoverAllPorts:   true,
+		ExpandLowerDevices: true,
+	}
+	// Excluded port must not come back through VLAN over its bond
+	decisions, err := ExplainInterfaces(defaultNetClassPath, discoverConfig, []int{1})
+	assert.NoError(t, err)
+	assert.Empty(t, AcceptedPorts(decisions))
+	for _, decision := range decisions {
+		if decision.Name == "bond0.100" {
+			assert.False(t, decision.Accepted)
+			assert.Empty(t, decision.ExpandedTo)
+			assert.Equal(t, FilterResult{Filter: "lower-devices", Reason: "all lower devices are rejected: eth0 (exclude-regexp)"}, decision.RejectedBy())
+		}
+	}
+
+	discoverConfig.PortsExcludeRegexp = nil
+	discoverConfig.DriversRegexp = regexp.MustCompile("^ixgbe$")
+	assert.Empty(t, GetInterfacesList(defaultNetClassPath, discoverConfig, []int{1}))
+
+	netClassDirectory := t.TempDir()
+	for _, deviceName := range []string{"vlan0", "bond0", "eth0", "eth1"} {
+		assert.NoError(t, os.Mkdir(path.Join(netClassDirectory, deviceName), 0755))
+	}
+	assert.NoError(t, os.Symlink("../bond0", path.Join(netClassDirectory, "vlan0/lower_bond0")))
+	assert.NoError(t, os.Symlink("../eth0", path.Join(netClassDirectory, "bond0/lower_eth0")))
+	assert.NoError(t, os.Symlink("../eth1", path.Join(netClassDirectory, "bond0/lower_eth1")))
+	discoverConfig = PortDiscoveryOptions{
+		PortsRegexp:        regexp.MustCompile("^vlan0$"),
+		PortsExcludeRegexp: regexp.MustCompile("^eth0$"),
+		DiscoverAllPorts:   true,
+		ExpandLowerDevices: true,
+	}
+	assert.Equal(t, []string{"eth1"}, GetInterfacesList(netClassDirectory, discoverConfig, []int{}))
+}
@@ -0,0 +1,79 @@
+package interfaces
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+const lowerLinkPrefix = "lower_"
+
+// Lower devices are linked as `lower_<name>`, eg `/sys/class/net/bond0.100/lower_bond0`
+func readLowerDevices(devicePath string) []string {
+	deviceEntries, err := os.ReadDir(devicePath)
+	if err != nil {
+		slog.Debug("Cannot list device attributes", "devicePath", devicePath, "error", err)
+		return nil
+	}
+	lowerDevices := []string{}
+	for _, deviceEntry := range deviceEntries {
+		if lowerDevice, found := strings.CutPrefix(deviceEntry.Name(), lowerLinkPrefix); found {
+			lowerDevices = append(lowerDevices, lowerDevice)
+		}
+	}
+	return lowerDevices
+}
+
+// ResolveLowestDevices walks `lower_*` links down to devices without lower ones,
+// eg VLAN over bond resolves to bond slaves. Returns nothing if device has no lower devices.
+func ResolveLowestDevices(netClassDirectory string, deviceName string) []string {
+	lowestDevices := []string{}
+	visitedDevices := []string{deviceName}
+	pendingDevices := readLowerDevices(path.Join(netClassDirectory, deviceName))
+	for len(pendingDevices) > 0 {
+		lowerDevice := pendingDevices[0]
+		pendingDevices = pendingDevices[1:]
+		if slices.Contains(visitedDevices, lowerDevice) {
+			continue
+		}
+		visitedDevices = append(visitedDevices, lowerDevice)
+		nextLowerDevices := readLowerDevices(path.Join(netClassDirectory, lowerDevice))
+		if len(nextLowerDevices) == 0 {
+			lowestDevices = append(lowestDevices, lowerDevice)
+		}
+		pendingDevices = append(pendingDevices, nextLowerDevices...)
+	}
+	slices.Sort(lowestDevices)
+	return lowestDevices
+}
+
+// Lower devices go through the same mandatory filters as discovered ports,
+// stacked port is rejected if none of its lower devices passes them
+func expandLowerDevices(decision *PortDecision, netClassDirectory string, portDetectionOptions PortDiscoveryOptions, allowedInterfaceTypes []int) bool {
+	if !portDetectionOptions.ExpandLowerDevices || slices.Contains(decision.PassedFilters(), filterBondMasters) {
+		return true
+	}
+	lowestDevices := ResolveLowestDevices(netClassDirectory, decision.Name)
+	if len(lowestDevices) == 0 {
+		return true
+	}
+	acceptedDevices := []string{}
+	rejectedDevices := []string{}
+	for _, lowerDevice := range lowestDevices {
+		lowerDecision := PortDecision{Name: lowerDevice}
+		if checkMandatoryFilters(&lowerDecision, netClassDirectory, portDetectionOptions, allowedInterfaceTypes) {
+			acceptedDevices = append(acceptedDevices, lowerDevice)
+			continue
+		}
+		slog.Debug("Lower device haven't passed filters, skipping", "deviceName", decision.Name, "lowerDevice", lowerDevice, "filter", lowerDecision.RejectedBy())
+		rejectedDevices = append(rejectedDevices, fmt.Sprintf("%s (%s)", lowerDevice, lowerDecision.RejectedBy().Filter))
+	}
+	if len(acceptedDevices) == 0 {
+		return decision.check(filterLowerDevices, false, fmt.Sprintf("all lower devices are rejected: %s", strings.Join(rejectedDevices, ", ")))
+	}
+	decision.ExpandedTo = acceptedDevices
+	return decision.check(filterLowerDevices, true, "")
+}
@@ -0,0 +1,31 @@
+package interfaces
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveLowestDevices(t *testing.T) {
+	assert.Equal(t, []string{"eth0"}, ResolveLowestDevices(defaultNetClassPath, "bond0.100"))
+	assert.Equal(t, []string{"eth0"}, ResolveLowestDevices(defaultNetClassPath, "bond0"))
+	assert.Equal(t, []string{"eth7"}, ResolveLowestDevices(defaultNetClassPath, "team0"))
+	assert.Empty(t, ResolveLowestDevices(defaultNetClassPath, "eth0"))
+	assert.Empty(t, ResolveLowestDevices(defaultNetClassPath, "absent0"))
+}
+
+func TestResolveLowestDevicesLoop(t *testing.T) {
+	netClassDirectory := t.TempDir()
+	for _, deviceName := range []string{"vlan0", "bond0", "eth0", "eth1"} {
+		assert.NoError(t, os.Mkdir(path.Join(netClassDirectory, deviceName), 0755))
+	}
+	assert.NoError(t, os.Symlink("../bond0", path.Join(netClassDirectory, "vlan0/lower_bond0")))
+	assert.NoError(t, os.Symlink("../eth0", path.Join(netClassDirectory, "bond0/lower_eth0")))
+	assert.NoError(t, os.Symlink("../eth1", path.Join(netClassDirectory, "bond0/lower_eth1")))
+	// Broken sysfs should never have loops, but it must not hang discovery
+	assert.NoError(t, os.Symlink("../vlan0", path.Join(netClassDirectory, "eth1/lower_vlan0")))
+
+	assert.Equal(t, []string{"eth0"}, ResolveLowestDevices(netClassDirectory, "vlan0"))
+}
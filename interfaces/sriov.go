@@ -0,0 +1,182 @@
+package interfaces
+
+import (
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+type SriovRole string
+
+const (
+	SriovRoleNone          SriovRole = ""
+	SriovRolePf            SriovRole = "pf"
+	SriovRoleVf            SriovRole = "vf"
+	SriovRoleRepresentor   SriovRole = "representor"
+	sriovRoleLabelName               = "sriov_role"
+	sriovPfDeviceLabelName           = "pf_device"
+)
+
+var (
+	// Switchdev representors of VFs, SFs and PFs, eg `pf0vf3`, `c1pf0sf88` or `pf0`
+	representorPortNameRegexp = regexp.MustCompile(`^(c\d+)?pf(\d+)((vf|sf)\d+)?$`)
+	// Uplink representor, it is the PF netdev itself, eg `p0`
+	uplinkPortNameRegexp = regexp.MustCompile(`^p(\d+)$`)
+)
+
+// SriovInfo describes the place of a device in SR-IOV topology.
+// PfDevice is the netdev of the physical function, it is the device itself for PFs.
+type SriovInfo struct {
+	Role     SriovRole
+	PfDevice string
+}
+
+func readPhysPortName(devicePath string) string {
+	portNameRaw, err := os.ReadFile(path.Join(devicePath, "phys_port_name"))
+	if err != nil {
+		// Most drivers don't implement it, reading returns EOPNOTSUPP
+		slog.Debug("Cannot read physical port name", "devicePath", devicePath, "error", err)
+		return ""
+	}
+	return strings.TrimSpace(string(portNameRaw))
+}
+
+func readPhysSwitchId(devicePath string) string {
+	switchIdRaw, err := os.ReadFile(path.Join(devicePath, "phys_switch_id"))
+	if err != nil {
+		slog.Debug("Cannot read physical switch id", "devicePath", devicePath, "error", err)
+		return ""
+	}
+	return strings.TrimSpace(string(switchIdRaw))
+}
+
+func isInterfaceRepresentor(devicePath string) bool {
+	return representorPortNameRegexp.MatchString(readPhysPortName(devicePath))
+}
+
+// VFs have `device/physfn` link to the PCI device of their PF
+func isInterfaceVirtualFunction(devicePath string) bool {
+	_, err := os.Lstat(path.Join(devicePath, "device/physfn"))
+	if os.IsNotExist(err) {
+		slog.Debug("Device is not a virtual function", "devicePath", devicePath)
+		return false
+	} else if err != nil {
+		slog.Warn("Device physfn link cannot be accessed", "devicePath", devicePath, "error", err)
+		return false
+	}
+	return true
+}
+
+// PFs have `device/virtfnN` links to the PCI devices of their VFs
+func isInterfacePhysicalFunction(devicePath string) bool {
+	virtualFunctions, err := filepath.Glob(path.Join(devicePath, "device/virtfn*"))
+	if err != nil {
+		slog.Warn("Device virtfn links cannot be listed", "devicePath", devicePath, "error", err)
+		return false
+	}
+	return len(virtualFunctions) > 0
+}
+
+// SriovTopology resolves SR-IOV roles of devices in the netclass directory.
+// Uplink representors are only listed once, on the first representor lookup,
+// so create it once per discovery pass instead of every device.
+type SriovTopology struct {
+	netClassDirectory string
+	// `<phys_switch_id>/<PF number>` -> PF netdev, nil until the first representor lookup
+	uplinks map[string]string
+}
+
+func NewSriovTopology(netClassDirectory string) *SriovTopology {
+	return &SriovTopology{netClassDirectory: netClassDirectory}
+}
+
+func uplinkKey(switchId string, pfNumber string) string {
+	return switchId + "/" + pfNumber
+}
+
+func (topology *SriovTopology) readUplinks() map[string]string {
+	uplinks := map[string]string{}
+	allInterfaces, err := os.ReadDir(topology.netClassDirectory)
+	if err != nil {
+		slog.Warn("Cannot access netclass directory", "netClassDirectory", topology.netClassDirectory, "error", err)
+		return uplinks
+	}
+	for _, deviceDir := range allInterfaces {
+		devicePath := path.Join(topology.netClassDirectory, deviceDir.Name())
+		uplinkMatch := uplinkPortNameRegexp.FindStringSubmatch(readPhysPortName(devicePath))
+		if uplinkMatch == nil {
+			continue
+		}
+		switchId := readPhysSwitchId(devicePath)
+		if switchId != "" {
+			uplinks[uplinkKey(switchId, uplinkMatch[1])] = deviceDir.Name()
+		}
+	}
+	return uplinks
+}
+
+// Representors share PCI device with the PF, so `device/net` is not enough:
+// the PF netdev is the uplink representor with the same switch id.
+func (topology *SriovTopology) findRepresentorPf(devicePath string) string {
+	portNameMatch := representorPortNameRegexp.FindStringSubmatch(readPhysPortName(devicePath))
+	switchId := readPhysSwitchId(devicePath)
+	if portNameMatch == nil || switchId == "" {
+		return ""
+	}
+	if topology.uplinks == nil {
+		topology.uplinks = topology.readUplinks()
+	}
+	pfDevice, found := topology.uplinks[uplinkKey(switchId, portNameMatch[2])]
+	if !found {
+		slog.Debug("Cannot find PF of representor", "devicePath", devicePath, "switchId", switchId)
+	}
+	return pfDevice
+}
+
+// In switchdev mode representors are also listed in `device/physfn/net`, so they are skipped
+func findVirtualFunctionPf(devicePath string) string {
+	pfNetPath := path.Join(devicePath, "device/physfn/net")
+	pfDevices, err := os.ReadDir(pfNetPath)
+	if err != nil {
+		slog.Debug("Cannot list PF devices of virtual function", "devicePath", devicePath, "error", err)
+		return ""
+	}
+	for _, pfDevice := range pfDevices {
+		if !isInterfaceRepresentor(path.Join(pfNetPath, pfDevice.Name())) {
+			return pfDevice.Name()
+		}
+	}
+	return ""
+}
+
+// GetSriovInfo lists uplink representors on every call, use SriovTopology for many devices
+func GetSriovInfo(netClassDirectory string, deviceName string) SriovInfo {
+	return NewSriovTopology(netClassDirectory).GetSriovInfo(deviceName)
+}
+
+func (topology *SriovTopology) GetSriovInfo(deviceName string) SriovInfo {
+	devicePath := path.Join(topology.netClassDirectory, deviceName)
+	switch {
+	case isInterfaceRepresentor(devicePath):
+		return SriovInfo{Role: SriovRoleRepresentor, PfDevice: topology.findRepresentorPf(devicePath)}
+	case isInterfaceVirtualFunction(devicePath):
+		return SriovInfo{Role: SriovRoleVf, PfDevice: findVirtualFunctionPf(devicePath)}
+	case isInterfacePhysicalFunction(devicePath):
+		return SriovInfo{Role: SriovRolePf, PfDevice: deviceName}
+	}
+	return SriovInfo{Role: SriovRoleNone}
+}
+
+// Labels returns nothing for devices that are not part of SR-IOV topology
+func (info SriovInfo) Labels() map[string]string {
+	if info.Role == SriovRoleNone {
+		return map[string]string{}
+	}
+	return map[string]string{
+		sriovRoleLabelName:     string(info.Role),
+		sriovPfDeviceLabelName: info.PfDevice,
+	}
+}
@@ -0,0 +1,42 @@
+package interfaces
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSriovInfo(t *testing.T) {
+	testCases := map[string]SriovInfo{
+		"ens7f0":   {Role: SriovRolePf, PfDevice: "ens7f0"},
+		"ens7f0v0": {Role: SriovRoleVf, PfDevice: "ens7f0"},
+		"ens7f0_0": {Role: SriovRoleRepresentor, PfDevice: "ens7f0"},
+		"eth6":     {Role: SriovRoleNone},
+		"absent0":  {Role: SriovRoleNone},
+	}
+	for deviceName, expectedInfo := range testCases {
+		t.Run(deviceName, func(t *testing.T) {
+			assert.Equal(t, expectedInfo, GetSriovInfo(defaultNetClassPath, deviceName))
+		})
+	}
+}
+
+func TestSriovTopologyReadsUplinksOnce(t *testing.T) {
+	topology := NewSriovTopology(defaultNetClassPath)
+	assert.Equal(t, SriovInfo{Role: SriovRolePf, PfDevice: "ens7f0"}, topology.GetSriovInfo("ens7f0"))
+	assert.Nil(t, topology.uplinks)
+
+	assert.Equal(t, "ens7f0", topology.GetSriovInfo("ens7f0_0").PfDevice)
+	assert.Len(t, topology.uplinks, 1)
+	// Cached uplinks are used for following representors
+	topology.uplinks = map[string]string{}
+	assert.Equal(t, SriovInfo{Role: SriovRoleRepresentor}, topology.GetSriovInfo("ens7f0_0"))
+}
+
+func TestSriovInfoLabels(t *testing.T) {
+	assert.Equal(t, map[string]string{}, SriovInfo{}.Labels())
+	assert.Equal(t,
+		map[string]string{"sriov_role": "vf", "pf_device": "ens7f0"},
+		SriovInfo{Role: SriovRoleVf, PfDevice: "ens7f0"}.Labels(),
+	)
+}
@@ -0,0 +1,111 @@
+//go:build linux
+
+package interfaces
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	watchPollTimeout   = 1000
+	watchReceiveBuffer = 64 * 1024
+)
+
+func openLinkNotificationSocket() (int, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_ROUTE)
+	if err != nil {
+		return -1, fmt.Errorf("cannot create netlink socket: %w", err)
+	}
+	err = unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: unix.RTMGRP_LINK})
+	if err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("cannot bind netlink socket: %w", err)
+	}
+	return fd, nil
+}
+
+// sysfs does not really support inotify, but it works for bind-mounted or fake netclass trees
+func openInotify(netClassDirectory string) (int, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		return -1, fmt.Errorf("cannot init inotify: %w", err)
+	}
+	_, err = unix.InotifyAddWatch(fd, netClassDirectory, unix.IN_CREATE|unix.IN_DELETE|unix.IN_MOVED_FROM|unix.IN_MOVED_TO)
+	if err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("cannot watch netclass directory %s: %w", netClassDirectory, err)
+	}
+	return fd, nil
+}
+
+// Checks if buffer contains any RTM_NEWLINK/RTM_DELLINK message
+func hasLinkChanges(data []byte) bool {
+	for len(data) >= unix.SizeofNlMsghdr {
+		messageLength := int(binary.NativeEndian.Uint32(data[0:4]))
+		messageType := binary.NativeEndian.Uint16(data[4:6])
+		if messageType == unix.RTM_NEWLINK || messageType == unix.RTM_DELLINK {
+			return true
+		}
+		if messageLength < unix.SizeofNlMsghdr || messageLength > len(data) {
+			return false
+		}
+		alignedLength := (messageLength + unix.NLMSG_ALIGNTO - 1) & ^(unix.NLMSG_ALIGNTO - 1)
+		data = data[min(alignedLength, len(data)):]
+	}
+	return false
+}
+
+// Invalidates the cache on RTM_NEWLINK/RTM_DELLINK notifications,
+// falling back to inotify on netclass directory if netlink is not available
+func (cache *DiscoveryCache) watchLinkChanges(ctx context.Context, netClassDirectory string, watchId uint64) error {
+	fd, err := openLinkNotificationSocket()
+	isNetlink := err == nil
+	if err != nil {
+		slog.Warn("Cannot watch netlink link notifications, falling back to inotify", "error", err)
+		fd, err = openInotify(netClassDirectory)
+		if err != nil {
+			return err
+		}
+	}
+	slog.Info("Watching for link changes to invalidate discovery cache", "netlink", isNetlink)
+	cache.Invalidate()
+	cache.activeWatch.Store(watchId)
+
+	go func() {
+		defer unix.Close(fd)
+		defer cache.activeWatch.CompareAndSwap(watchId, 0)
+		pollFds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+		buffer := make([]byte, watchReceiveBuffer)
+		for ctx.Err() == nil {
+			_, err := unix.Poll(pollFds, watchPollTimeout)
+			if err != nil && !errors.Is(err, unix.EINTR) {
+				slog.Error("Cannot poll for link changes, disabling discovery cache", "error", err)
+				return
+			}
+			if pollFds[0].Revents&unix.POLLIN == 0 {
+				continue
+			}
+			bytesRead, err := unix.Read(fd, buffer)
+			switch {
+			case errors.Is(err, unix.ENOBUFS):
+				slog.Debug("Netlink socket buffer overrun, invalidating discovery cache")
+				cache.Invalidate()
+			case errors.Is(err, unix.EAGAIN), errors.Is(err, unix.EINTR):
+				continue
+			case err != nil:
+				slog.Error("Cannot read link changes, disabling discovery cache", "error", err)
+				return
+			case !isNetlink || hasLinkChanges(buffer[:bytesRead]):
+				slog.Debug("Got link change, invalidating discovery cache")
+				cache.Invalidate()
+			}
+		}
+	}()
+	return nil
+}
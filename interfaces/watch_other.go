@@ -0,0 +1,12 @@
+//go:build !linux
+
+package interfaces
+
+import (
+	"context"
+	"errors"
+)
+
+func (cache *DiscoveryCache) watchLinkChanges(ctx context.Context, netClassDirectory string, watchId uint64) error {
+	return errors.New("watching link changes is only supported on Linux")
+}
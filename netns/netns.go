@@ -0,0 +1,19 @@
+// Package netns runs port discovery and collection inside other network namespaces.
+package netns
+
+// Namespace is either a named namespace, eg `/run/netns/dpdk`, or a namespace of some process,
+// eg `/proc/1234/ns/net`
+type Namespace struct {
+	Name string
+	Path string
+	// nsfs inode, the same namespace can be reachable via several paths
+	inode uint64
+}
+
+// Paths are only valid inside the function passed to Namespace.Run
+type Paths struct {
+	// Sysfs mounted inside namespace, eg `/tmp/go-ethtool-exporter-netns-123/class/net`
+	NetClassPath string
+	// Procfs of the current thread, eg `/proc/thread-self/net/bonding`
+	ProcNetBondingPath string
+}
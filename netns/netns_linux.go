@@ -0,0 +1,184 @@
+//go:build linux
+
+package netns
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"runtime"
+	"slices"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+const threadProcNetBondingPath = "/proc/thread-self/net/bonding"
+
+// Also checks that path is a namespace, not a stale file left after `ip netns delete`
+func readNamespaceInode(namespacePath string) (uint64, error) {
+	var statfs unix.Statfs_t
+	err := unix.Statfs(namespacePath, &statfs)
+	if err != nil {
+		return 0, err
+	}
+	if statfs.Type != unix.NSFS_MAGIC {
+		return 0, fmt.Errorf("%s is not a namespace", namespacePath)
+	}
+	var stat unix.Stat_t
+	err = unix.Stat(namespacePath, &stat)
+	if err != nil {
+		return 0, err
+	}
+	return stat.Ino, nil
+}
+
+// ListNamespaces returns named namespaces from runNetnsDirectory, like `ip netns list` does,
+// and optionally namespaces of all the processes. Named namespaces are skipped if runNetnsDirectory is empty.
+// Namespace of the exporter itself is always skipped.
+func ListNamespaces(runNetnsDirectory string, procDirectory string, includeProcesses bool) ([]Namespace, error) {
+	namespaces := []Namespace{}
+	selfInode, err := readNamespaceInode(path.Join(procDirectory, "self/ns/net"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read own network namespace: %w", err)
+	}
+	seenInodes := []uint64{selfInode}
+
+	var namedEntries []os.DirEntry
+	if runNetnsDirectory != "" {
+		namedEntries, err = os.ReadDir(runNetnsDirectory)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("cannot access netns directory %s: %w", runNetnsDirectory, err)
+		}
+	}
+	for _, namedEntry := range namedEntries {
+		namespacePath := path.Join(runNetnsDirectory, namedEntry.Name())
+		inode, err := readNamespaceInode(namespacePath)
+		if err != nil {
+			slog.Warn("Cannot access network namespace, skipping", "namespacePath", namespacePath, "error", err)
+			continue
+		}
+		if slices.Contains(seenInodes, inode) {
+			slog.Debug("Network namespace is already listed, skipping", "namespacePath", namespacePath)
+			continue
+		}
+		seenInodes = append(seenInodes, inode)
+		namespaces = append(namespaces, Namespace{Name: namedEntry.Name(), Path: namespacePath, inode: inode})
+	}
+	if !includeProcesses {
+		return namespaces, nil
+	}
+
+	procEntries, err := os.ReadDir(procDirectory)
+	if err != nil {
+		return nil, fmt.Errorf("cannot access proc directory %s: %w", procDirectory, err)
+	}
+	// Sorted by name, so the process with the lowest pid usually names the namespace
+	slices.SortFunc(procEntries, func(a, b os.DirEntry) int {
+		aPid, _ := strconv.Atoi(a.Name())
+		bPid, _ := strconv.Atoi(b.Name())
+		return aPid - bPid
+	})
+	for _, procEntry := range procEntries {
+		if _, err := strconv.Atoi(procEntry.Name()); err != nil {
+			continue
+		}
+		namespacePath := path.Join(procDirectory, procEntry.Name(), "ns/net")
+		inode, err := readNamespaceInode(namespacePath)
+		if err != nil {
+			// Processes come and go, kernel threads have no namespaces
+			slog.Debug("Cannot access process network namespace, skipping", "namespacePath", namespacePath, "error", err)
+			continue
+		}
+		if slices.Contains(seenInodes, inode) {
+			continue
+		}
+		seenInodes = append(seenInodes, inode)
+		namespaces = append(namespaces, Namespace{Name: "pid/" + procEntry.Name(), Path: namespacePath, inode: inode})
+	}
+	return namespaces, nil
+}
+
+// Run calls the function on a dedicated OS thread, that is moved to the namespace.
+// Sysfs only shows devices of the namespace it was mounted in, so it is mounted again
+// in a private mount namespace of the thread. Commands started by the function,
+// eg ethtool, inherit both namespaces. Requires CAP_SYS_ADMIN.
+func (namespace Namespace) Run(function func(paths Paths) error) error {
+	namespaceFile, err := os.Open(namespace.Path)
+	if err != nil {
+		return fmt.Errorf("cannot open network namespace %s: %w", namespace.Path, err)
+	}
+	defer namespaceFile.Close()
+
+	return runOnSpareThread(func() error {
+		return runLocked(int(namespaceFile.Fd()), function)
+	})
+}
+
+// Enter calls the function on a dedicated OS thread, that is moved to the network namespace only.
+// Mounts are left intact, so sysfs still shows devices of the exporter's own namespace,
+// which is enough for netlink and ioctl users, eg ethtool. Requires CAP_SYS_ADMIN.
+func (namespace Namespace) Enter(function func() error) error {
+	namespaceFile, err := os.Open(namespace.Path)
+	if err != nil {
+		return fmt.Errorf("cannot open network namespace %s: %w", namespace.Path, err)
+	}
+	defer namespaceFile.Close()
+
+	return runOnSpareThread(func() error {
+		err := unix.Setns(int(namespaceFile.Fd()), unix.CLONE_NEWNET)
+		if err != nil {
+			return fmt.Errorf("cannot enter network namespace: %w", err)
+		}
+		return function()
+	})
+}
+
+// Thread is never unlocked, so runtime terminates it instead of reusing with foreign namespaces.
+// Namespaces of the main thread are visible via /proc/self, so it is only locked to make runtime pick another one.
+func runOnSpareThread(function func() error) error {
+	errChan := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		if unix.Gettid() == unix.Getpid() {
+			errChan <- runOnSpareThread(function)
+			runtime.UnlockOSThread()
+			return
+		}
+		errChan <- function()
+	}()
+	return <-errChan
+}
+
+func runLocked(namespaceFd int, function func(paths Paths) error) error {
+	err := unix.Unshare(unix.CLONE_NEWNS)
+	if err != nil {
+		return fmt.Errorf("cannot unshare mount namespace: %w", err)
+	}
+	// Otherwise sysfs mount below would propagate to the host
+	err = unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, "")
+	if err != nil {
+		return fmt.Errorf("cannot make mounts private: %w", err)
+	}
+	err = unix.Setns(namespaceFd, unix.CLONE_NEWNET)
+	if err != nil {
+		return fmt.Errorf("cannot enter network namespace: %w", err)
+	}
+
+	sysfsPath, err := os.MkdirTemp("", "go-ethtool-exporter-netns-")
+	if err != nil {
+		return fmt.Errorf("cannot create sysfs mountpoint: %w", err)
+	}
+	defer os.Remove(sysfsPath)
+	err = unix.Mount("sysfs", sysfsPath, "sysfs", unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "")
+	if err != nil {
+		return fmt.Errorf("cannot mount sysfs: %w", err)
+	}
+	defer unix.Unmount(sysfsPath, unix.MNT_DETACH)
+
+	return function(Paths{
+		NetClassPath:       path.Join(sysfsPath, "class/net"),
+		ProcNetBondingPath: threadProcNetBondingPath,
+	})
+}
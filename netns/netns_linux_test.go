@@ -0,0 +1,137 @@
+//go:build linux
+
+package netns
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+// Keeps a thread in a new network namespace until the test ends
+func newTestNamespace(t *testing.T) Namespace {
+	pathChan := make(chan string)
+	errChan := make(chan error)
+	doneChan := make(chan struct{})
+	go runOnSpareThread(func() error {
+		err := unix.Unshare(unix.CLONE_NEWNET)
+		if err != nil {
+			errChan <- err
+			return err
+		}
+		pathChan <- fmt.Sprintf("/proc/%d/task/%d/ns/net", os.Getpid(), unix.Gettid())
+		<-doneChan
+		return nil
+	})
+	select {
+	case err := <-errChan:
+		t.Skipf("Cannot create network namespace: %v", err)
+	case namespacePath := <-pathChan:
+		t.Cleanup(func() { close(doneChan) })
+		return Namespace{Name: "test", Path: namespacePath}
+	}
+	return Namespace{}
+}
+
+func TestListNamespacesNamed(t *testing.T) {
+	runNetnsDirectory := t.TempDir()
+	namespace := newTestNamespace(t)
+	assert.NoError(t, os.Symlink(namespace.Path, path.Join(runNetnsDirectory, "dpdk")))
+	assert.NoError(t, os.Symlink(namespace.Path, path.Join(runNetnsDirectory, "dpdk-again")))
+	assert.NoError(t, os.Symlink("/proc/self/ns/net", path.Join(runNetnsDirectory, "host")))
+	assert.NoError(t, os.WriteFile(path.Join(runNetnsDirectory, "stale"), []byte{}, 0644))
+
+	namespaces, err := ListNamespaces(runNetnsDirectory, "/proc", false)
+	assert.NoError(t, err)
+	names := []string{}
+	for _, namespace := range namespaces {
+		names = append(names, namespace.Name)
+	}
+	assert.Equal(t, []string{"dpdk"}, names)
+}
+
+func TestListNamespacesProcesses(t *testing.T) {
+	err := exec.Command("unshare", "--net", "true").Run()
+	if err != nil {
+		t.Skipf("Cannot create network namespace: %v", err)
+	}
+	unshareCommand := exec.Command("unshare", "--net", "sleep", "30")
+	err = unshareCommand.Start()
+	if err != nil {
+		t.Skipf("Cannot start process in network namespace: %v", err)
+	}
+	t.Cleanup(func() {
+		unshareCommand.Process.Kill()
+		unshareCommand.Wait()
+	})
+	expectedName := fmt.Sprintf("pid/%d", unshareCommand.Process.Pid)
+
+	assert.Eventually(t, func() bool {
+		namespaces, err := ListNamespaces("non_existent_run_netns", "/proc", true)
+		assert.NoError(t, err)
+		for _, namespace := range namespaces {
+			assert.True(t, strings.HasPrefix(namespace.Name, "pid/"), namespace.Name)
+			if namespace.Name == expectedName {
+				return true
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestListNamespacesBrokenProc(t *testing.T) {
+	_, err := ListNamespaces(t.TempDir(), "non_existent_proc", false)
+	assert.Error(t, err)
+}
+
+func TestNamespaceRun(t *testing.T) {
+	namespace := newTestNamespace(t)
+	var devices []string
+	err := namespace.Run(func(paths Paths) error {
+		deviceDirs, err := os.ReadDir(paths.NetClassPath)
+		if err != nil {
+			return err
+		}
+		for _, deviceDir := range deviceDirs {
+			devices = append(devices, deviceDir.Name())
+		}
+		return nil
+	})
+	if err != nil && strings.Contains(err.Error(), "cannot unshare mount namespace") {
+		t.Skipf("Cannot enter network namespace: %v", err)
+	}
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"lo"}, devices)
+}
+
+func TestNamespaceRunBrokenPath(t *testing.T) {
+	err := Namespace{Path: "non_existent_netns"}.Run(func(paths Paths) error { return nil })
+	assert.Error(t, err)
+}
+
+func TestNamespaceEnter(t *testing.T) {
+	namespace := newTestNamespace(t)
+	expectedInode, err := readNamespaceInode(namespace.Path)
+	assert.NoError(t, err)
+	var commandOutput []byte
+	err = namespace.Enter(func() error {
+		var err error
+		// Commands inherit namespace of the thread
+		commandOutput, err = exec.Command("readlink", "/proc/thread-self/ns/net").Output()
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("net:[%d]\n", expectedInode), string(commandOutput))
+}
+
+func TestNamespaceEnterBrokenPath(t *testing.T) {
+	err := Namespace{Path: "non_existent_netns"}.Enter(func() error { return nil })
+	assert.Error(t, err)
+}
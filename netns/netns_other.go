@@ -0,0 +1,17 @@
+//go:build !linux
+
+package netns
+
+import "errors"
+
+func ListNamespaces(runNetnsDirectory string, procDirectory string, includeProcesses bool) ([]Namespace, error) {
+	return nil, errors.New("network namespaces are only supported on Linux")
+}
+
+func (namespace Namespace) Run(function func(paths Paths) error) error {
+	return errors.New("network namespaces are only supported on Linux")
+}
+
+func (namespace Namespace) Enter(function func() error) error {
+	return errors.New("network namespaces are only supported on Linux")
+}
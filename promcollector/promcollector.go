@@ -0,0 +1,82 @@
+// Package promcollector exposes ethtool metrics as prometheus.Collector,
+// so they can be registered into registry of another Go program instead of running the exporter.
+package promcollector
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/newrushbolt/go-ethtool-exporter/collector"
+	"github.com/newrushbolt/go-ethtool-exporter/interfaces"
+	"github.com/newrushbolt/go-ethtool-exporter/registry"
+)
+
+// Config mirrors exporter flags, see `go-ethtool-exporter --help`
+type Config struct {
+	// Eg `/sys/class/net`
+	NetClassPath          string
+	DiscoveryOptions      interfaces.PortDiscoveryOptions
+	AllowedInterfaceTypes []int
+	Collector             collector.CollectorConfig
+	// Prepended to all metric names, eg `ethtool_`. Exporter itself uses no prefix
+	MetricPrefix string
+}
+
+// Collector runs discovery and ethtool on every Collect call, there is no caching.
+// Metric names depend on enabled collectors and the hardware, so it is an unchecked collector and Describe sends nothing.
+type Collector struct {
+	config Config
+}
+
+var allPortsRegexp = regexp.MustCompile(".+")
+
+func New(config Config) *Collector {
+	if config.DiscoveryOptions.PortsRegexp == nil {
+		config.DiscoveryOptions.PortsRegexp = allPortsRegexp
+	}
+	return &Collector{config: config}
+}
+
+func (ethtoolCollector *Collector) Describe(chan<- *prometheus.Desc) {}
+
+// Discovery errors are logged instead of failing the whole scrape of the registry
+func (ethtoolCollector *Collector) Collect(metrics chan<- prometheus.Metric) {
+	config := ethtoolCollector.config
+	discoveredInterfaces, err := interfaces.ListInterfaces(config.NetClassPath, config.DiscoveryOptions, config.AllowedInterfaceTypes)
+	if err != nil {
+		slog.Error("Cannot discover ports", "netClassPath", config.NetClassPath, "error", err)
+		return
+	}
+	for _, interfaceName := range discoveredInterfaces {
+		for _, metricRecord := range collector.CollectInterfaceMetrics(interfaceName, config.Collector) {
+			metrics <- newConstMetric(config.MetricPrefix, metricRecord)
+		}
+	}
+}
+
+func metricValueType(metricName string) prometheus.ValueType {
+	if registry.GetMetricType(metricName) == registry.MetricTypeCounter {
+		return prometheus.CounterValue
+	}
+	return prometheus.GaugeValue
+}
+
+func newConstMetric(metricPrefix string, metricRecord registry.MetricRecord) prometheus.Metric {
+	metricName := metricPrefix + metricRecord.Name
+	labelNames := slices.Sorted(maps.Keys(metricRecord.Labels))
+	labelValues := []string{}
+	for _, labelName := range labelNames {
+		labelValues = append(labelValues, metricRecord.Labels[labelName])
+	}
+	description := prometheus.NewDesc(metricName, fmt.Sprintf("Ethtool metric %s", metricRecord.Name), labelNames, nil)
+	metric, err := prometheus.NewConstMetric(description, metricValueType(metricRecord.Name), metricRecord.Value, labelValues...)
+	if err != nil {
+		return prometheus.NewInvalidMetric(description, err)
+	}
+	return metric
+}
@@ -0,0 +1,84 @@
+package promcollector
+
+import (
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/newrushbolt/go-ethtool-exporter/collector"
+	"github.com/newrushbolt/go-ethtool-exporter/interfaces"
+	"github.com/newrushbolt/go-ethtool-exporter/registry"
+	"github.com/newrushbolt/go-ethtool-metrics/pkg/metrics/generic_info"
+	"github.com/newrushbolt/go-ethtool-metrics/pkg/metrics/statistics"
+)
+
+func gatherFamilies(t *testing.T, ethtoolCollector prometheus.Collector) map[string]*dto.MetricFamily {
+	metricRegistry := prometheus.NewRegistry()
+	require.NoError(t, metricRegistry.Register(ethtoolCollector))
+	families, err := metricRegistry.Gather()
+	require.NoError(t, err)
+	familiesByName := map[string]*dto.MetricFamily{}
+	for _, family := range families {
+		familiesByName[family.GetName()] = family
+	}
+	return familiesByName
+}
+
+func TestCollector(t *testing.T) {
+	genericInfoRaw, err := os.ReadFile("../testdata/eth4.generic_info.src")
+	require.NoError(t, err)
+	ethtoolCollector := New(Config{
+		NetClassPath: "../testdata/interfaces/sys/class/net",
+		DiscoveryOptions: interfaces.PortDiscoveryOptions{
+			PortsRegexp:      regexp.MustCompile("^eth4$"),
+			DiscoverAllPorts: true,
+		},
+		AllowedInterfaceTypes: []int{1},
+		Collector: collector.CollectorConfig{
+			GenericInfo:     generic_info.CollectConfig{CollectSettings: true},
+			Statistics:      statistics.CollectConfig{General: true},
+			DataSource:      collector.StaticDataSource{"eth4": {"generic_info": string(genericInfoRaw), "statistics": "NIC statistics:\n     rx_packets: 42\n"}},
+			ListLabelFormat: "single-label",
+		},
+		MetricPrefix: "ethtool_",
+	})
+
+	families := gatherFamilies(t, ethtoolCollector)
+
+	speedFamily := families["ethtool_generic_info_settings_speed_bits"]
+	require.NotNil(t, speedFamily)
+	assert.Equal(t, dto.MetricType_GAUGE, speedFamily.GetType())
+	require.Len(t, speedFamily.GetMetric(), 1)
+	assert.Equal(t, 1e10, speedFamily.GetMetric()[0].GetGauge().GetValue())
+	assert.Equal(t, "device", speedFamily.GetMetric()[0].GetLabel()[0].GetName())
+	assert.Equal(t, "eth4", speedFamily.GetMetric()[0].GetLabel()[0].GetValue())
+
+	for name, family := range families {
+		if family.GetType() == dto.MetricType_COUNTER {
+			assert.Regexp(t, "^ethtool_statistics_", name)
+		}
+	}
+}
+
+func TestCollectorDiscoveryError(t *testing.T) {
+	ethtoolCollector := New(Config{NetClassPath: "../testdata/non_existent", Collector: collector.CollectorConfig{DataSource: collector.StaticDataSource{}}})
+	families := gatherFamilies(t, ethtoolCollector)
+	assert.Empty(t, families)
+}
+
+func TestMetricValueType(t *testing.T) {
+	assert.Equal(t, prometheus.CounterValue, metricValueType("link_events_total"))
+	assert.Equal(t, prometheus.CounterValue, metricValueType("statistics_general_rx_packets"))
+	assert.Equal(t, prometheus.GaugeValue, metricValueType("generic_info_settings_info"))
+	assert.Equal(t, prometheus.GaugeValue, metricValueType("module_info_diagnostics_values_module_temperature"))
+}
+
+func TestNewConstMetricInvalidLabel(t *testing.T) {
+	metric := newConstMetric("", registry.MetricRecord{Name: "driver_info_common_info", Labels: map[string]string{"DriverName": "\xff"}, Value: 1})
+	assert.Error(t, metric.Write(&dto.Metric{}))
+}
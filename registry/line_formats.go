@@ -0,0 +1,155 @@
+package registry
+
+import (
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Neither Influx nor Graphite accept NaN and infinite values, such samples are skipped
+func isFiniteValue(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
+
+func formatLineValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// Extra labels are added to every sample, metric labels take precedence
+func mergeLabels(extraLabels map[string]string, metricLabels map[string]string) map[string]string {
+	labels := maps.Clone(extraLabels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	maps.Copy(labels, metricLabels)
+	return labels
+}
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `)
+	influxKeyEscaper         = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `)
+)
+
+// Collector is the longest name from the list, that prefixes metric name
+func splitCollectorName(metricName string, collectorNames []string) (string, string) {
+	collectorName := ""
+	for _, name := range collectorNames {
+		if strings.HasPrefix(metricName, name+"_") && len(name) > len(collectorName) {
+			collectorName = name
+		}
+	}
+	if collectorName == "" {
+		return "", metricName
+	}
+	return collectorName, strings.TrimPrefix(metricName, collectorName+"_")
+}
+
+// GetAllMetricsInflux renders all registries as Influx line protocol, one line per sample:
+// labels and `collector` become tags, metric name without collector prefix becomes field name.
+// Metrics without known collector prefix keep full name and have no `collector` tag.
+func (collection *RegistryCollection) GetAllMetricsInflux(measurement string, collectorNames []string, extraLabels map[string]string, timestamp time.Time) string {
+	var output strings.Builder
+	timestampString := strconv.FormatInt(timestamp.UnixNano(), 10)
+	for _, registryName := range slices.Sorted(maps.Keys(*collection)) {
+		for _, metricRecord := range (*collection)[registryName] {
+			if !isFiniteValue(metricRecord.Value) {
+				continue
+			}
+			collectorName, fieldName := splitCollectorName(metricRecord.Name, collectorNames)
+			tags := mergeLabels(extraLabels, metricRecord.Labels)
+			if collectorName != "" {
+				tags["collector"] = collectorName
+			}
+			output.WriteString(influxMeasurementEscaper.Replace(measurement))
+			for _, tagName := range slices.Sorted(maps.Keys(tags)) {
+				// Influx rejects empty tag values
+				if tags[tagName] == "" {
+					continue
+				}
+				output.WriteString("," + influxKeyEscaper.Replace(tagName) + "=" + influxKeyEscaper.Replace(tags[tagName]))
+			}
+			output.WriteString(" " + influxKeyEscaper.Replace(fieldName) + "=" + formatLineValue(metricRecord.Value))
+			output.WriteString(" " + timestampString + "\n")
+		}
+	}
+	return output.String()
+}
+
+var (
+	graphitePlaceholderRegexp    = regexp.MustCompile(`\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
+	graphiteUnsafeCharsRegexp    = regexp.MustCompile(`[^a-zA-Z0-9_:-]`)
+	graphiteTagUnsafeCharsRegexp = regexp.MustCompile(`[;~!^=\s]`)
+)
+
+// Renders path template, where `{name}` is metric name and other placeholders are label values.
+// Returns labels not used in the template. Path components that became empty are dropped.
+func renderGraphitePath(pathTemplate string, metricName string, labels map[string]string) (string, map[string]string) {
+	unusedLabels := maps.Clone(labels)
+	components := []string{}
+	for _, component := range strings.Split(pathTemplate, ".") {
+		component = graphitePlaceholderRegexp.ReplaceAllStringFunc(component, func(placeholder string) string {
+			labelName := strings.Trim(placeholder, "{}")
+			if labelName == "name" {
+				return metricName
+			}
+			delete(unusedLabels, labelName)
+			return graphiteUnsafeCharsRegexp.ReplaceAllString(labels[labelName], "_")
+		})
+		if component != "" {
+			components = append(components, component)
+		}
+	}
+	return strings.Join(components, "."), unusedLabels
+}
+
+// GetAllMetricsGraphite renders all registries as Graphite plaintext protocol, with path built from the template.
+// Labels not used in the template are added as Graphite tags, eg `path;queue=0`, so samples don't clash.
+func (collection *RegistryCollection) GetAllMetricsGraphite(pathTemplate string, extraLabels map[string]string, timestamp time.Time) string {
+	var output strings.Builder
+	timestampString := strconv.FormatInt(timestamp.Unix(), 10)
+	for _, registryName := range slices.Sorted(maps.Keys(*collection)) {
+		for _, metricRecord := range (*collection)[registryName] {
+			if !isFiniteValue(metricRecord.Value) {
+				continue
+			}
+			metricPath, tags := renderGraphitePath(pathTemplate, metricRecord.Name, mergeLabels(extraLabels, metricRecord.Labels))
+			output.WriteString(metricPath)
+			for _, tagName := range slices.Sorted(maps.Keys(tags)) {
+				// Graphite rejects empty tag values
+				if tags[tagName] == "" {
+					continue
+				}
+				output.WriteString(";" + graphiteTagUnsafeCharsRegexp.ReplaceAllString(tagName, "_") + "=" + graphiteTagUnsafeCharsRegexp.ReplaceAllString(tags[tagName], "_"))
+			}
+			output.WriteString(" " + formatLineValue(metricRecord.Value) + " " + timestampString + "\n")
+		}
+	}
+	return output.String()
+}
+
+// SplitByCollector splits registries by collector name prefix of metrics, like GetAllMetricsInflux does.
+// Metrics without known collector prefix, eg `missing_metric_info`, are split by their `collector` label,
+// otherwise they go to otherName. Registry keys are kept in every part.
+func (collection *RegistryCollection) SplitByCollector(collectorNames []string, otherName string) map[string]RegistryCollection {
+	parts := map[string]RegistryCollection{}
+	for registryName, metricRegistry := range *collection {
+		for _, metricRecord := range metricRegistry {
+			collectorName, _ := splitCollectorName(metricRecord.Name, collectorNames)
+			if collectorName == "" && slices.Contains(collectorNames, metricRecord.Labels["collector"]) {
+				collectorName = metricRecord.Labels["collector"]
+			}
+			if collectorName == "" {
+				collectorName = otherName
+			}
+			if parts[collectorName] == nil {
+				parts[collectorName] = RegistryCollection{}
+			}
+			parts[collectorName][registryName] = append(parts[collectorName][registryName], metricRecord)
+		}
+	}
+	return parts
+}
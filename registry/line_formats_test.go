@@ -0,0 +1,70 @@
+package registry
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var lineFormatsCollection = RegistryCollection{
+	"eth0": {
+		{Name: "module_info_diagnostics_alarms_bias_high", Labels: map[string]string{"device": "eth0"}, Value: 0},
+		{Name: "generic_info_settings_speed_bits", Labels: map[string]string{"device": "eth0"}, Value: 1e10},
+		{Name: "statistics_per_queue_rx_packets", Labels: map[string]string{"device": "eth0", "queue": "1"}, Value: 42},
+		{Name: "module_info_diagnostics_values_module_temperature", Labels: map[string]string{"device": "eth0"}, Value: math.NaN()},
+		{Name: "driver_info_common_info", Labels: map[string]string{"device": "eth0", "DriverName": "ixgbe", "Version": "", "BusInfo": "0000:01:00.0 x"}, Value: 1},
+	},
+	"": {
+		{Name: "discovery_refreshes_total", Labels: map[string]string{}, Value: 3},
+	},
+}
+
+func TestGetAllMetricsInflux(t *testing.T) {
+	expectedLines := `ethtool,instance=host1 discovery_refreshes_total=3 1700000000123000000
+ethtool,collector=module_info,device=eth0,instance=host1 diagnostics_alarms_bias_high=0 1700000000123000000
+ethtool,collector=generic_info,device=eth0,instance=host1 settings_speed_bits=1e+10 1700000000123000000
+ethtool,collector=statistics,device=eth0,instance=host1,queue=1 per_queue_rx_packets=42 1700000000123000000
+ethtool,BusInfo=0000:01:00.0\ x,DriverName=ixgbe,collector=driver_info,device=eth0,instance=host1 common_info=1 1700000000123000000
+`
+	collectorNames := []string{"driver_info", "generic_info", "module_info", "statistics", "module"}
+	output := lineFormatsCollection.GetAllMetricsInflux("ethtool", collectorNames, map[string]string{"instance": "host1"}, time.UnixMilli(1700000000123))
+	assert.Equal(t, expectedLines, output)
+}
+
+func TestGetAllMetricsGraphite(t *testing.T) {
+	expectedLines := `ethtool.host_example_com.discovery_refreshes_total 3 1700000000
+ethtool.host_example_com.eth0.module_info_diagnostics_alarms_bias_high 0 1700000000
+ethtool.host_example_com.eth0.generic_info_settings_speed_bits 1e+10 1700000000
+ethtool.host_example_com.eth0.statistics_per_queue_rx_packets;queue=1 42 1700000000
+ethtool.host_example_com.eth0.driver_info_common_info;BusInfo=0000:01:00.0_x;DriverName=ixgbe 1 1700000000
+`
+	output := lineFormatsCollection.GetAllMetricsGraphite("ethtool.{hostname}.{device}.{name}", map[string]string{"hostname": "host.example.com"}, time.UnixMilli(1700000000123))
+	assert.Equal(t, expectedLines, output)
+}
+
+func TestRenderGraphitePath(t *testing.T) {
+	metricPath, unusedLabels := renderGraphitePath("{device}_{queue}.x{missing}.{name}", "rx_packets", map[string]string{"device": "eth0", "queue": "1", "netns": "blue"})
+	assert.Equal(t, "eth0_1.x.rx_packets", metricPath)
+	assert.Equal(t, map[string]string{"netns": "blue"}, unusedLabels)
+}
+
+func TestSplitByCollector(t *testing.T) {
+	collection := maps.Clone(lineFormatsCollection)
+	collection["eth1"] = Registry{
+		{Name: "missing_metric_info", Labels: map[string]string{"device": "eth1", "collector": "module_info", "metric_name": "module_info_vendor"}, Value: 1},
+		{Name: "missing_metric_info", Labels: map[string]string{"device": "eth1", "collector": "unknown"}, Value: 1},
+	}
+	parts := collection.SplitByCollector([]string{"generic_info", "module_info", "statistics"}, "other")
+	assert.ElementsMatch(t, []string{"generic_info", "module_info", "statistics", "other"}, slices.Collect(maps.Keys(parts)))
+	assert.Len(t, parts["module_info"]["eth0"], 2)
+	assert.Len(t, parts["module_info"]["eth1"], 1, "absent metric marker goes to its collector")
+	assert.Len(t, parts["other"]["eth1"], 1)
+	assert.Len(t, parts["statistics"]["eth0"], 1)
+	assert.Len(t, parts["other"]["eth0"], 1, "driver_info is not in the list")
+	assert.Len(t, parts["other"][""], 1)
+	assert.NotContains(t, parts["generic_info"], "")
+}
@@ -38,3 +38,17 @@ func (metricRecord *MetricRecord) FormatPrometheusLine() (string, error) {
 
 	return fmt.Sprintf("%s{%s} %v", metricRecord.Name, labelStrings, metricRecord.Value), nil
 }
+
+const (
+	MetricTypeCounter = "counter"
+	MetricTypeGauge   = "gauge"
+)
+
+// Collectors don't track metric types, so they are derived from names:
+// NIC statistics and `_total` metrics only grow, everything else, including `_info` metrics, is a gauge
+func GetMetricType(metricName string) string {
+	if strings.HasSuffix(metricName, "_total") || strings.HasPrefix(metricName, "statistics_") {
+		return MetricTypeCounter
+	}
+	return MetricTypeGauge
+}
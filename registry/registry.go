@@ -60,3 +60,25 @@ func (registry *Registry) AddLabelsToSomeMetrics(targetMetricName string, extraL
 		}
 	}
 }
+
+func (registry *Registry) AddLabelsToAllMetrics(extraLabels map[string]string) {
+	for metricIndex, metricObj := range *registry {
+		newLabels := map[string]string{}
+		maps.Insert(newLabels, maps.All(metricObj.Labels))
+		maps.Insert(newLabels, maps.All(extraLabels))
+		(*registry)[metricIndex].Labels = newLabels
+	}
+}
+
+// Records of other devices in the same registry, eg bond slaves in bond master's one, are left as is
+func (registry *Registry) AddLabelsToDeviceMetrics(deviceName string, extraLabels map[string]string) {
+	for metricIndex, metricObj := range *registry {
+		if metricDevice, found := metricObj.Labels["device"]; found && metricDevice != deviceName {
+			continue
+		}
+		newLabels := map[string]string{}
+		maps.Insert(newLabels, maps.All(metricObj.Labels))
+		maps.Insert(newLabels, maps.All(extraLabels))
+		(*registry)[metricIndex].Labels = newLabels
+	}
+}
@@ -1,13 +1,63 @@
 package registry
 
-import "strings"
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+)
 
 type RegistryCollection map[string]Registry
 
 func (collection *RegistryCollection) GetAllMetricsText() string {
 	var allMetrics []string
-	for _, registry := range *collection {
+	for _, registryName := range slices.Sorted(maps.Keys(*collection)) {
+		registry := (*collection)[registryName]
 		allMetrics = append(allMetrics, registry.FormatTextfileString())
 	}
 	return strings.Join(allMetrics, "\n")
 }
+
+// OpenMetrics family name and type of the metric.
+// Counter and info samples must have `_total` and `_info` suffixes, so counters without it, eg NIC statistics, are `unknown`.
+func getOpenMetricsFamily(metricName string) (string, string) {
+	if strings.HasSuffix(metricName, "_info") {
+		return strings.TrimSuffix(metricName, "_info"), "info"
+	}
+	if GetMetricType(metricName) == MetricTypeCounter {
+		if strings.HasSuffix(metricName, "_total") {
+			return strings.TrimSuffix(metricName, "_total"), MetricTypeCounter
+		}
+		return metricName, "unknown"
+	}
+	return metricName, MetricTypeGauge
+}
+
+// GetAllMetricsOpenMetrics groups samples of all registries into families, as OpenMetrics requires, and ends with `# EOF`
+func (collection *RegistryCollection) GetAllMetricsOpenMetrics() string {
+	familyLines := map[string][]string{}
+	familyTypes := map[string]string{}
+	for _, registryName := range slices.Sorted(maps.Keys(*collection)) {
+		for _, metricRecord := range (*collection)[registryName] {
+			metricLine, err := metricRecord.FormatPrometheusLine()
+			if err != nil {
+				slog.Error("Cannot format metric: ", "metricFormatError", err)
+				continue
+			}
+			familyName, familyType := getOpenMetricsFamily(metricRecord.Name)
+			familyLines[familyName] = append(familyLines[familyName], metricLine)
+			familyTypes[familyName] = familyType
+		}
+	}
+
+	var output strings.Builder
+	for _, familyName := range slices.Sorted(maps.Keys(familyLines)) {
+		fmt.Fprintf(&output, "# TYPE %s %s\n", familyName, familyTypes[familyName])
+		for _, metricLine := range familyLines[familyName] {
+			fmt.Fprintln(&output, metricLine)
+		}
+	}
+	output.WriteString("# EOF\n")
+	return output.String()
+}
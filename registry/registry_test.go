@@ -104,3 +104,88 @@ func TestGetMetricIndex_MultipleMatches(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "multiple metrics with the same name <foo>")
 }
+
+func TestAddLabelsToAllMetrics(t *testing.T) {
+	metricList := Registry{
+		{Name: "foo", Labels: map[string]string{"device": "eth0"}, Value: 1},
+		{Name: "bar", Labels: nil, Value: 2},
+	}
+	metricList.AddLabelsToAllMetrics(map[string]string{"pf_device": "eth0"})
+	assert.Equal(t, map[string]string{"device": "eth0", "pf_device": "eth0"}, metricList[0].Labels)
+	assert.Equal(t, map[string]string{"pf_device": "eth0"}, metricList[1].Labels)
+}
+
+func TestGetMetricType(t *testing.T) {
+	assert.Equal(t, MetricTypeCounter, GetMetricType("link_events_total"))
+	assert.Equal(t, MetricTypeCounter, GetMetricType("statistics_general_rx_packets"))
+	assert.Equal(t, MetricTypeGauge, GetMetricType("generic_info_settings_info"))
+	assert.Equal(t, MetricTypeGauge, GetMetricType("module_info_diagnostics_values_module_temperature"))
+}
+
+func TestGetAllMetricsOpenMetrics(t *testing.T) {
+	expectedMetrics := `# TYPE driver_info_common info
+driver_info_common_info{DriverName="ixgbe",device="eth0"} 1
+driver_info_common_info{DriverName="mlx5_core",device="eth1"} 1
+# TYPE generic_info_settings_speed_bits gauge
+generic_info_settings_speed_bits{device="eth0"} 1e+10
+# TYPE link_events counter
+link_events_total{device="eth0",event="link_down"} 3
+# TYPE statistics_general_rx_packets unknown
+statistics_general_rx_packets{device="eth1"} 42
+# EOF
+`
+	collection := RegistryCollection{
+		"eth1": {
+			{Name: "driver_info_common_info", Labels: map[string]string{"device": "eth1", "DriverName": "mlx5_core"}, Value: 1},
+			{Name: "statistics_general_rx_packets", Labels: map[string]string{"device": "eth1"}, Value: 42},
+		},
+		"eth0": {
+			{Name: "driver_info_common_info", Labels: map[string]string{"device": "eth0", "DriverName": "ixgbe"}, Value: 1},
+			{Name: "generic_info_settings_speed_bits", Labels: map[string]string{"device": "eth0"}, Value: 1e10},
+			{Name: "link_events_total", Labels: map[string]string{"device": "eth0", "event": "link_down"}, Value: 3},
+		},
+	}
+	assert.Equal(t, expectedMetrics, collection.GetAllMetricsOpenMetrics())
+}
+
+func TestWriteTextfileOptions(t *testing.T) {
+	dirPath := t.TempDir()
+	textFilePath := dirPath + "/custom.prom"
+	assert.NoError(t, WriteTextfile(textFilePath, "test_metric 1\n", TextfileOptions{Mode: 0o644, Fsync: true}))
+
+	fileInfo, err := os.Stat(textFilePath)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o644), fileInfo.Mode().Perm())
+	content, err := os.ReadFile(textFilePath)
+	assert.NoError(t, err)
+	assert.Equal(t, "test_metric 1\n", string(content))
+
+	// Only the textfile itself is left, temporary file is renamed
+	entries, err := os.ReadDir(dirPath)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestWriteTextfileDefaultMode(t *testing.T) {
+	textFilePath := t.TempDir() + "/ethtool_exporter.prom"
+	MustWriteTextfile(textFilePath, "")
+	fileInfo, err := os.Stat(textFilePath)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), fileInfo.Mode().Perm())
+}
+
+func TestWriteTextfileBrokenPath(t *testing.T) {
+	assert.Error(t, WriteTextfile("/non-existed-root-dir/ethtool_exporter.prom", "", TextfileOptions{Mode: 0o644}))
+}
+
+func TestAddLabelsToDeviceMetrics(t *testing.T) {
+	metricList := Registry{
+		{Name: "foo", Labels: map[string]string{"device": "bond0"}, Value: 1},
+		{Name: "foo", Labels: map[string]string{"device": "eth0", "master": "bond0"}, Value: 1},
+		{Name: "bar", Labels: nil, Value: 2},
+	}
+	metricList.AddLabelsToDeviceMetrics("bond0", map[string]string{"driver": "bonding"})
+	assert.Equal(t, map[string]string{"device": "bond0", "driver": "bonding"}, metricList[0].Labels)
+	assert.Equal(t, map[string]string{"device": "eth0", "master": "bond0"}, metricList[1].Labels)
+	assert.Equal(t, map[string]string{"driver": "bonding"}, metricList[2].Labels)
+}
@@ -0,0 +1,66 @@
+package registry
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of Prometheus remote-write 1.0 messages, see
+// https://github.com/prometheus/prometheus/blob/main/prompb/remote.proto and types.proto
+const (
+	remoteWriteRequestTimeseries = 1
+	remoteWriteTimeseriesLabels  = 1
+	remoteWriteTimeseriesSamples = 2
+	remoteWriteLabelName         = 1
+	remoteWriteLabelValue        = 2
+	remoteWriteSampleValue       = 1
+	remoteWriteSampleTimestamp   = 2
+)
+
+func appendRemoteWriteLabel(buffer []byte, labelName string, labelValue string) []byte {
+	var label []byte
+	label = protowire.AppendTag(label, remoteWriteLabelName, protowire.BytesType)
+	label = protowire.AppendString(label, labelName)
+	label = protowire.AppendTag(label, remoteWriteLabelValue, protowire.BytesType)
+	label = protowire.AppendString(label, labelValue)
+	buffer = protowire.AppendTag(buffer, remoteWriteTimeseriesLabels, protowire.BytesType)
+	return protowire.AppendBytes(buffer, label)
+}
+
+// Labels are sorted by name, including `__name__`, as remote-write receivers expect
+func appendRemoteWriteTimeseries(buffer []byte, metricRecord MetricRecord, extraLabels map[string]string, timestampMs int64) []byte {
+	labels := mergeLabels(extraLabels, metricRecord.Labels)
+	labels["__name__"] = metricRecord.Name
+
+	var timeseries []byte
+	for _, labelName := range slices.Sorted(maps.Keys(labels)) {
+		timeseries = appendRemoteWriteLabel(timeseries, labelName, labels[labelName])
+	}
+	var sample []byte
+	sample = protowire.AppendTag(sample, remoteWriteSampleValue, protowire.Fixed64Type)
+	sample = protowire.AppendFixed64(sample, math.Float64bits(metricRecord.Value))
+	sample = protowire.AppendTag(sample, remoteWriteSampleTimestamp, protowire.VarintType)
+	sample = protowire.AppendVarint(sample, uint64(timestampMs))
+	timeseries = protowire.AppendTag(timeseries, remoteWriteTimeseriesSamples, protowire.BytesType)
+	timeseries = protowire.AppendBytes(timeseries, sample)
+
+	buffer = protowire.AppendTag(buffer, remoteWriteRequestTimeseries, protowire.BytesType)
+	return protowire.AppendBytes(buffer, timeseries)
+}
+
+// GetRemoteWriteRequest encodes all registries as snappy-compressed remote-write WriteRequest protobuf.
+// Extra labels, eg `job` and `instance`, are added to every series, metric labels take precedence.
+func (collection *RegistryCollection) GetRemoteWriteRequest(extraLabels map[string]string, timestamp time.Time) []byte {
+	var request []byte
+	for _, registryName := range slices.Sorted(maps.Keys(*collection)) {
+		for _, metricRecord := range (*collection)[registryName] {
+			request = appendRemoteWriteTimeseries(request, metricRecord, extraLabels, timestamp.UnixMilli())
+		}
+	}
+	return snappy.Encode(nil, request)
+}
@@ -0,0 +1,105 @@
+package registry
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type decodedSample struct {
+	Labels    [][2]string
+	Value     float64
+	Timestamp int64
+}
+
+// Minimal decoder of WriteRequest, enough to check what encoder produced
+func consumeRemoteWriteFields(t *testing.T, buffer []byte, function func(number protowire.Number, value []byte, scalar uint64)) {
+	for len(buffer) > 0 {
+		number, fieldType, length := protowire.ConsumeTag(buffer)
+		require.GreaterOrEqual(t, length, 0)
+		buffer = buffer[length:]
+		switch fieldType {
+		case protowire.BytesType:
+			value, length := protowire.ConsumeBytes(buffer)
+			require.GreaterOrEqual(t, length, 0)
+			function(number, value, 0)
+			buffer = buffer[length:]
+		case protowire.Fixed64Type:
+			value, length := protowire.ConsumeFixed64(buffer)
+			require.GreaterOrEqual(t, length, 0)
+			function(number, nil, value)
+			buffer = buffer[length:]
+		case protowire.VarintType:
+			value, length := protowire.ConsumeVarint(buffer)
+			require.GreaterOrEqual(t, length, 0)
+			function(number, nil, value)
+			buffer = buffer[length:]
+		default:
+			t.Fatalf("unexpected wire type %v", fieldType)
+		}
+	}
+}
+
+func decodeRemoteWriteRequest(t *testing.T, compressed []byte) []decodedSample {
+	request, err := snappy.Decode(nil, compressed)
+	require.NoError(t, err)
+	samples := []decodedSample{}
+	consumeRemoteWriteFields(t, request, func(_ protowire.Number, timeseries []byte, _ uint64) {
+		sample := decodedSample{}
+		consumeRemoteWriteFields(t, timeseries, func(number protowire.Number, message []byte, _ uint64) {
+			if number == remoteWriteTimeseriesLabels {
+				label := [2]string{}
+				consumeRemoteWriteFields(t, message, func(number protowire.Number, value []byte, _ uint64) {
+					label[number-1] = string(value)
+				})
+				sample.Labels = append(sample.Labels, label)
+				return
+			}
+			consumeRemoteWriteFields(t, message, func(number protowire.Number, _ []byte, scalar uint64) {
+				if number == remoteWriteSampleValue {
+					sample.Value = math.Float64frombits(scalar)
+				} else {
+					sample.Timestamp = int64(scalar)
+				}
+			})
+		})
+		samples = append(samples, sample)
+	})
+	return samples
+}
+
+func TestGetRemoteWriteRequest(t *testing.T) {
+	collection := RegistryCollection{
+		"eth1": {
+			{Name: "statistics_general_rx_packets", Labels: map[string]string{"device": "eth1"}, Value: 42},
+		},
+		"": {
+			{Name: "exporter_info", Labels: map[string]string{"job": "custom"}, Value: 1},
+		},
+	}
+	timestamp := time.UnixMilli(1700000000123)
+	samples := decodeRemoteWriteRequest(t, collection.GetRemoteWriteRequest(map[string]string{"job": "ethtool", "instance": "host1"}, timestamp))
+	expectedSamples := []decodedSample{
+		{
+			Labels:    [][2]string{{"__name__", "exporter_info"}, {"instance", "host1"}, {"job", "custom"}},
+			Value:     1,
+			Timestamp: 1700000000123,
+		},
+		{
+			Labels:    [][2]string{{"__name__", "statistics_general_rx_packets"}, {"device", "eth1"}, {"instance", "host1"}, {"job", "ethtool"}},
+			Value:     42,
+			Timestamp: 1700000000123,
+		},
+	}
+	assert.Equal(t, expectedSamples, samples)
+}
+
+func TestGetRemoteWriteRequestEmpty(t *testing.T) {
+	collection := RegistryCollection{}
+	assert.Empty(t, decodeRemoteWriteRequest(t, collection.GetRemoteWriteRequest(nil, time.Now())))
+}
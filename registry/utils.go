@@ -12,11 +12,20 @@ func sanitizelabelPair(labelName, labelValue string) (string, string) {
 	return labelName, labelValue
 }
 
-func MustWriteTextfile(filePath string, fileContent string) {
+type TextfileOptions struct {
+	// Mode of the textfile, zero keeps 0600 of os.CreateTemp. Umask is not applied
+	Mode os.FileMode
+	// Sync textfile and its directory to disk, so rename survives power loss
+	Fsync bool
+}
+
+// WriteTextfile atomically replaces the textfile: content is written to a temporary file in the same directory, then it is renamed.
+// Temporary file name is `<name>-*`, so node_exporter doesn't read it as `*.prom`.
+func WriteTextfile(filePath string, fileContent string, options TextfileOptions) error {
 	tmpDir := filepath.Dir(filePath)
-	tmpFile, err := os.CreateTemp(tmpDir, "ethtool_exporter.prom-*")
+	tmpFile, err := os.CreateTemp(tmpDir, filepath.Base(filePath)+"-*")
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer os.Remove(tmpFile.Name())
 
@@ -25,19 +34,52 @@ func MustWriteTextfile(filePath string, fileContent string) {
 		// Hard to cover by unit tests, because it would require to brake\overflow the filesystem, or mock the os.File
 		//coverage:ignore
 		slog.Error("Cannot write formated metric to file", "file", tmpFile.Name(), "error", err)
-		panic(err)
+		tmpFile.Close()
+		return err
+	}
+	if options.Mode != 0 {
+		err = tmpFile.Chmod(options.Mode)
+		if err != nil {
+			tmpFile.Close()
+			return err
+		}
+	}
+	if options.Fsync {
+		err = tmpFile.Sync()
+		if err != nil {
+			//coverage:ignore
+			tmpFile.Close()
+			return err
+		}
 	}
 	err = tmpFile.Close()
 	if err != nil {
 		//coverage:ignore
-		panic(err)
+		return err
 	}
 
-	// Not sure, we should check how default mod is set
-	// if err := os.Chmod(tmpFile.Name(), 0o644); err != nil {
-	// 	return err
-	// }
 	err = os.Rename(tmpFile.Name(), filePath)
+	if err != nil {
+		return err
+	}
+	if options.Fsync {
+		return syncDirectory(tmpDir)
+	}
+	return nil
+}
+
+func syncDirectory(dirPath string) error {
+	dir, err := os.Open(dirPath)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// MustWriteTextfile is WriteTextfile with default options, that panics on errors
+func MustWriteTextfile(filePath string, fileContent string) {
+	err := WriteTextfile(filePath, fileContent, TextfileOptions{})
 	if err != nil {
 		panic(err)
 	}
@@ -9,7 +9,7 @@ SCRIPT_DIR="$(dirname "$0")"
 sleep 0.1
 
 case "$1" in
-  eth4)
+  eth4|ens7f0v0)
     cat "$SCRIPT_DIR/eth4.generic_info.src"
     exit 0
     ;;
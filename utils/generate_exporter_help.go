@@ -14,16 +14,35 @@ import (
 	"go/token"
 	"log/slog"
 	"os"
+	"regexp"
 	"sort"
 	"strings"
 )
 
 const (
-	srcFlagFile = "exporter_cmd.go"
+	srcFlagFile     = "exporter_cmd.go"
+	flagEnvarPrefix = "GO_ETHTOOL_EXPORTER_"
 )
 
+var flagEnvarNameRegexp = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// The same as flagEnvarName() in exporter_config.go
+func flagEnvarName(flagName string) string {
+	return flagEnvarPrefix + flagEnvarNameRegexp.ReplaceAllString(strings.ToUpper(flagName), "_")
+}
+
+// The same as commandFlagEnvarName() in exporter_config.go
+func commandFlagEnvarName(commandName string, flagName string) string {
+	envarName := flagEnvarName(flagName)
+	if strings.HasPrefix(envarName, flagEnvarName(commandName)+"_") {
+		return envarName
+	}
+	return flagEnvarName(commandName + "-" + flagName)
+}
+
 type Flag struct {
 	Name        string
+	Envar       string
 	Description string
 	Default     string
 	Type        string
@@ -80,6 +99,13 @@ func extractFlagInfo(expr ast.Expr) (name, description, defaultValue, flagType s
 			break // Not a call expression, stop traversing
 		}
 
+		// optionalRegexp(kingpin.Flag(...)) wrapper from exporter_config.go
+		if ident, ok := callExpr.Fun.(*ast.Ident); ok && ident.Name == "optionalRegexp" && len(callExpr.Args) == 1 {
+			flagType = "Regexp"
+			currentExpr = callExpr.Args[0]
+			continue
+		}
+
 		selectorExpr, ok := callExpr.Fun.(*ast.SelectorExpr)
 		if !ok {
 			break // Not a selector expression (e.g., kingpin.Flag), stop traversing
@@ -141,13 +167,14 @@ func formatFlag(f Flag, indent int) string {
 		doubleIndentString := strings.Repeat(" ", indent*2)
 		desc = fmt.Sprintf("\n%s%s", doubleIndentString, f.Description)
 	}
+	envar := fmt.Sprintf(" ($%s)", f.Envar)
 	if f.Type == "Bool" {
 		if f.Default == "true" {
-			return fmt.Sprintf("%s--no-%s%s", indentString, f.Name, desc)
+			return fmt.Sprintf("%s--no-%s%s%s", indentString, f.Name, envar, desc)
 		}
-		return fmt.Sprintf("%s--%s%s", indentString, f.Name, desc)
+		return fmt.Sprintf("%s--%s%s%s", indentString, f.Name, envar, desc)
 	}
-	return fmt.Sprintf("%s--%s=%s%s", indentString, f.Name, f.Default, desc)
+	return fmt.Sprintf("%s--%s=%s%s%s", indentString, f.Name, f.Default, envar, desc)
 }
 
 func main() {
@@ -217,7 +244,7 @@ func main() {
 			if flagName != "" {
 				if isCmdFlag {
 					if cmd, ok := commands[cmdVars[cmdVar]]; ok {
-						cmd.Flags = append(cmd.Flags, Flag{flagName, flagDesc, flagDefault, flagType})
+						cmd.Flags = append(cmd.Flags, Flag{flagName, commandFlagEnvarName(cmd.Name, flagName), flagDesc, flagDefault, flagType})
 					}
 				} else {
 					// Global flag
@@ -225,7 +252,7 @@ func main() {
 						groupOrder = append(groupOrder, currentGroup)
 						seenGroups[currentGroup] = true
 					}
-					groups[currentGroup] = append(groups[currentGroup], formatFlag(Flag{flagName, flagDesc, flagDefault, flagType}, 2))
+					groups[currentGroup] = append(groups[currentGroup], formatFlag(Flag{flagName, flagEnvarName(flagName), flagDesc, flagDefault, flagType}, 2))
 				}
 				continue
 			}
@@ -260,6 +287,8 @@ func main() {
 	}
 
 	helpBuffer.WriteString("\n\nFlags:\n\n")
+	helpBuffer.WriteString("Every flag can also be set via environment variable shown in brackets, command line flags take precedence.\n")
+	helpBuffer.WriteString("Log level is only set via $" + flagEnvarPrefix + "LOG_LEVEL (debug, info, warn or error).\n\n")
 	// Grouped global flags
 	for _, group := range groupOrder {
 		helpBuffer.WriteString(group + ":\n")